@@ -0,0 +1,108 @@
+package irdata
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// adaptivePacerT spaces requests out evenly over what's left of the rate
+// limit window
+type adaptivePacerT struct {
+	mu   sync.Mutex
+	next time.Time // when the next request may go
+}
+
+// SetAdaptivePacing spreads /data requests evenly over what's left of the
+// rate limit window (see RateLimitStatus): with remaining requests left and
+// the window resetting in d, requests go at most one every d/remaining.
+// Rather than run at full speed until the limit is hit and then stall until
+// the reset, a busy client slows down as the budget runs low.  The waits are
+// subject to SetMaxRateLimitWait.  It's off by default.
+func (i *Irdata) SetAdaptivePacing(enabled bool) {
+	if !enabled {
+		i.adaptivePacer = nil
+		return
+	}
+
+	i.adaptivePacer = &adaptivePacerT{}
+}
+
+// reserve returns how long a request made at now has to wait given status,
+// and the spacing it reserved (to give back with unreserve if the request
+// isn't made)
+func (p *adaptivePacerT) reserve(status RateLimit, now time.Time) (time.Duration, time.Duration) {
+	if !now.Before(status.Reset) {
+		return 0, 0
+	}
+
+	left := status.Reset.Sub(now)
+
+	// nothing left, wait for the reset
+	if status.Remaining <= 0 {
+		return left, 0
+	}
+
+	spacing := left / time.Duration(status.Remaining)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+
+	p.next = at.Add(spacing)
+
+	return at.Sub(now), spacing
+}
+
+// unreserve gives back the spacing reserved for a request that wasn't made
+func (p *adaptivePacerT) unreserve(spacing time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.next = p.next.Add(-spacing)
+}
+
+// adaptivePace waits for the request's turn, if adaptive pacing is on
+func (i *Irdata) adaptivePace(ctx context.Context) error {
+	pacer := i.adaptivePacer
+	if pacer == nil {
+		return nil
+	}
+
+	status, ok := i.RateLimitStatus()
+	if !ok {
+		return nil
+	}
+
+	wait, spacing := pacer.reserve(status, time.Now())
+	if wait == 0 {
+		return nil
+	}
+
+	if i.maxRateLimitWait > 0 && wait > i.maxRateLimitWait {
+		pacer.unreserve(spacing)
+
+		return &RateLimitExceededError{Wait: wait, MaxWait: i.maxRateLimitWait, Reset: status.Reset}
+	}
+
+	i.rateLimited(wait)
+
+	log.WithFields(logrus.Fields{
+		"remaining": status.Remaining,
+		"wait":      wait,
+	}).Debug("Adaptive pacing")
+
+	if err := sleepCtx(ctx, wait); err != nil {
+		pacer.unreserve(spacing)
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package irdata
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptivePacerReserve(t *testing.T) {
+	p := &adaptivePacerT{}
+
+	reserve := func(status RateLimit, now time.Time) time.Duration {
+		wait, _ := p.reserve(status, now)
+		return wait
+	}
+
+	now := time.Now()
+
+	status := RateLimit{Remaining: 10, Reset: now.Add(10 * time.Second)}
+
+	// a second apart
+	assert.Equal(t, time.Duration(0), reserve(status, now))
+	assert.Equal(t, time.Second, reserve(status, now))
+	assert.Equal(t, 2*time.Second, reserve(status, now))
+
+	// fewer left, further apart
+	later := now.Add(5 * time.Second)
+	status.Remaining = 1
+
+	assert.Equal(t, time.Duration(0), reserve(status, later))
+	assert.Equal(t, 5*time.Second, reserve(status, later))
+
+	// none left
+	status.Remaining = 0
+	assert.Equal(t, 5*time.Second, reserve(status, later))
+
+	// the window has reset
+	assert.Equal(t, time.Duration(0), reserve(status, now.Add(time.Minute)))
+}
+
+func TestAdaptivePacerUnreserve(t *testing.T) {
+	p := &adaptivePacerT{}
+
+	now := time.Now()
+
+	status := RateLimit{Remaining: 10, Reset: now.Add(10 * time.Second)}
+
+	p.reserve(status, now)
+
+	wait, spacing := p.reserve(status, now)
+	assert.Equal(t, time.Second, wait)
+
+	// the request that gave up doesn't push the next one back
+	p.unreserve(spacing)
+
+	wait, _ = p.reserve(status, now)
+	assert.Equal(t, time.Second, wait)
+}
+
+func TestSetAdaptivePacing(t *testing.T) {
+	var reset time.Time
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Remaining", "1")
+		w.Header().Set("X-Ratelimit-Reset", fmt.Sprint(reset.Unix()))
+
+		fmt.Fprint(w, `{}`)
+	}))
+
+	reset = time.Now().Add(time.Hour)
+
+	fake.SetAdaptivePacing(true)
+	fake.SetMaxRateLimitWait(time.Minute)
+
+	// learns the limit, then 1 left in an hour can go now
+	for n := 0; n < 2; n++ {
+		_, err := fake.Get("/data/member/info")
+		assert.NoError(t, err)
+	}
+
+	// but the next has to wait most of the hour
+	_, err := fake.Get("/data/member/info")
+
+	var rateLimitErr *RateLimitExceededError
+
+	if assert.True(t, errors.As(err, &rateLimitErr)) {
+		assert.Greater(t, rateLimitErr.Wait, 50*time.Minute)
+	}
+
+	// giving up didn't take a turn, the next would wait as long
+	_, err = fake.Get("/data/member/info")
+
+	if assert.True(t, errors.As(err, &rateLimitErr)) {
+		assert.LessOrEqual(t, rateLimitErr.Wait, time.Hour)
+	}
+
+	fake.SetAdaptivePacing(false)
+
+	_, err = fake.Get("/data/member/info")
+	assert.NoError(t, err)
+}
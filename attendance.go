@@ -0,0 +1,148 @@
+package irdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// the reason_out of drivers that finished
+const _reasonOutRunning = "Running"
+
+// AttendanceRow is a member's participation in a league season.  The json
+// tags make the rows ready for Flatten and the sinks.
+type AttendanceRow struct {
+	CustID         int64   `json:"cust_id"`
+	DisplayName    string  `json:"display_name"`
+	OnRoster       bool    `json:"on_roster"`
+	Sessions       int     `json:"sessions"`
+	Attended       int     `json:"attended"`
+	AttendanceRate float64 `json:"attendance_rate"`
+	Finished       int     `json:"finished"`
+	CleanRaces     int     `json:"clean_races"`
+	Incidents      int     `json:"incidents"`
+	LapsComplete   int     `json:"laps_complete"`
+}
+
+// LeagueAttendance crawls the sessions of a league season that have results
+// and reports the attendance of every roster member and anyone else who
+// raced (guests), ordered by attendance.  A clean race is a finished race
+// without incidents.
+func (i *Irdata) LeagueAttendance(leagueID int64, seasonID int64) ([]AttendanceRow, error) {
+	leagueData, err := i.Get(fmt.Sprintf("/data/league/get?league_id=%d", leagueID))
+	if err != nil {
+		return nil, err
+	}
+
+	var league struct {
+		Roster []struct {
+			CustID      int64  `json:"cust_id"`
+			DisplayName string `json:"display_name"`
+		} `json:"roster"`
+	}
+
+	if err := json.Unmarshal(leagueData, &league); err != nil {
+		return nil, makeErrorf("unable to decode league [%v]", err)
+	}
+
+	sessionsData, err := i.Get(fmt.Sprintf("/data/league/season_sessions?league_id=%d&season_id=%d&results_only=true", leagueID, seasonID))
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions struct {
+		Sessions []struct {
+			SubsessionID int64 `json:"subsession_id"`
+		} `json:"sessions"`
+	}
+
+	if err := json.Unmarshal(sessionsData, &sessions); err != nil {
+		return nil, makeErrorf("unable to decode season sessions [%v]", err)
+	}
+
+	rows := map[int64]*AttendanceRow{}
+
+	for _, member := range league.Roster {
+		rows[member.CustID] = &AttendanceRow{
+			CustID:      member.CustID,
+			DisplayName: member.DisplayName,
+			OnRoster:    true,
+		}
+	}
+
+	count := 0
+
+	for _, session := range sessions.Sessions {
+		if session.SubsessionID == 0 {
+			continue
+		}
+
+		data, err := i.Get(fmt.Sprintf("/data/results/get?subsession_id=%d", session.SubsessionID))
+		if err != nil {
+			return nil, err
+		}
+
+		results, err := mainEventResults(data)
+		if err != nil {
+			return nil, err
+		}
+
+		count++
+
+		addAttendance(rows, results)
+	}
+
+	attendance := make([]AttendanceRow, 0, len(rows))
+
+	for _, row := range rows {
+		row.Sessions = count
+
+		if count > 0 {
+			row.AttendanceRate = float64(row.Attended) / float64(count)
+		}
+
+		attendance = append(attendance, *row)
+	}
+
+	sort.Slice(attendance, func(a, b int) bool {
+		if attendance[a].Attended != attendance[b].Attended {
+			return attendance[a].Attended > attendance[b].Attended
+		}
+
+		return attendance[a].CustID < attendance[b].CustID
+	})
+
+	return attendance, nil
+}
+
+// addAttendance adds a session's results to rows, team results count for
+// each of the team's drivers
+func addAttendance(rows map[int64]*AttendanceRow, results []resultT) {
+	for _, result := range results {
+		drivers := result.DriverResults
+		if len(drivers) == 0 {
+			drivers = []resultT{result}
+		}
+
+		for _, driver := range drivers {
+			row, ok := rows[driver.CustID]
+			if !ok {
+				row = &AttendanceRow{CustID: driver.CustID, DisplayName: driver.DisplayName}
+				rows[driver.CustID] = row
+			}
+
+			row.Attended++
+			row.Incidents += driver.Incidents
+			row.LapsComplete += driver.LapsComplete
+
+			// a team's finish is the team's, not the driver's
+			if result.ReasonOut == _reasonOutRunning {
+				row.Finished++
+
+				if driver.Incidents == 0 {
+					row.CleanRaces++
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeagueAttendance(t *testing.T) {
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/league/get":
+			fmt.Fprint(w, `{"roster":[{"cust_id":1,"display_name":"A"},{"cust_id":2,"display_name":"B"},{"cust_id":3,"display_name":"C"}]}`)
+		case "/data/league/season_sessions":
+			assert.Equal(t, "9", r.URL.Query().Get("season_id"))
+			fmt.Fprint(w, `{"sessions":[{"subsession_id":100},{"subsession_id":200},{"subsession_id":0}]}`)
+		case "/data/results/get":
+			switch r.URL.Query().Get("subsession_id") {
+			case "100":
+				fmt.Fprint(w, `{"session_results":[{"simsession_number":0,"results":[
+					{"cust_id":1,"incidents":0,"laps_complete":20,"reason_out":"Running"},
+					{"cust_id":2,"incidents":4,"laps_complete":20,"reason_out":"Running"}]}]}`)
+			case "200":
+				fmt.Fprint(w, `{"session_results":[{"simsession_number":0,"results":[
+					{"cust_id":1,"incidents":8,"laps_complete":5,"reason_out":"Disconnected"},
+					{"cust_id":4,"display_name":"Guest","incidents":0,"laps_complete":20,"reason_out":"Running"}]}]}`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	attendance, err := fake.LeagueAttendance(7, 9)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []AttendanceRow{
+		{CustID: 1, DisplayName: "A", OnRoster: true, Sessions: 2, Attended: 2, AttendanceRate: 1, Finished: 1, CleanRaces: 1, Incidents: 8, LapsComplete: 25},
+		{CustID: 2, DisplayName: "B", OnRoster: true, Sessions: 2, Attended: 1, AttendanceRate: 0.5, Finished: 1, Incidents: 4, LapsComplete: 20},
+		{CustID: 4, DisplayName: "Guest", Sessions: 2, Attended: 1, AttendanceRate: 0.5, Finished: 1, CleanRaces: 1, LapsComplete: 20},
+		{CustID: 3, DisplayName: "C", OnRoster: true, Sessions: 2},
+	}, attendance)
+}
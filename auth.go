@@ -2,84 +2,239 @@ package irdata
 
 import (
 	"bytes"
-	"crypto/aes"
+	"context"
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/gob"
-	"errors"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus"
 )
 
+// the default login url of new clients, see SetLoginURL
 const loginURL = "https://members-ng.iracing.com/auth"
-const testUrl = "https://members-ng.iracing.com/data/constants/event_types"
+
+// fetched after logging in to check the session works
+const testURI = "/data/constants/event_types"
+
+// version of authDataT written to creds files, bump it (and teach
+// migrateAuthData about the old one) whenever authDataT changes
+const _authDataVersion = 1
 
 type authDataT struct {
+	Version         int // zero for creds files written before this was tracked
 	Username        string
 	EncodedPassword string
+	CreatedAt       time.Time // zero for creds files written before this was tracked
+}
+
+// CredsAgeWarning describes credentials that are older than the maximum age
+// set with SetCredsMaxAge
+type CredsAgeWarning struct {
+	Filename  string
+	CreatedAt time.Time
+	Age       time.Duration
+	MaxAge    time.Duration
+}
+
+// body returned by the login endpoint
+type authResponseT struct {
+	Message              string
+	VerificationRequired bool
 }
 
 var additionalContext = []byte("irdata.auth")
 
 // AuthWithCredsFromFile loads the username and password from a file
 // at authFilename and encrypted with the key in keyFilename.
+//
+// If the credentials are older than the age set with SetCredsMaxAge a warning
+// is logged and the hook set with SetCredsAgeHook is called.
 func (i *Irdata) AuthWithCredsFromFile(keyFilename string, authFilename string) error {
-	authData, err := readCreds(keyFilename, authFilename)
+	return i.AuthWithCredsFromFileWithKey(i.keyFromFile(keyFilename), authFilename)
+}
+
+// AuthWithCredsFromFileWithKey is AuthWithCredsFromFile with the key
+// supplied by keyProvider.
+func (i *Irdata) AuthWithCredsFromFileWithKey(keyProvider KeyProvider, authFilename string) error {
+	authData, err := readCreds(keyProvider, authFilename, i.fileOpts())
 	if err != nil {
 		return err
 	}
 
+	i.checkCredsAge(authFilename, authData)
+
 	return i.auth(authData)
 }
 
+// SetCredsIdentity binds creds files to identity (e.g. a hostname or a
+// username).  Files written while an identity is set can only be read back
+// with the same identity set, so a copied creds file can't be used in another
+// context unless the identity is deliberately set to match.  Files written
+// without an identity are unaffected.
+func (i *Irdata) SetCredsIdentity(identity string) {
+	i.credsIdentity = identity
+}
+
+// SetCredsMaxAge sets the age after which credentials loaded from a creds file
+// are due for rotation.  Zero (the default) disables the check.
+func (i *Irdata) SetCredsMaxAge(maxAge time.Duration) {
+	i.credsMaxAge = maxAge
+}
+
+// SetCredsAgeHook sets a function to call when credentials loaded from a creds
+// file are older than the age set with SetCredsMaxAge
+func (i *Irdata) SetCredsAgeHook(hook func(CredsAgeWarning)) {
+	i.credsAgeHook = hook
+}
+
+func (i *Irdata) checkCredsAge(authFilename string, authData authDataT) {
+	if i.credsMaxAge == 0 {
+		return
+	}
+
+	if authData.CreatedAt.IsZero() {
+		log.WithFields(logrus.Fields{"authFilename": authFilename}).Info("Creds file has no creation time, re-save it to track its age")
+		return
+	}
+
+	age := time.Since(authData.CreatedAt)
+
+	if age <= i.credsMaxAge {
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"authFilename": authFilename,
+		"createdAt":    authData.CreatedAt,
+		"age":          age,
+		"maxAge":       i.credsMaxAge,
+	}).Warn("Credentials are due for rotation")
+
+	if i.credsAgeHook != nil {
+		i.credsAgeHook(CredsAgeWarning{
+			Filename:  authFilename,
+			CreatedAt: authData.CreatedAt,
+			Age:       age,
+			MaxAge:    i.credsMaxAge,
+		})
+	}
+}
+
 // AuthWithProvideCreds calls the provided function for the username and password
 func (i *Irdata) AuthWithProvideCreds(authSource CredsProvider) error {
-	log.WithFields(log.Fields{"authSource": authSource}).Debug("Calling CredsProvider")
+	log.WithFields(logrus.Fields{"authSource": fmt.Sprintf("%T", authSource)}).Debug("Calling CredsProvider")
 
-	username, password, err := authSource.GetCreds()
+	authData, err := i.authDataFromProvider(authSource)
 	if err != nil {
 		return err
 	}
 
+	return i.auth(authData)
+}
+
+// ValidateCreds checks that the username and password from authSource can
+// log in without touching the client's own session: the client stays authed
+// (or not) as it was and nothing is saved.  Setup tools can use it to check
+// creds before saving them.
+func (i *Irdata) ValidateCreds(authSource CredsProvider) error {
+	log.WithFields(logrus.Fields{"authSource": fmt.Sprintf("%T", authSource)}).Debug("Calling CredsProvider")
+
+	authData, err := i.authDataFromProvider(authSource)
+	if err != nil {
+		return err
+	}
+
+	jar, err := newSessionJar()
+	if err != nil {
+		return err
+	}
+
+	i.authMu.Lock()
+
+	// logs in with its own cookies so the session it gets is thrown away
+	probe := &Irdata{
+		httpClient:      i.httpClient,
+		secureMemory:    i.secureMemory,
+		baseURL:         i.baseURL,
+		loginURL:        i.loginURL,
+		authMaxAttempts: i.authMaxAttempts,
+		authBackoff:     i.authBackoff,
+		httpDump:        i.httpDump,
+		jar:             jar,
+	}
+
+	i.authMu.Unlock()
+
+	i.headersMu.Lock()
+	probe.headers = i.headers.Clone()
+	i.headersMu.Unlock()
+
+	password := i.passwordSecret(authData.EncodedPassword)
+
+	defer password.Destroy()
+
+	return probe.login(context.Background(), authData.Username, password)
+}
+
+// authDataFromProvider calls the provider and encodes the password it returns
+func (i *Irdata) authDataFromProvider(authSource CredsProvider) (authDataT, error) {
 	var authData authDataT
 
+	username, password, masked, err := getCreds(authSource)
+	if err != nil {
+		return authData, err
+	}
+
+	if i.secureMemory {
+		defer shred(&password)
+	}
+
 	authData.Username = string(username)
+
+	if masked {
+		authData.EncodedPassword = string(password)
+		return authData, nil
+	}
+
 	authData.EncodedPassword, err = encodePassword(username, password)
 	if err != nil {
-		return err
+		return authData, err
 	}
 
-	return i.auth(authData)
+	return authData, nil
 }
 
 // AuthAndSaveProvidedCredsToFile calls the provided function for the
 // username and password, verifies auth, and then saves these credentials to
 // authFilename using the key in  keyFilename
 func (i *Irdata) AuthAndSaveProvidedCredsToFile(keyFilename string, authFilename string, authSource CredsProvider) error {
-	log.WithFields(log.Fields{"authSource": authSource}).Debug("Calling CredsProvider")
+	return i.AuthAndSaveProvidedCredsToFileWithKey(i.keyFromFile(keyFilename), authFilename, authSource)
+}
 
-	// check that the keyfile exists before collecting creds
-	_, err := getKey(keyFilename)
-	if err != nil {
-		return err
-	}
+// AuthAndSaveProvidedCredsToFileWithKey is AuthAndSaveProvidedCredsToFile
+// with the key supplied by keyProvider.
+func (i *Irdata) AuthAndSaveProvidedCredsToFileWithKey(keyProvider KeyProvider, authFilename string, authSource CredsProvider) error {
+	log.WithFields(logrus.Fields{"authSource": fmt.Sprintf("%T", authSource)}).Debug("Calling CredsProvider")
 
-	username, password, err := authSource.GetCreds()
+	// check that the key is available before collecting creds
+	key, err := strictKeys(keyProvider, i.strictSecurity).GetKey()
 	if err != nil {
 		return err
 	}
 
-	var authData authDataT
+	shred(&key)
 
-	authData.Username = string(username)
-	authData.EncodedPassword, err = encodePassword(username, password)
+	authData, err := i.authDataFromProvider(authSource)
 	if err != nil {
 		return err
 	}
@@ -89,155 +244,303 @@ func (i *Irdata) AuthAndSaveProvidedCredsToFile(keyFilename string, authFilename
 		return err
 	}
 
-	return writeCreds(keyFilename, authFilename, authData)
+	return writeCreds(keyProvider, authFilename, authData, i.fileOpts())
 }
 
-func writeCreds(keyFilename string, authFilename string, authData authDataT) error {
-	key, err := getKey(keyFilename)
-	if err != nil {
-		return err
+func writeCreds(keyProvider KeyProvider, authFilename string, authData authDataT, opts fileOptsT) error {
+	if authData.CreatedAt.IsZero() {
+		authData.CreatedAt = time.Now().UTC()
 	}
 
-	block, err := aes.NewCipher(key)
+	authData.Version = _authDataVersion
 
-	// not a defer because we want to do this right away
-	shred(&key)
+	buf := bytes.Buffer{}
 
-	if err != nil {
-		if errors.Is(err, aes.KeySizeError(0)) {
-			return makeErrorf("key must be 16, 24, or 32 bytes long")
-		} else {
-			return makeErrorf("unable to intialize AES cipher [%v]", err)
-		}
-	}
+	enc := gob.NewEncoder(&buf)
 
-	aesgcm, err := cipher.NewGCM(block)
+	err := enc.Encode(authData)
 	if err != nil {
-		return makeErrorf("unable to initialice GCM [%v]", err)
+		return makeErrorf("uanble to gob encode auth data %v", err)
 	}
 
-	nonce, err := makeNonce(aesgcm)
+	return encryptToFile(keyProvider, authFilename, buf.Bytes(), opts)
+}
+
+func readCreds(keyProvider KeyProvider, authFilename string, opts fileOptsT) (authDataT, error) {
+	var authData authDataT
+
+	authGob, err := decryptFromFile(keyProvider, authFilename, opts)
 	if err != nil {
-		return err
+		return authData, err
 	}
 
-	buf := bytes.Buffer{}
+	buf := bytes.NewReader(authGob)
 
-	enc := gob.NewEncoder(&buf)
+	dec := gob.NewDecoder(buf)
 
-	err = enc.Encode(authData)
+	err = dec.Decode(&authData)
 	if err != nil {
-		return makeErrorf("uanble to gob encode auth data %v", err)
+		return authData, makeErrorf("unable to gob decode [%v]", err)
 	}
 
-	data := aesgcm.Seal(nonce, nonce, buf.Bytes(), additionalContext)
+	if err := migrateAuthData(&authData); err != nil {
+		return authData, fmt.Errorf("%w (%s)", err, authFilename)
+	}
 
-	base64data := base64.StdEncoding.Strict().EncodeToString(data)
+	return authData, nil
+}
+
+// migrateAuthData brings creds written by older versions up to
+// _authDataVersion, and refuses creds written by newer versions rather than
+// decoding them into zero values
+func migrateAuthData(authData *authDataT) error {
+	if authData.Version > _authDataVersion {
+		return fmt.Errorf("%w: creds version %d", ErrUnsupportedFileVersion, authData.Version)
+	}
 
-	if err := os.WriteFile(authFilename, []byte(base64data), os.ModePerm); err != nil {
-		return makeErrorf("unable to write %s [%v]", authFilename, err)
+	if authData.Version == 0 {
+		// unversioned creds have the same fields, only CreatedAt may be zero
+		authData.Version = _authDataVersion
 	}
 
 	return nil
 }
 
-func readCreds(keyFilename string, authFilename string) (authDataT, error) {
-	var authData authDataT
+// authFlightT is a login in progress, callers that arrive while it's in
+// flight wait for it rather than logging in again
+type authFlightT struct {
+	done chan struct{}
+	err  error
+}
 
-	key, err := getKey(keyFilename)
-	if err != nil {
-		return authData, err
-	}
+// how long a login may take once the callers waiting for it have given up,
+// see singleFlightAuth
+const _sharedAuthTimeout = 2 * time.Minute
 
-	block, err := aes.NewCipher(key)
+// auth client, safe to call from several goroutines at once: only one login
+// is sent and the others share its result
+func (i *Irdata) auth(authData authDataT) error {
+	return i.singleFlightAuth(context.Background(), authData, i.passwordSecret(authData.EncodedPassword), false)
+}
 
-	// not a defer because we want to do this right away
-	shred(&key)
+// renew logs in again with the kept auth data after the session has expired
+func (i *Irdata) renew(ctx context.Context) error {
+	i.authMu.Lock()
 
-	if err != nil {
-		if errors.Is(err, aes.KeySizeError(0)) {
-			return authData, makeErrorf("key must be 16, 24, or 32 bytes long")
-		} else {
-			return authData, makeErrorf("unable to intialize AES cipher [%v]", err)
-		}
+	if i.authData == nil {
+		i.authMu.Unlock()
+		return ErrUnauthorized
 	}
 
-	aesgcm, err := cipher.NewGCM(block)
+	authData := *i.authData
+	password := i.newSecret(i.authPassword.Bytes())
 
-	if err != nil {
-		return authData, makeErrorf("unable to initialice GCM [%v]", err)
+	i.authMu.Unlock()
+
+	return i.singleFlightAuth(ctx, authData, password, true)
+}
+
+// singleFlightAuth logs in with authData's username and password, which it
+// takes ownership of: on success it's kept to renew the session, otherwise
+// it's destroyed.
+//
+// Callers stop waiting when their ctx is done.  The login itself is shared so
+// it doesn't belong to any one caller's ctx, it carries on (for up to
+// _sharedAuthTimeout) and its result is kept for the next caller.
+func (i *Irdata) singleFlightAuth(ctx context.Context, authData authDataT, password *secret, renewing bool) error {
+	i.authMu.Lock()
+
+	if i.isAuthed {
+		i.authMu.Unlock()
+		password.Destroy()
+		return nil
 	}
 
-	base64data, err := os.ReadFile(authFilename)
-	if err != nil {
-		return authData, makeErrorf("unable to read file %s [%v]", authFilename, err)
+	if flight := i.authFlight; flight != nil {
+		i.authMu.Unlock()
+
+		password.Destroy()
+
+		return waitForAuth(ctx, flight)
 	}
 
-	data, err := base64.StdEncoding.Strict().DecodeString(string(base64data))
-	if err != nil {
-		return authData, makeErrorf("unable to decode base64 creds [%v]", err)
+	flight := &authFlightT{done: make(chan struct{})}
+
+	i.authFlight = flight
+	session := i.session
+
+	i.authMu.Unlock()
+
+	go i.flyAuth(flight, session, authData, password, renewing)
+
+	return waitForAuth(ctx, flight)
+}
+
+func waitForAuth(ctx context.Context, flight *authFlightT) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-flight.done:
+		return flight.err
 	}
+}
 
-	authGob, err := aesgcm.Open(nil, data[:aesgcm.NonceSize()], data[aesgcm.NonceSize():], additionalContext)
-	if err != nil {
-		return authData, makeErrorf("unable to open aesgcm [%v]", err)
+// flyAuth sends the login for flight and lands its result
+func (i *Irdata) flyAuth(flight *authFlightT, session uint64, authData authDataT, password *secret, renewing bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), _sharedAuthTimeout)
+	defer cancel()
+
+	ctx, span := i.startSpan(ctx, TraceAuth, map[string]any{"renewing": renewing})
+
+	// the whole login belongs to the session it started in, see keepCookies
+	flight.err = i.login(withSession(ctx, session), authData.Username, password)
+
+	span.End(flight.err)
+
+	i.authMu.Lock()
+
+	i.authFlight = nil
+
+	if flight.err == nil && i.session != session {
+		flight.err = ErrLoggedOut
 	}
 
-	buf := bytes.NewReader(authGob)
+	if flight.err == nil {
+		i.isAuthed = true
+		i.keepAuthData(authData, password)
+		i.session++
+	} else {
+		password.Destroy()
+	}
 
-	dec := gob.NewDecoder(buf)
+	i.authMu.Unlock()
 
-	err = dec.Decode(&authData)
-	if err != nil {
-		return authData, makeErrorf("unable to gob decode [%v]", err)
+	// before waking the waiters so they see the events of the login they
+	// waited on (emitting doesn't block)
+	switch {
+	case renewing && flight.err == nil:
+		i.emitAuthEvent(AuthEventRenew, nil)
+	case renewing:
+		i.emitAuthEvent(AuthEventRenewFailed, flight.err)
+	case flight.err == nil:
+		i.emitAuthEvent(AuthEventLogin, nil)
+	default:
+		i.emitAuthEvent(AuthEventLoginFailed, flight.err)
 	}
 
-	return authData, nil
+	close(flight.done)
 }
 
-// auth client
-func (i *Irdata) auth(authData authDataT) error {
-	if i.isAuthed {
-		return nil
+// keepAuthData keeps authData to renew the session with.  Its encoded
+// password is kept in password (in a locked buffer in secure memory mode)
+// rather than as a string.  The caller must hold authMu.
+func (i *Irdata) keepAuthData(authData authDataT, password *secret) {
+	authData.EncodedPassword = ""
+
+	if i.authPassword != nil {
+		i.authPassword.Destroy()
 	}
 
-	if authData.EncodedPassword == "" {
-		return makeErrorf("must provide credentials before calling")
+	i.authData = &authData
+	i.authPassword = password
+}
+
+// dropAuthData forgets the kept auth data.  The caller must hold authMu.
+func (i *Irdata) dropAuthData() {
+	if i.authPassword != nil {
+		i.authPassword.Destroy()
 	}
 
-	log.Info("Authenticating")
+	i.authData = nil
+	i.authPassword = nil
+}
 
-	retries := 5
+// passwordSecret copies an encoded password into a secret
+func (i *Irdata) passwordSecret(encodedPassword string) *secret {
+	b := []byte(encodedPassword)
 
-	var err error
-	var resp *http.Response
+	defer shred(&b)
 
-	for retries > 0 {
-		resp, err = i.httpClient.Post(loginURL, "application/json",
-			strings.NewReader(
-				fmt.Sprintf("{\"email\": \"%s\" ,\"password\": \"%s\"}", authData.Username, authData.EncodedPassword),
-			),
-		)
+	return i.newSecret(b)
+}
 
-		if resp.StatusCode < 500 {
-			break
+// loginBody builds the body of a login request in a secret so that the
+// encoded password is never copied to the heap
+func (i *Irdata) loginBody(username string, password *secret) (*secret, error) {
+	email, err := json.Marshal(username)
+	if err != nil {
+		return nil, makeErrorf("unable to encode login request [%v]", err)
+	}
+
+	// encoded passwords are base64 so they don't need escaping
+	for _, b := range password.Bytes() {
+		if b < 0x20 || b == '"' || b == '\\' {
+			return nil, makeErrorf("encoded password isn't valid")
 		}
+	}
 
-		retries--
+	prefix := `{"email":` + string(email) + `,"password":"`
+	suffix := `"}`
 
-		backoff := time.Duration((6-retries)*5) * time.Second
+	body := i.allocSecret(len(prefix) + len(password.Bytes()) + len(suffix))
 
-		log.WithFields(log.Fields{"resp.StatusCode": resp.StatusCode, "backoff": backoff}).Warn(" *** Retrying Authentication due to error")
+	n := copy(body.Bytes(), prefix)
+	n += copy(body.Bytes()[n:], password.Bytes())
+	copy(body.Bytes()[n:], suffix)
 
-		time.Sleep(backoff)
+	return body, nil
+}
+
+// login posts username and password to the login endpoint and checks the
+// session works
+func (i *Irdata) login(ctx context.Context, username string, password *secret) error {
+	if len(password.Bytes()) == 0 {
+		return makeErrorf("must provide credentials before calling")
 	}
 
+	log.Info("Authenticating")
+
+	loginSecret, err := i.loginBody(username, password)
+	if err != nil {
+		return err
+	}
+
+	defer loginSecret.Destroy()
+
+	resp, err := i.authRetryingDo(ctx, i.loginURL, func() (*http.Response, error) {
+		req, err := i.newRequest(ctx, http.MethodPost, i.loginURL, bytes.NewReader(loginSecret.Bytes()))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+
+		return i.do(req)
+	})
+
 	if err != nil {
 		return makeErrorf("post to login failed %v", err)
 	}
 
+	body, err := io.ReadAll(io.LimitReader(resp.Body, _maxDrainBytes))
+
+	resp.Body.Close()
+
+	if err != nil {
+		return makeErrorf("unable to read login response [%v]", err)
+	}
+
+	if err := checkAuthResponse(body); err != nil {
+		log.WithFields(logrus.Fields{
+			"resp.StatusCode": resp.StatusCode,
+			"err":             err,
+		}).Warn("Login rejected")
+
+		return err
+	}
+
 	if resp.StatusCode != 200 {
-		log.WithFields(log.Fields{
+		log.WithFields(logrus.Fields{
 			"resp.Status":     resp.Status,
 			"resp.StatusCode": resp.StatusCode,
 		}).Warn("Failed to authenticate")
@@ -246,16 +549,27 @@ func (i *Irdata) auth(authData authDataT) error {
 	}
 
 	// test we are really auth'ed
-	resp, err = i.retryingGet(testUrl)
+	testUrl := i.baseURL.ResolveReference(&url.URL{Path: testURI}).String()
+
+	resp, err = i.authRetryingDo(ctx, testUrl, func() (*http.Response, error) {
+		req, err := i.newRequest(ctx, http.MethodGet, testUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.do(req)
+	})
 	if err != nil {
 		return err
 	}
 
+	drainAndClose(resp)
+
 	if resp.StatusCode != 200 {
 		if resp.StatusCode == 401 {
 			return makeErrorf("login failed, check creds")
 		} else {
-			log.WithFields(log.Fields{
+			log.WithFields(logrus.Fields{
 				"resp.Status":     resp.Status,
 				"resp.StatusCode": resp.StatusCode,
 				"testUrl":         testUrl,
@@ -267,26 +581,95 @@ func (i *Irdata) auth(authData authDataT) error {
 
 	log.Info("Login succeeded")
 
-	i.isAuthed = true
+	return nil
+}
+
+// Logout ends the session: the session cookies and the credentials kept for
+// renewing the session are dropped, so the client must auth again before it
+// can be used.  Creds files are left alone.
+//
+// Requests and logins still in progress don't undo it: the cookies their
+// responses set are dropped and a login fails with ErrLoggedOut.
+func (i *Irdata) Logout() error {
+	i.authMu.Lock()
+
+	if err := i.jar.reset(); err != nil {
+		i.authMu.Unlock()
+		return err
+	}
+
+	i.session++
+	i.isAuthed = false
+	i.dropAuthData()
+
+	i.authMu.Unlock()
+
+	log.Info("Logged out")
+
+	i.emitAuthEvent(AuthEventLogout, nil)
 
 	return nil
 }
 
+// checkAuthResponse looks for conditions reported in the body of the login
+// response that the status code alone doesn't reveal
+func checkAuthResponse(body []byte) error {
+	var authResponse authResponseT
+
+	if err := json.Unmarshal(body, &authResponse); err != nil {
+		// not json, leave it to the status checks
+		return nil
+	}
+
+	if authResponse.VerificationRequired {
+		return fmt.Errorf("%w [%s]", ErrVerificationRequired, authResponse.Message)
+	}
+
+	return nil
+}
+
+// MaskPassword returns password masked the way iRacing's login expects it
+// (the base64 encoded SHA-256 of the password followed by the lowercased
+// username).  Provisioning tools can mask a password up front and hand the
+// result to a CredsProvider in place of the password, wrapped in MaskedCreds
+// so that it's sent as is.
+//
 // See: https://forums.iracing.com/discussion/22109/login-form-changes/p1
-func encodePassword(username []byte, password []byte) (string, error) {
+func MaskPassword(password string, username string) string {
+	return maskPassword([]byte(username), []byte(password))
+}
+
+// IsMasked reports whether password looks like the output of MaskPassword,
+// i.e. the base64 encoding of 32 bytes.  A real password can look like that
+// too, so passwords are only taken as masked when wrapped in MaskedCreds.
+func IsMasked(password string) bool {
+	return isMasked([]byte(password))
+}
+
+func maskPassword(username []byte, password []byte) string {
 	hasher := sha256.New()
 
-	_, err := hasher.Write(password)
-	if err != nil {
-		return "", makeErrorf("unable to hash password to sha256 [%v]", err)
-	}
+	// writes to a hash never fail
+	hasher.Write(password)
+	hasher.Write([]byte(strings.ToLower(string(username))))
 
-	_, err = hasher.Write([]byte(strings.ToLower(string(username))))
-	if err != nil {
-		return "", makeErrorf("unable to hash username to sha256 [%v]", err)
+	return base64.StdEncoding.Strict().EncodeToString(hasher.Sum(nil))
+}
+
+func isMasked(password []byte) bool {
+	if len(password) != base64.StdEncoding.EncodedLen(sha256.Size) {
+		return false
 	}
 
-	return base64.StdEncoding.Strict().EncodeToString(hasher.Sum(nil)), nil
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(password)))
+
+	n, err := base64.StdEncoding.Strict().Decode(decoded, password)
+
+	return err == nil && n == sha256.Size
+}
+
+func encodePassword(username []byte, password []byte) (string, error) {
+	return maskPassword(username, password), nil
 }
 
 // nonce generator
@@ -300,13 +683,12 @@ func makeNonce(gcm cipher.AEAD) ([]byte, error) {
 
 // read secret key
 func getKey(keyFilename string) ([]byte, error) {
-	stat, err := os.Stat(keyFilename)
-	if err != nil {
-		return nil, makeErrorf("unable to stat %s [%v]", keyFilename, err)
-	}
+	return getKeyWithPolicy(keyFilename, KeyFilePermsRequire)
+}
 
-	if (stat.Mode() & os.ModePerm) != 0400 {
-		return nil, makeErrorf("key file %v must have perms set to 0400", keyFilename)
+func getKeyWithPolicy(keyFilename string, policy KeyFilePermissionPolicy) ([]byte, error) {
+	if err := applyKeyFilePolicy(keyFilename, policy); err != nil {
+		return nil, err
 	}
 
 	content, err := os.ReadFile(keyFilename)
@@ -5,9 +5,14 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -75,6 +80,43 @@ func TestEncodePassword(t *testing.T) {
 	assert.Equal(t, encodedPasswordExpected, encodedPasswordActual)
 }
 
+func TestMaskPassword(t *testing.T) {
+	masked := MaskPassword(string(testPassword), string(testUsername))
+
+	assert.Equal(t, "nKb060s95vcF0RpjfkGKapQG1o0AgbaPz10/H6QpHn4=", masked)
+	assert.True(t, IsMasked(masked))
+	assert.False(t, IsMasked(string(testPassword)))
+	assert.False(t, IsMasked("not base64 but 44 characters long, honest!!!"))
+
+	fake := mustOpen()
+
+	// a real password can look masked, it's only taken as masked when the
+	// provider says so
+	t.Setenv(DefaultUsernameEnv, string(testUsername))
+	t.Setenv(DefaultPasswordEnv, masked)
+
+	authData, err := fake.authDataFromProvider(CredsFromEnv{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, MaskPassword(masked, string(testUsername)), authData.EncodedPassword)
+
+	authData, err = fake.authDataFromProvider(MaskedCreds{Provider: CredsFromEnv{}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, masked, authData.EncodedPassword)
+
+	// also when chained
+	authData, err = fake.authDataFromProvider(ChainCredsProvider{failingCredsProvider{}, MaskedCreds{Provider: CredsFromEnv{}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, masked, authData.EncodedPassword)
+
+	// a password that isn't masked is refused rather than sent as is
+	_, err = fake.authDataFromProvider(MaskedCreds{Provider: testCredsProvider{}})
+
+	assert.Error(t, err)
+}
+
 func TestShredKey(t *testing.T) {
 	expectedKey := []byte{0, 1, 2, 3, 4, 5, 6, 7}
 
@@ -96,7 +138,7 @@ func TestShredKey(t *testing.T) {
 }
 
 func TestGetCreds(t *testing.T) {
-	auth, err := readCreds(testKeyFilename, testCredsFilename)
+	auth, err := readCreds(KeyFromFile(testKeyFilename), testCredsFilename, fileOptsT{})
 
 	assert.NoError(t, err)
 
@@ -129,12 +171,258 @@ func TestWriteCreds(t *testing.T) {
 
 	credsFn := filepath.Join(testAuthDir, "test.creds")
 
-	writeCreds(testKeyFilename, credsFn, *authDataExpected)
+	writeCreds(KeyFromFile(testKeyFilename), credsFn, *authDataExpected, fileOptsT{})
 
-	authDataActual, err := readCreds(testKeyFilename, credsFn)
+	authDataActual, err := readCreds(KeyFromFile(testKeyFilename), credsFn, fileOptsT{})
 
 	assert.NoError(t, err)
 
 	assert.Equal(t, authDataExpected.Username, authDataActual.Username)
 	assert.Equal(t, authDataExpected.EncodedPassword, authDataActual.EncodedPassword)
 }
+
+func TestCheckAuthResponse(t *testing.T) {
+	assert.NoError(t, checkAuthResponse([]byte(`{"authcode":"eyJ...","custId":123,"verificationRequired":false}`)))
+	assert.NoError(t, checkAuthResponse([]byte(`<html>not json</html>`)))
+
+	err := checkAuthResponse([]byte(`{"authcode":0,"message":"Please verify your account.","verificationRequired":true}`))
+
+	assert.ErrorIs(t, err, ErrVerificationRequired)
+	assert.Contains(t, err.Error(), "Please verify your account.")
+}
+
+func TestReadCredsTooShort(t *testing.T) {
+	setupAuthTest()
+	t.Cleanup(cleanupAuthTest)
+
+	credsFn := filepath.Join(testAuthDir, "short.creds")
+
+	assert.NoError(t, os.WriteFile(credsFn, []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0600))
+
+	_, err := readCreds(KeyFromFile(testKeyFilename), credsFn, fileOptsT{})
+
+	assert.Error(t, err)
+}
+
+func TestCredsAge(t *testing.T) {
+	setupAuthTest()
+	t.Cleanup(cleanupAuthTest)
+
+	credsFn := filepath.Join(testAuthDir, "old.creds")
+
+	createdAt := time.Now().Add(-48 * time.Hour)
+
+	assert.NoError(t, writeCreds(KeyFromFile(testKeyFilename), credsFn, authDataT{
+		Username:        string(testUsername),
+		EncodedPassword: "x",
+		CreatedAt:       createdAt,
+	}, fileOptsT{}))
+
+	authData, err := readCreds(KeyFromFile(testKeyFilename), credsFn, fileOptsT{})
+
+	assert.NoError(t, err)
+	assert.True(t, createdAt.Equal(authData.CreatedAt))
+
+	var warning *CredsAgeWarning
+
+	i.SetCredsAgeHook(func(w CredsAgeWarning) { warning = &w })
+	t.Cleanup(func() {
+		i.SetCredsAgeHook(nil)
+		i.SetCredsMaxAge(0)
+	})
+
+	i.SetCredsMaxAge(72 * time.Hour)
+	i.checkCredsAge(credsFn, authData)
+
+	assert.Nil(t, warning)
+
+	i.SetCredsMaxAge(24 * time.Hour)
+	i.checkCredsAge(credsFn, authData)
+
+	assert.NotNil(t, warning)
+	assert.Equal(t, credsFn, warning.Filename)
+	assert.Equal(t, 24*time.Hour, warning.MaxAge)
+	assert.Greater(t, warning.Age, 47*time.Hour)
+
+	// legacy creds have no creation time
+	legacy, err := readCreds(KeyFromFile(testKeyFilename), testCredsFilename, fileOptsT{})
+
+	assert.NoError(t, err)
+	assert.True(t, legacy.CreatedAt.IsZero())
+}
+
+func TestCredsVersion(t *testing.T) {
+	// the legacy test creds predate versioning and are migrated on read
+	legacy, err := readCreds(KeyFromFile(testKeyFilename), testCredsFilename, fileOptsT{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, _authDataVersion, legacy.Version)
+
+	credsFn := filepath.Join(t.TempDir(), "creds")
+
+	assert.NoError(t, writeCreds(testKeyProvider{}, credsFn, authDataT{Username: "user", EncodedPassword: "encoded"}, fileOptsT{}))
+
+	authData, err := readCreds(testKeyProvider{}, credsFn, fileOptsT{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, _authDataVersion, authData.Version)
+
+	// creds from the future aren't guessed at
+	assert.ErrorIs(t, migrateAuthData(&authDataT{Version: _authDataVersion + 1}), ErrUnsupportedFileVersion)
+}
+
+func TestLogout(t *testing.T) {
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "authtoken_members", Value: "abc", Path: "/"})
+		fmt.Fprint(w, `{}`)
+	}))
+
+	fake.keepAuthData(authDataT{Username: "user"}, fake.passwordSecret("encoded"))
+
+	_, err := fake.Get("/data/member/info")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, fake.jar.Cookies(fake.baseURL))
+
+	assert.NoError(t, fake.Logout())
+
+	assert.False(t, fake.isAuthed)
+	assert.Nil(t, fake.authData)
+	assert.Empty(t, fake.jar.Cookies(fake.baseURL))
+
+	_, err = fake.Get("/data/member/info")
+	assert.Error(t, err)
+}
+
+func TestValidateCreds(t *testing.T) {
+	setupRetryTest(t)
+
+	logins := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: "authtoken_members", Value: "abc", Path: "/"})
+			fmt.Fprint(w, `{}`)
+		default:
+			if _, err := r.Cookie("authtoken_members"); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+
+	fake.isAuthed = false
+
+	assert.NoError(t, fake.ValidateCreds(testCredsProvider{}))
+	assert.Equal(t, 1, logins)
+
+	// the client's own session is untouched
+	assert.False(t, fake.isAuthed)
+	assert.Nil(t, fake.authData)
+	assert.Empty(t, fake.jar.Cookies(fake.baseURL))
+}
+
+func TestValidateCredsRejected(t *testing.T) {
+	setupRetryTest(t)
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/auth" {
+			fmt.Fprint(w, `{}`)
+			return
+		}
+
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	assert.Error(t, fake.ValidateCreds(testCredsProvider{}))
+}
+
+// blockingTransport answers every request itself, setting a session cookie,
+// once release is closed.  Each request is sent on arrived as it comes in.
+type blockingTransport struct {
+	arrived chan *http.Request
+	release chan struct{}
+}
+
+func newBlockingTransport() *blockingTransport {
+	return &blockingTransport{
+		arrived: make(chan *http.Request, 10),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b.arrived <- req
+
+	<-b.release
+
+	header := http.Header{}
+	header.Add("Set-Cookie", "authtoken_members=abc; Path=/")
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestLogoutWhileGetting(t *testing.T) {
+	fake := fakeAPI(t, http.NotFoundHandler())
+
+	transport := newBlockingTransport()
+
+	fake.SetTransport(transport)
+
+	errs := make(chan error)
+
+	go func() {
+		_, err := fake.Get("/data/member/info")
+		errs <- err
+	}()
+
+	<-transport.arrived
+
+	assert.NoError(t, fake.Logout())
+
+	close(transport.release)
+
+	// the data still comes back but the cookie it set is for a session
+	// that's gone
+	assert.NoError(t, <-errs)
+	assert.False(t, fake.isAuthed)
+	assert.Empty(t, fake.jar.Cookies(fake.baseURL))
+}
+
+func TestLogoutWhileAuthing(t *testing.T) {
+	fake := fakeAPI(t, http.NotFoundHandler())
+	fake.isAuthed = false
+
+	transport := newBlockingTransport()
+
+	fake.SetTransport(transport)
+
+	errs := make(chan error)
+
+	go func() {
+		errs <- fake.auth(authDataT{Username: "user", EncodedPassword: "encoded"})
+	}()
+
+	login := <-transport.arrived
+
+	assert.Equal(t, http.MethodPost, login.Method)
+
+	assert.NoError(t, fake.Logout())
+
+	close(transport.release)
+
+	assert.ErrorIs(t, <-errs, ErrLoggedOut)
+
+	// the login that finished after the logout didn't log the client back in
+	assert.False(t, fake.isAuthed)
+	assert.Nil(t, fake.authData)
+	assert.Empty(t, fake.jar.Cookies(fake.baseURL))
+}
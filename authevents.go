@@ -0,0 +1,65 @@
+package irdata
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const _authEventsBuffer = 64
+
+// AuthEventType is the type of an AuthEvent
+type AuthEventType string
+
+const (
+	AuthEventLogin       AuthEventType = "login"
+	AuthEventLoginFailed AuthEventType = "login_failed"
+	AuthEventRenew       AuthEventType = "renew" // the session expired and the client logged in again
+	AuthEventRenewFailed AuthEventType = "renew_failed"
+	AuthEventLogout      AuthEventType = "logout"
+)
+
+// AuthEvent reports a change to the client's session.  Error is set for the
+// failed types.
+type AuthEvent struct {
+	Type  AuthEventType
+	Error error
+	At    time.Time
+}
+
+// AuthEvents returns a channel that reports logins, session renewals (the
+// client logging in again with the saved creds after its session expired),
+// failures of either, and logouts.  Services can use it to alarm when
+// sessions keep expiring.
+//
+// No events are kept until AuthEvents is first called.  If events aren't
+// read fast enough the channel's buffer fills up and further events are
+// dropped with a warning.
+func (i *Irdata) AuthEvents() <-chan AuthEvent {
+	i.authMu.Lock()
+	defer i.authMu.Unlock()
+
+	if i.authEvents == nil {
+		i.authEvents = make(chan AuthEvent, _authEventsBuffer)
+	}
+
+	return i.authEvents
+}
+
+func (i *Irdata) emitAuthEvent(eventType AuthEventType, err error) {
+	i.authMu.Lock()
+	events := i.authEvents
+	i.authMu.Unlock()
+
+	if events == nil {
+		return
+	}
+
+	select {
+	case events <- AuthEvent{Type: eventType, Error: err, At: time.Now()}:
+	default:
+		log.WithFields(logrus.Fields{
+			"type": eventType,
+		}).Warn("Auth events channel is full, dropping event")
+	}
+}
@@ -0,0 +1,63 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthEvents(t *testing.T) {
+	setupRetryTest(t)
+
+	loggedIn := false
+	reject := false
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/auth":
+			loggedIn = !reject
+			fmt.Fprint(w, `{}`)
+		case !loggedIn:
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+
+	fake.isAuthed = false
+
+	// nothing is kept until asked for
+	assert.NoError(t, fake.AuthWithProvideCreds(testCredsProvider{}))
+	assert.Nil(t, fake.authEvents)
+
+	events := fake.AuthEvents()
+
+	// the session expires
+	loggedIn = false
+
+	_, err := fake.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	loggedIn = false
+	reject = true
+
+	_, err = fake.Get("/data/member/info")
+	assert.Error(t, err)
+
+	assert.NoError(t, fake.Logout())
+
+	assert.Equal(t, AuthEventRenew, (<-events).Type)
+
+	event := <-events
+
+	assert.Equal(t, AuthEventRenewFailed, event.Type)
+	assert.Error(t, event.Error)
+	assert.False(t, event.At.IsZero())
+
+	assert.Equal(t, AuthEventLogout, (<-events).Type)
+
+	assert.Error(t, fake.AuthWithProvideCreds(testCredsProvider{}))
+	assert.Equal(t, AuthEventLoginFailed, (<-events).Type)
+}
@@ -0,0 +1,102 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net/http"
+)
+
+// authStateT is what ExportAuthState saves: the creds to renew the session
+// with and the session cookies themselves
+type authStateT struct {
+	AuthData authDataT
+	Cookies  []*http.Cookie
+}
+
+// ExportAuthState returns the client's session and creds encrypted with the
+// key from keyProvider, for environments without a filesystem to keep a creds
+// file in (e.g. serverless functions that park it in a secrets manager
+// between invocations).  ImportAuthState restores it.
+//
+// The client must be authed.  The state holds the encoded password, so treat
+// it as you would a creds file.
+func (i *Irdata) ExportAuthState(keyProvider KeyProvider) ([]byte, error) {
+	i.authMu.Lock()
+
+	if !i.isAuthed || i.authData == nil {
+		i.authMu.Unlock()
+		return nil, makeErrorf("must auth first")
+	}
+
+	state := authStateT{
+		AuthData: *i.authData,
+		Cookies:  i.jar.Cookies(i.baseURL),
+	}
+
+	state.AuthData.EncodedPassword = string(i.authPassword.Bytes())
+
+	i.authMu.Unlock()
+
+	buf := bytes.Buffer{}
+
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return nil, makeErrorf("unable to gob encode auth state [%v]", err)
+	}
+
+	plaintext := buf.Bytes()
+
+	defer shred(&plaintext)
+
+	key, err := strictKeys(keyProvider, i.strictSecurity).GetKey()
+	if err != nil {
+		return nil, err
+	}
+
+	defer shred(&key)
+
+	return seal(key, plaintext, i.credsIdentity)
+}
+
+// ImportAuthState authenticates the client with state returned by
+// ExportAuthState.  The saved session is reused without logging in; if it
+// has expired Get logs in again with the saved creds.
+func (i *Irdata) ImportAuthState(keyProvider KeyProvider, data []byte) error {
+	key, err := strictKeys(keyProvider, i.strictSecurity).GetKey()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := unseal(key, data, i.credsIdentity)
+
+	shred(&key)
+
+	if err != nil {
+		return err
+	}
+
+	defer shred(&plaintext)
+
+	var state authStateT
+
+	if err := gob.NewDecoder(bytes.NewReader(plaintext)).Decode(&state); err != nil {
+		return makeErrorf("unable to gob decode auth state [%v]", err)
+	}
+
+	if err := migrateAuthData(&state.AuthData); err != nil {
+		return err
+	}
+
+	if state.AuthData.EncodedPassword == "" {
+		return makeErrorf("auth state has no credentials")
+	}
+
+	i.authMu.Lock()
+	defer i.authMu.Unlock()
+
+	i.jar.SetCookies(i.baseURL, state.Cookies)
+	i.isAuthed = true
+	i.keepAuthData(state.AuthData, i.passwordSecret(state.AuthData.EncodedPassword))
+	i.session++
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthState(t *testing.T) {
+	setupRetryTest(t)
+
+	logins := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: "authtoken_members", Value: "abc", Path: "/"})
+			fmt.Fprint(w, `{}`)
+		default:
+			if _, err := r.Cookie("authtoken_members"); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			fmt.Fprint(w, `{"ok":true}`)
+		}
+	}))
+
+	_, err := fake.ExportAuthState(testKeyProvider{})
+	assert.Error(t, err)
+
+	fake.isAuthed = false
+
+	assert.NoError(t, fake.AuthWithProvideCreds(testCredsProvider{}))
+	assert.Equal(t, 1, logins)
+
+	state, err := fake.ExportAuthState(testKeyProvider{})
+	assert.NoError(t, err)
+
+	restored := mustOpen()
+
+	assert.NoError(t, restored.SetBaseURL(fake.baseURL.String()))
+	assert.NoError(t, restored.SetLoginURL(fake.loginURL))
+
+	assert.Error(t, restored.ImportAuthState(staticKey(make([]byte, 32)), state))
+	assert.NoError(t, restored.ImportAuthState(testKeyProvider{}, state))
+
+	// the saved session is reused
+	data, err := restored.Get("/data/member/info")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, "user@example.com", restored.authData.Username)
+}
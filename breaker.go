@@ -0,0 +1,76 @@
+package irdata
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// breakerT counts consecutive failures and opens after threshold of them,
+// failing requests fast until coolDown has passed.  After that requests are
+// let through again and a single failure opens it again.
+type breakerT struct {
+	threshold int
+	coolDown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// SetCircuitBreaker makes requests fail fast with ErrCircuitOpen for
+// coolDown after failures consecutive attempts have failed with a 5xx or a
+// network error, sparing both the client and iRacing during outages.
+// failures below 1 turns the breaker off (the default).
+func (i *Irdata) SetCircuitBreaker(failures int, coolDown time.Duration) {
+	if failures < 1 {
+		i.breaker = nil
+		return
+	}
+
+	i.breaker = &breakerT{threshold: failures, coolDown: coolDown}
+}
+
+// allow returns ErrCircuitOpen if the breaker is open at now
+func (b *breakerT) allow(now time.Time) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Before(b.openUntil) {
+		return fmt.Errorf("%w until %s", ErrCircuitOpen, b.openUntil.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// record counts the outcome of an attempt made at now
+func (b *breakerT) record(failed bool, now time.Time) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.coolDown)
+
+		log.WithFields(logrus.Fields{
+			"failures":  b.failures,
+			"openUntil": b.openUntil,
+		}).Warn("Circuit breaker opened")
+	}
+}
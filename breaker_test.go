@@ -0,0 +1,73 @@
+package irdata
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	b := &breakerT{threshold: 2, coolDown: time.Minute}
+
+	b.record(true, now)
+	assert.NoError(t, b.allow(now))
+
+	// a success resets the count
+	b.record(false, now)
+	b.record(true, now)
+	assert.NoError(t, b.allow(now))
+
+	b.record(true, now)
+	assert.ErrorIs(t, b.allow(now), ErrCircuitOpen)
+	assert.ErrorIs(t, b.allow(now.Add(59*time.Second)), ErrCircuitOpen)
+
+	// after the cool down requests go through, one more failure opens it again
+	later := now.Add(time.Minute)
+
+	assert.NoError(t, b.allow(later))
+
+	b.record(true, later)
+	assert.ErrorIs(t, b.allow(later), ErrCircuitOpen)
+
+	// off
+	var off *breakerT
+
+	off.record(true, now)
+	assert.NoError(t, off.allow(now))
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	setupRetryTest(t)
+
+	var calls int32
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+
+	fake.SetCircuitBreaker(2, time.Hour)
+
+	_, err := fake.Get("/data/member/info")
+
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// fails fast
+	_, err = fake.Get("/data/member/info")
+
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	fake.SetCircuitBreaker(0, 0)
+
+	_, err = fake.Get("/data/member/info")
+
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.Greater(t, atomic.LoadInt32(&calls), int32(2))
+}
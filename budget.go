@@ -0,0 +1,187 @@
+package irdata
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// budgetsT splits each rate limit window among uri prefixes
+type budgetsT struct {
+	prefixes  []string // longest first so the most specific matches
+	fractions map[string]float64
+
+	mu     sync.Mutex
+	window time.Time // the reset of the window being counted
+	used   map[string]int
+
+	// the window being counted started at window rather than ending then:
+	// the rate limit has reset but the new window hasn't been reported yet
+	afterReset bool
+}
+
+// SetRateBudgets gives uri prefixes a fraction of the rate limit each, e.g.
+//
+//	api.SetRateBudgets(map[string]float64{"/data/results": 0.7})
+//
+// lets requests under /data/results use 70% of every rate limit window and
+// the rest of the requests the other 30%.  A request whose prefix has used
+// its share waits for the window to reset (see SetMaxRateLimitWait).  A uri
+// counts against the longest prefix it matches, uris that match none share
+// what's left over.  Budgets take effect once iRacing has reported the limit
+// (see RateLimitStatus); the requests made in a window before then are taken
+// to have been spread over the budgets by their fractions.  The fractions
+// must add up to no more than 1; nil removes the budgets.
+func (i *Irdata) SetRateBudgets(budgets map[string]float64) error {
+	if len(budgets) == 0 {
+		i.budgets = nil
+		return nil
+	}
+
+	b := &budgetsT{fractions: map[string]float64{}, used: map[string]int{}}
+
+	total := 0.0
+
+	for prefix, fraction := range budgets {
+		if prefix == "" || fraction < 0 {
+			return makeErrorf("invalid rate budget %q: %v", prefix, fraction)
+		}
+
+		b.prefixes = append(b.prefixes, prefix)
+		b.fractions[prefix] = fraction
+
+		total += fraction
+	}
+
+	if total > 1 {
+		return makeErrorf("rate budgets add up to %v, more than 1", total)
+	}
+
+	// the rest
+	b.fractions[""] = 1 - total
+
+	sort.Slice(b.prefixes, func(m, n int) bool {
+		return len(b.prefixes[m]) > len(b.prefixes[n])
+	})
+
+	i.budgets = b
+
+	return nil
+}
+
+// prefixOf returns the budget path counts against, "" for the rest
+func (b *budgetsT) prefixOf(path string) string {
+	for _, prefix := range b.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return prefix
+		}
+	}
+
+	return ""
+}
+
+// take counts a request for path against its budget in status's window and
+// returns how long it has to wait if the budget is used up.  Once the window
+// has reset, requests are counted against the next one and let through until
+// it's reported.
+func (b *budgetsT) take(path string, status RateLimit, now time.Time) (prefix string, wait time.Duration) {
+	prefix = b.prefixOf(path)
+
+	if status.Limit <= 0 || status.Reset.IsZero() {
+		return prefix, 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Before(status.Reset) {
+		if !b.window.Equal(status.Reset) || b.afterReset {
+			// what was counted since the reset belongs to this window
+			if !b.afterReset || !b.window.Before(status.Reset) {
+				b.used = map[string]int{}
+			}
+
+			b.window = status.Reset
+			b.afterReset = false
+
+			b.seed(status)
+		}
+	} else if !b.window.Equal(status.Reset) || !b.afterReset {
+		b.window = status.Reset
+		b.afterReset = true
+		b.used = map[string]int{}
+	}
+
+	// allow for fractions like 1-0.7 coming out a hair under 0.3
+	allowed := int(b.fractions[prefix]*float64(status.Limit) + 1e-9)
+
+	if b.used[prefix] >= allowed && !b.afterReset {
+		return prefix, status.Reset.Sub(now)
+	}
+
+	b.used[prefix]++
+
+	return prefix, 0
+}
+
+// seed counts the requests status says were made in its window that haven't
+// been counted, spread over the budgets by their fractions.  The caller must
+// hold mu.
+func (b *budgetsT) seed(status RateLimit) {
+	counted := 0
+
+	for _, used := range b.used {
+		counted += used
+	}
+
+	uncounted := status.Limit - status.Remaining - counted
+	if uncounted <= 0 {
+		return
+	}
+
+	for prefix, fraction := range b.fractions {
+		b.used[prefix] += int(fraction*float64(uncounted) + 0.5)
+	}
+}
+
+// waitForBudget waits for the rate limit to reset if rawURL's budget is used up
+func (i *Irdata) waitForBudget(ctx context.Context, rawURL string) error {
+	if i.budgets == nil {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	// after waiting the request is counted in the new window
+	for {
+		status, _ := i.RateLimitStatus()
+
+		prefix, wait := i.budgets.take(u.Path, status, time.Now())
+		if wait == 0 {
+			return nil
+		}
+
+		if i.maxRateLimitWait > 0 && wait > i.maxRateLimitWait {
+			return &RateLimitExceededError{Wait: wait, MaxWait: i.maxRateLimitWait, Reset: status.Reset}
+		}
+
+		i.rateLimited(wait)
+
+		log.WithFields(logrus.Fields{
+			"prefix": prefix,
+			"wait":   wait,
+		}).Info("Rate budget used up, waiting for the reset")
+
+		if err := sleepCtx(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
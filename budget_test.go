@@ -0,0 +1,153 @@
+package irdata
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRateBudgets(t *testing.T) {
+	client := mustOpen()
+
+	assert.Error(t, client.SetRateBudgets(map[string]float64{"/data/results": 0.7, "/data/member": 0.4}))
+	assert.Error(t, client.SetRateBudgets(map[string]float64{"/data/results": -1}))
+	assert.Error(t, client.SetRateBudgets(map[string]float64{"": 0.5}))
+
+	assert.NoError(t, client.SetRateBudgets(map[string]float64{"/data/results": 0.7, "/data/results/lap_data": 0.1}))
+	assert.Equal(t, "/data/results/lap_data", client.budgets.prefixOf("/data/results/lap_data"))
+	assert.Equal(t, "/data/results", client.budgets.prefixOf("/data/results/get"))
+	assert.Equal(t, "", client.budgets.prefixOf("/data/member/info"))
+
+	assert.NoError(t, client.SetRateBudgets(nil))
+	assert.Nil(t, client.budgets)
+}
+
+func TestBudgetsTake(t *testing.T) {
+	client := mustOpen()
+
+	client.SetRateBudgets(map[string]float64{"/data/results": 0.7})
+
+	now := time.Now()
+
+	status := RateLimit{Limit: 10, Remaining: 10, Reset: now.Add(time.Minute)}
+
+	for n := 0; n < 7; n++ {
+		_, wait := client.budgets.take("/data/results/get", status, now)
+		assert.Zero(t, wait)
+	}
+
+	_, wait := client.budgets.take("/data/results/get", status, now)
+	assert.Equal(t, time.Minute, wait)
+
+	// the rest still have theirs
+	for n := 0; n < 3; n++ {
+		_, wait := client.budgets.take("/data/member/info", status, now)
+		assert.Zero(t, wait)
+	}
+
+	_, wait = client.budgets.take("/data/member/info", status, now)
+	assert.Equal(t, time.Minute, wait)
+
+	// a new window
+	status.Reset = now.Add(2 * time.Minute)
+
+	_, wait = client.budgets.take("/data/results/get", status, now)
+	assert.Zero(t, wait)
+
+	// the limit isn't known
+	_, wait = client.budgets.take("/data/member/info", RateLimit{}, now)
+	assert.Zero(t, wait)
+}
+
+func TestBudgetsTakeAfterReset(t *testing.T) {
+	client := mustOpen()
+
+	client.SetRateBudgets(map[string]float64{"/data/results": 0.7})
+
+	now := time.Now()
+
+	status := RateLimit{Limit: 10, Remaining: 10, Reset: now.Add(time.Minute)}
+
+	// the window has reset but the next one hasn't been reported, requests
+	// are let through and counted
+	later := status.Reset.Add(time.Second)
+
+	for n := 0; n < 7; n++ {
+		_, wait := client.budgets.take("/data/results/get", status, later)
+		assert.Zero(t, wait)
+	}
+
+	// and count against the window once it is
+	status = RateLimit{Limit: 10, Remaining: 3, Reset: later.Add(time.Minute)}
+
+	_, wait := client.budgets.take("/data/results/get", status, later)
+	assert.Equal(t, time.Minute, wait)
+
+	_, wait = client.budgets.take("/data/member/info", status, later)
+	assert.Zero(t, wait)
+}
+
+func TestBudgetsTakeSeeded(t *testing.T) {
+	client := mustOpen()
+
+	client.SetRateBudgets(map[string]float64{"/data/results": 0.7})
+
+	now := time.Now()
+
+	// 6 requests were made in the window before it was first seen, 4 of
+	// them are taken to be /data/results ones
+	status := RateLimit{Limit: 10, Remaining: 4, Reset: now.Add(time.Minute)}
+
+	for n := 0; n < 3; n++ {
+		_, wait := client.budgets.take("/data/results/get", status, now)
+		assert.Zero(t, wait)
+	}
+
+	_, wait := client.budgets.take("/data/results/get", status, now)
+	assert.Equal(t, time.Minute, wait)
+
+	_, wait = client.budgets.take("/data/member/info", status, now)
+	assert.Zero(t, wait)
+
+	_, wait = client.budgets.take("/data/member/info", status, now)
+	assert.Equal(t, time.Minute, wait)
+}
+
+func TestRateBudgetsGet(t *testing.T) {
+	var reset time.Time
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Limit", "4")
+		w.Header().Set("X-Ratelimit-Remaining", "4")
+		w.Header().Set("X-Ratelimit-Reset", fmt.Sprint(reset.Unix()))
+
+		fmt.Fprint(w, `{}`)
+	}))
+
+	reset = time.Now().Add(time.Hour)
+
+	fake.SetRateBudgets(map[string]float64{"/data/results": 0.5})
+	fake.SetMaxRateLimitWait(time.Minute)
+
+	// learns the limit
+	_, err := fake.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	for n := 0; n < 2; n++ {
+		_, err = fake.Get(fmt.Sprintf("/data/results/get?subsession_id=%d", n))
+		assert.NoError(t, err)
+	}
+
+	_, err = fake.Get("/data/results/get?subsession_id=3")
+
+	var rateLimitErr *RateLimitExceededError
+
+	assert.True(t, errors.As(err, &rateLimitErr))
+
+	_, err = fake.Get("/data/member/info")
+	assert.NoError(t, err)
+}
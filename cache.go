@@ -1,12 +1,15 @@
 package irdata
 
 import (
+	"bytes"
 	"crypto/md5"
+	"encoding/binary"
 	"errors"
+	"net/url"
 	"time"
 
 	"git.mills.io/prologic/bitcask"
-	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus"
 )
 
 const _maxValueSize = 1024 * 1024 * 256 // 256MB
@@ -48,25 +51,151 @@ func (i *Irdata) cacheClose() {
 	log.Info("Done")
 }
 
+// SetCacheIgnoredParams sets query parameters that don't change the result
+// (e.g. a cache buster) so they're left out of cache keys and uris that only
+// differ by them share cache entries
+func (i *Irdata) SetCacheIgnoredParams(params ...string) {
+	i.cacheIgnoredParams = params
+}
+
+// cacheKey normalizes uri so that equivalent uris share a cache entry: query
+// parameters are sorted and the ignored ones (see SetCacheIgnoredParams)
+// dropped
+func (i *Irdata) cacheKey(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.RawQuery == "" {
+		return uri
+	}
+
+	query := u.Query()
+
+	for _, param := range i.cacheIgnoredParams {
+		query.Del(param)
+	}
+
+	// Encode sorts by parameter
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
 func hashKey(key string) hashedKey {
 	hash := md5.Sum([]byte(key))
 	return hash[:]
 }
 
-func (i *Irdata) getCachedData(key string) ([]byte, error) {
-	data, err := i.cask.Get(hashKey(key))
+// cache entries start with a magic, when they expire (unix nanoseconds, big
+// endian), and (since irc2) the name of the compression of the data that
+// follows, as a length byte and the name.  Entries written before there was
+// a header are plain data, json never starts with a NUL.
+var (
+	_cacheEntryMagicV1 = []byte("\x00irc1")
+	_cacheEntryMagic   = []byte("\x00irc2")
+)
+
+type cacheEntryT struct {
+	data    []byte
+	expires time.Time // zero for entries written without a header
+}
+
+// encodeCacheEntry encodes entry, whose data was compressed with compression
+// ("" for none)
+func encodeCacheEntry(entry cacheEntryT, compression string) []byte {
+	value := make([]byte, 0, len(_cacheEntryMagic)+8+1+len(compression)+len(entry.data))
+
+	value = append(value, _cacheEntryMagic...)
+	value = binary.BigEndian.AppendUint64(value, uint64(entry.expires.UnixNano()))
+	value = append(value, byte(len(compression)))
+	value = append(value, compression...)
+	value = append(value, entry.data...)
+
+	return value
+}
+
+// decodeCacheEntry decodes value and returns the name of the compression of
+// the entry's data
+func decodeCacheEntry(value []byte) (entry cacheEntryT, compression string) {
+	switch {
+	case bytes.HasPrefix(value, _cacheEntryMagic) && len(value) >= len(_cacheEntryMagic)+9:
+		value = value[len(_cacheEntryMagic):]
+
+		entry.expires = time.Unix(0, int64(binary.BigEndian.Uint64(value)))
+
+		value = value[8:]
+
+		nameLen := int(value[0])
+
+		if len(value) < 1+nameLen {
+			return cacheEntryT{data: value}, ""
+		}
+
+		compression = string(value[1 : 1+nameLen])
+		entry.data = value[1+nameLen:]
+
+		return entry, compression
+	case bytes.HasPrefix(value, _cacheEntryMagicV1) && len(value) >= len(_cacheEntryMagicV1)+8:
+		value = value[len(_cacheEntryMagicV1):]
+
+		return cacheEntryT{
+			data:    value[8:],
+			expires: time.Unix(0, int64(binary.BigEndian.Uint64(value))),
+		}, ""
+	}
+
+	return cacheEntryT{data: value}, ""
+}
+
+// stale is true once the entry's ttl has passed, entries can be kept past
+// it (see SetStaleCacheFallback)
+func (e cacheEntryT) stale(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// getCacheEntry returns the entry for key, stale or not, found is false if
+// there isn't one
+func (i *Irdata) getCacheEntry(key string) (entry cacheEntryT, found bool, err error) {
+	value, err := i.cask.Get(hashKey(key))
 
 	if errors.Is(err, bitcask.ErrKeyExpired) || errors.Is(err, bitcask.ErrKeyNotFound) {
-		return nil, nil
+		return cacheEntryT{}, false, nil
 	} else if err != nil {
-		return nil, makeErrorf("cache get error for %s [%v]", key, err)
+		return cacheEntryT{}, false, makeErrorf("cache get error for %s [%v]", key, err)
 	}
 
-	return data, nil
+	entry, compression := decodeCacheEntry(value)
+
+	entry.data, err = i.decompressCacheData(entry.data, compression)
+	if err != nil {
+		// as good as not cached, it'll be replaced
+		log.WithFields(logrus.Fields{"key": key, "err": err}).Warn("Unable to decompress cached data")
+
+		return cacheEntryT{}, false, nil
+	}
+
+	return entry, true, nil
+}
+
+// getCachedData returns the data cached for key, nil if there's none or it
+// has expired
+func (i *Irdata) getCachedData(key string) ([]byte, error) {
+	entry, found, err := i.getCacheEntry(key)
+	if err != nil || !found || entry.stale(time.Now()) {
+		return nil, err
+	}
+
+	return entry.data, nil
 }
 
 func (i *Irdata) setCachedData(key string, data []byte, ttl time.Duration) error {
-	err := i.cask.PutWithTTL(hashKey(key), data, ttl)
+	data, compression, err := i.compressCacheData(data)
+	if err != nil {
+		return makeErrorf("cache compression error for %s [%v]", key, err)
+	}
+
+	value := encodeCacheEntry(cacheEntryT{data: data, expires: time.Now().Add(ttl)}, compression)
+
+	// kept past its ttl to fall back on if need be
+	err = i.cask.PutWithTTL(hashKey(key), value, ttl+i.cacheMaxStale)
 	if err != nil {
 		return makeErrorf("cache put error for %s [%v]", key, err)
 	}
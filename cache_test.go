@@ -1,6 +1,8 @@
 package irdata
 
 import (
+	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
@@ -89,3 +91,42 @@ func TestDelete(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Nil(t, data)
 }
+
+func TestCacheKey(t *testing.T) {
+	client := mustOpen()
+
+	assert.Equal(t, "/data/member/info", client.cacheKey("/data/member/info"))
+	assert.Equal(t, "/data/results/get?a=1&b=2", client.cacheKey("/data/results/get?b=2&a=1"))
+	assert.Equal(t, client.cacheKey("/data/results/get?a=1&b=2"), client.cacheKey("/data/results/get?b=2&a=1"))
+	assert.NotEqual(t, client.cacheKey("/data/results/get?a=1&b=2"), client.cacheKey("/data/results/get?a=1&b=3"))
+
+	client.SetCacheIgnoredParams("_")
+
+	assert.Equal(t, "/data/results/get?a=1", client.cacheKey("/data/results/get?_=123&a=1"))
+	assert.Equal(t, "/data/results/get", client.cacheKey("/data/results/get?_=123"))
+}
+
+func TestGetWithCacheNormalizesKey(t *testing.T) {
+	requests := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{}`)
+	}))
+
+	assert.NoError(t, fake.EnableCache(filepath.Join(t.TempDir(), "cache")))
+	t.Cleanup(fake.Close)
+
+	fake.SetCacheIgnoredParams("nocache")
+
+	for _, uri := range []string{
+		"/data/results/get?subsession_id=1&include_licenses=true",
+		"/data/results/get?include_licenses=true&subsession_id=1",
+		"/data/results/get?include_licenses=true&nocache=42&subsession_id=1",
+	} {
+		_, err := fake.GetWithCache(uri, testTtl)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, requests)
+}
@@ -0,0 +1,141 @@
+package irdata
+
+import (
+	"encoding/gob"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// cache archives are a gob encoded header followed by one gob encoded entry
+// per cached value
+const (
+	_cacheArchiveMagic   = "irdata-cache"
+	_cacheArchiveVersion = 1
+)
+
+type cacheArchiveHeaderT struct {
+	Magic   string
+	Version int
+}
+
+type cacheArchiveEntryT struct {
+	Key     []byte    // hashed, uris aren't kept in the cache
+	Value   []byte    // as stored, compressed
+	Expires time.Time // when the entry's ttl runs out
+}
+
+// ExportCache writes the entries in the cache, with when they expire, to w
+// as an archive ImportCache can load into another cache, e.g. to seed a CI
+// job or a new machine.  Entries that have expired (but are kept, see
+// SetStaleCacheFallback) are exported too; entries written by versions of
+// irdata that didn't record when they expire are not.
+func (i *Irdata) ExportCache(w io.Writer) error {
+	if i.cask == nil {
+		return makeErrorf("cache must be enabled")
+	}
+
+	// the cask is locked while folding so collect the keys first
+	var keys [][]byte
+
+	err := i.cask.Fold(func(key []byte) error {
+		keys = append(keys, append([]byte(nil), key...))
+		return nil
+	})
+	if err != nil {
+		return makeErrorf("unable to list cache keys [%v]", err)
+	}
+
+	enc := gob.NewEncoder(w)
+
+	err = enc.Encode(cacheArchiveHeaderT{Magic: _cacheArchiveMagic, Version: _cacheArchiveVersion})
+	if err != nil {
+		return makeErrorf("unable to write cache archive [%v]", err)
+	}
+
+	exported := 0
+
+	for _, key := range keys {
+		value, err := i.cask.Get(key)
+		if err != nil {
+			// expired or removed since the keys were listed
+			continue
+		}
+
+		entry, _ := decodeCacheEntry(value)
+
+		if entry.expires.IsZero() {
+			continue
+		}
+
+		err = enc.Encode(cacheArchiveEntryT{Key: key, Value: value, Expires: entry.expires})
+		if err != nil {
+			return makeErrorf("unable to write cache archive [%v]", err)
+		}
+
+		exported++
+	}
+
+	log.WithFields(logrus.Fields{"entries": exported}).Info("Exported cache")
+
+	return nil
+}
+
+// ImportCache loads an archive written by ExportCache into the cache.
+// Entries keep the ttl they had when they were exported, those that have
+// since expired are skipped (unless SetStaleCacheFallback keeps them) as are
+// those older than what's already cached.
+func (i *Irdata) ImportCache(r io.Reader) error {
+	if i.cask == nil {
+		return makeErrorf("cache must be enabled")
+	}
+
+	dec := gob.NewDecoder(r)
+
+	var header cacheArchiveHeaderT
+
+	if err := dec.Decode(&header); err != nil || header.Magic != _cacheArchiveMagic {
+		return makeErrorf("not a cache archive")
+	}
+
+	if header.Version != _cacheArchiveVersion {
+		return makeErrorf("unsupported cache archive version %d", header.Version)
+	}
+
+	imported := 0
+
+	for {
+		var archived cacheArchiveEntryT
+
+		err := dec.Decode(&archived)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return makeErrorf("unable to read cache archive [%v]", err)
+		}
+
+		ttl := time.Until(archived.Expires) + i.cacheMaxStale
+		if ttl <= 0 {
+			continue
+		}
+
+		if value, err := i.cask.Get(archived.Key); err == nil {
+			if current, _ := decodeCacheEntry(value); !current.expires.Before(archived.Expires) {
+				continue
+			}
+		}
+
+		err = i.cask.PutWithTTL(archived.Key, archived.Value, ttl)
+		if err != nil {
+			return makeErrorf("cache put error [%v]", err)
+		}
+
+		imported++
+	}
+
+	log.WithFields(logrus.Fields{"entries": imported}).Info("Imported cache")
+
+	return nil
+}
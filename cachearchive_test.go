@@ -0,0 +1,72 @@
+package irdata
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImportCache(t *testing.T) {
+	requests := 0
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"uri":"%s"}`, r.URL.RequestURI())
+	})
+
+	built := fakeAPI(t, handler)
+
+	assert.NoError(t, built.EnableCache(filepath.Join(t.TempDir(), "built")))
+	t.Cleanup(built.Close)
+
+	_, err := built.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+
+	_, err = built.GetWithCache("/data/constants/divisions", time.Hour)
+	assert.NoError(t, err)
+
+	_, err = built.GetWithCache("/data/results/get?subsession_id=1", time.Millisecond)
+	assert.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+
+	archive := bytes.Buffer{}
+
+	assert.NoError(t, built.ExportCache(&archive))
+
+	seeded := fakeAPI(t, handler)
+
+	assert.NoError(t, seeded.EnableCache(filepath.Join(t.TempDir(), "seeded")))
+	t.Cleanup(seeded.Close)
+
+	assert.NoError(t, seeded.ImportCache(bytes.NewReader(archive.Bytes())))
+
+	requests = 0
+
+	data, err := seeded.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"uri":"/data/member/info"}`, string(data))
+
+	_, err = seeded.GetWithCache("/data/constants/divisions", time.Hour)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, requests)
+
+	// the expired entry wasn't imported
+	_, err = seeded.GetWithCache("/data/results/get?subsession_id=1", time.Hour)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, requests)
+
+	// not an archive
+	assert.Error(t, seeded.ImportCache(strings.NewReader("nope")))
+
+	// cache must be enabled
+	assert.Error(t, fakeAPI(t, handler).ExportCache(&archive))
+}
@@ -0,0 +1,60 @@
+package irdata
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SetCachePolicy caches the results of Gets of uris starting with prefix for
+// ttl, e.g.
+//
+//	api.SetCachePolicy("/data/constants", 24*time.Hour)
+//	api.SetCachePolicy("/data/results", 5*time.Minute)
+//
+// so the ttls live in one place rather than at every GetWithCache.  When
+// several prefixes match a uri the longest wins.  Policies only apply once
+// the cache is enabled (see EnableCache).  A ttl of zero or less removes the
+// policy.
+func (i *Irdata) SetCachePolicy(prefix string, ttl time.Duration) {
+	i.cachePoliciesMu.Lock()
+	defer i.cachePoliciesMu.Unlock()
+
+	if ttl <= 0 {
+		delete(i.cachePolicies, prefix)
+		return
+	}
+
+	if i.cachePolicies == nil {
+		i.cachePolicies = map[string]time.Duration{}
+	}
+
+	i.cachePolicies[prefix] = ttl
+}
+
+// cachePolicyFor returns the ttl of the policy uri matches, if any
+func (i *Irdata) cachePolicyFor(uri string) (ttl time.Duration, ok bool) {
+	i.cachePoliciesMu.Lock()
+	defer i.cachePoliciesMu.Unlock()
+
+	if len(i.cachePolicies) == 0 {
+		return 0, false
+	}
+
+	path := uri
+
+	if u, err := url.Parse(uri); err == nil {
+		path = u.Path
+	}
+
+	longest := -1
+
+	for prefix, prefixTTL := range i.cachePolicies {
+		if strings.HasPrefix(path, prefix) && len(prefix) > longest {
+			longest = len(prefix)
+			ttl = prefixTTL
+		}
+	}
+
+	return ttl, longest >= 0
+}
@@ -0,0 +1,77 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachePolicyFor(t *testing.T) {
+	client := mustOpen()
+
+	_, ok := client.cachePolicyFor("/data/constants/divisions")
+	assert.False(t, ok)
+
+	client.SetCachePolicy("/data/constants", 24*time.Hour)
+	client.SetCachePolicy("/data/constants/event_types", time.Hour)
+	client.SetCachePolicy("/data/results", 5*time.Minute)
+
+	ttl, ok := client.cachePolicyFor("/data/constants/divisions")
+	assert.True(t, ok)
+	assert.Equal(t, 24*time.Hour, ttl)
+
+	ttl, _ = client.cachePolicyFor("/data/constants/event_types")
+	assert.Equal(t, time.Hour, ttl)
+
+	ttl, _ = client.cachePolicyFor("/data/results/get?subsession_id=1")
+	assert.Equal(t, 5*time.Minute, ttl)
+
+	_, ok = client.cachePolicyFor("/data/member/info")
+	assert.False(t, ok)
+
+	client.SetCachePolicy("/data/results", 0)
+
+	_, ok = client.cachePolicyFor("/data/results/get")
+	assert.False(t, ok)
+}
+
+func TestGetWithCachePolicy(t *testing.T) {
+	requests := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{}`)
+	}))
+
+	fake.SetCachePolicy("/data/constants", time.Hour)
+
+	// policies need the cache
+	fake.Get("/data/constants/divisions")
+	fake.Get("/data/constants/divisions")
+
+	assert.Equal(t, 2, requests)
+
+	assert.NoError(t, fake.EnableCache(filepath.Join(t.TempDir(), "cache")))
+	t.Cleanup(fake.Close)
+
+	requests = 0
+
+	for n := 0; n < 3; n++ {
+		data, err := fake.Get("/data/constants/divisions")
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{}`, string(data))
+	}
+
+	assert.Equal(t, 1, requests)
+
+	// no policy, not cached
+	fake.Get("/data/member/info")
+	fake.Get("/data/member/info")
+
+	assert.Equal(t, 3, requests)
+}
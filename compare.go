@@ -0,0 +1,141 @@
+package irdata
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// LapComparison aligns the laps of several drivers in a subsession.  Deltas
+// and gaps are relative to the first driver (the reference), positive means
+// slower or behind.
+type LapComparison struct {
+	CustIDs []int64
+	Laps    []ComparedLap
+	Stats   map[int64]LapStats
+}
+
+// ComparedLap is one lap number of a LapComparison.  Drivers without a
+// (valid) time on the lap are missing from the maps.
+type ComparedLap struct {
+	LapNumber int
+	Times     map[int64]time.Duration
+	Deltas    map[int64]time.Duration // lap time minus the reference's
+	Gaps      map[int64]time.Duration // time behind the reference at the end of the lap
+}
+
+// LapStats summarizes the valid laps of a driver.  Consistency is the
+// standard deviation of the lap times, lower is more consistent.
+type LapStats struct {
+	Laps        int
+	Fastest     time.Duration
+	Median      time.Duration
+	Consistency time.Duration
+}
+
+// CompareLaps compares the laps of custIDs (see ParseLaps)
+func CompareLaps(laps []Lap, custIDs []int64) LapComparison {
+	comparison := LapComparison{
+		CustIDs: custIDs,
+		Stats:   map[int64]LapStats{},
+	}
+
+	if len(custIDs) == 0 {
+		return comparison
+	}
+
+	reference := custIDs[0]
+
+	// lap number -> cust id -> lap
+	byLap := map[int]map[int64]Lap{}
+
+	for _, custID := range custIDs {
+		var times []time.Duration
+
+		for _, lap := range laps {
+			if lap.CustID != custID || lap.LapNumber == 0 {
+				continue
+			}
+
+			if byLap[lap.LapNumber] == nil {
+				byLap[lap.LapNumber] = map[int64]Lap{}
+			}
+
+			byLap[lap.LapNumber][custID] = lap
+
+			if lap.Valid() {
+				times = append(times, lap.LapTime)
+			}
+		}
+
+		comparison.Stats[custID] = lapStats(times)
+	}
+
+	lapNumbers := make([]int, 0, len(byLap))
+
+	for lapNumber := range byLap {
+		lapNumbers = append(lapNumbers, lapNumber)
+	}
+
+	sort.Ints(lapNumbers)
+
+	for _, lapNumber := range lapNumbers {
+		compared := ComparedLap{
+			LapNumber: lapNumber,
+			Times:     map[int64]time.Duration{},
+			Deltas:    map[int64]time.Duration{},
+			Gaps:      map[int64]time.Duration{},
+		}
+
+		referenceLap, hasReference := byLap[lapNumber][reference]
+
+		for custID, lap := range byLap[lapNumber] {
+			if lap.Valid() {
+				compared.Times[custID] = lap.LapTime
+
+				if hasReference && referenceLap.Valid() {
+					compared.Deltas[custID] = lap.LapTime - referenceLap.LapTime
+				}
+			}
+
+			if hasReference && lap.SessionTime > 0 && referenceLap.SessionTime > 0 {
+				compared.Gaps[custID] = lap.SessionTime - referenceLap.SessionTime
+			}
+		}
+
+		comparison.Laps = append(comparison.Laps, compared)
+	}
+
+	return comparison
+}
+
+func lapStats(times []time.Duration) LapStats {
+	stats := LapStats{Laps: len(times)}
+
+	if len(times) == 0 {
+		return stats
+	}
+
+	var total float64
+
+	for _, t := range times {
+		if stats.Fastest == 0 || t < stats.Fastest {
+			stats.Fastest = t
+		}
+
+		total += float64(t)
+	}
+
+	mean := total / float64(len(times))
+
+	var squares float64
+
+	for _, t := range times {
+		squares += (float64(t) - mean) * (float64(t) - mean)
+	}
+
+	stats.Median = medianDuration(times)
+	stats.Consistency = time.Duration(math.Sqrt(squares / float64(len(times))))
+
+	return stats
+}
@@ -0,0 +1,41 @@
+package irdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareLaps(t *testing.T) {
+	s := time.Second
+
+	laps := []Lap{
+		{CustID: 1, LapNumber: 0, SessionTime: 10 * s},
+		{CustID: 1, LapNumber: 1, LapTime: 90 * s, SessionTime: 100 * s},
+		{CustID: 1, LapNumber: 2, LapTime: 92 * s, SessionTime: 192 * s},
+		{CustID: 2, LapNumber: 1, LapTime: 91 * s, SessionTime: 101 * s},
+		{CustID: 2, LapNumber: 2, LapTime: 95 * s, SessionTime: 196 * s, LapEvents: []string{LapEventInvalid}},
+		{CustID: 3, LapNumber: 1, LapTime: 80 * s, SessionTime: 90 * s},
+	}
+
+	comparison := CompareLaps(laps, []int64{1, 2})
+
+	assert.Len(t, comparison.Laps, 2)
+
+	lap1 := comparison.Laps[0]
+	assert.Equal(t, 1, lap1.LapNumber)
+	assert.Equal(t, map[int64]time.Duration{1: 90 * s, 2: 91 * s}, lap1.Times)
+	assert.Equal(t, map[int64]time.Duration{1: 0, 2: s}, lap1.Deltas)
+	assert.Equal(t, map[int64]time.Duration{1: 0, 2: s}, lap1.Gaps)
+
+	lap2 := comparison.Laps[1]
+	assert.NotContains(t, lap2.Times, int64(2))
+	assert.NotContains(t, lap2.Deltas, int64(2))
+	assert.Equal(t, 4*s, lap2.Gaps[2])
+
+	assert.Equal(t, LapStats{Laps: 2, Fastest: 90 * s, Median: 91 * s, Consistency: s}, comparison.Stats[1])
+	assert.Equal(t, LapStats{Laps: 1, Fastest: 91 * s, Median: 91 * s}, comparison.Stats[2])
+
+	assert.Empty(t, CompareLaps(laps, nil).Laps)
+}
@@ -0,0 +1,110 @@
+package irdata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// CacheCompressor compresses the values written to the cache, see
+// SetCacheCompression
+type CacheCompressor interface {
+	// Name identifies the compression in cache entries (it must be no longer
+	// than 255 bytes and not change)
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCompression is the default CacheCompressor
+var GzipCompression CacheCompressor = gzipCompressorT{}
+
+type gzipCompressorT struct{}
+
+func (gzipCompressorT) Name() string {
+	return "gzip"
+}
+
+func (gzipCompressorT) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressorT) Decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	defer zr.Close()
+
+	return io.ReadAll(zr)
+}
+
+// SetCacheCompression sets how values written to the cache are compressed,
+// GzipCompression by default, nil for not at all.  JSON (chunked results
+// especially) compresses very well, keeping the cache small and large
+// results under the cache's maximum value size.  Another compression (e.g.
+// zstd) can be used by implementing CacheCompressor.  Entries already in the
+// cache can still be read as long as they were written uncompressed, with
+// gzip, or with the current compressor.
+func (i *Irdata) SetCacheCompression(compressor CacheCompressor) {
+	if compressor == nil {
+		compressor = noCompressionT{}
+	}
+
+	i.cacheCompressor = compressor
+}
+
+// noCompressionT is SetCacheCompression(nil)
+type noCompressionT struct{}
+
+func (noCompressionT) Name() string                           { return "" }
+func (noCompressionT) Compress(data []byte) ([]byte, error)   { return data, nil }
+func (noCompressionT) Decompress(data []byte) ([]byte, error) { return data, nil }
+
+func (i *Irdata) getCacheCompressor() CacheCompressor {
+	if i.cacheCompressor == nil {
+		return GzipCompression
+	}
+
+	return i.cacheCompressor
+}
+
+// compressCacheData compresses data and returns the name of the compression
+// used
+func (i *Irdata) compressCacheData(data []byte) ([]byte, string, error) {
+	compressor := i.getCacheCompressor()
+
+	compressed, err := compressor.Compress(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return compressed, compressor.Name(), nil
+}
+
+// decompressCacheData decompresses data that was compressed with compression
+func (i *Irdata) decompressCacheData(data []byte, compression string) ([]byte, error) {
+	switch compressor := i.getCacheCompressor(); compression {
+	case "":
+		return data, nil
+	case compressor.Name():
+		return compressor.Decompress(data)
+	case GzipCompression.Name():
+		return GzipCompression.Decompress(data)
+	default:
+		return nil, makeErrorf("unknown cache compression %q", compression)
+	}
+}
@@ -0,0 +1,93 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reverseCompressor struct{}
+
+func (reverseCompressor) Name() string { return "reverse" }
+
+func (reverseCompressor) Compress(data []byte) ([]byte, error) {
+	return reverse(data), nil
+}
+
+func (reverseCompressor) Decompress(data []byte) ([]byte, error) {
+	return reverse(data), nil
+}
+
+func reverse(data []byte) []byte {
+	r := make([]byte, len(data))
+
+	for n, b := range data {
+		r[len(data)-1-n] = b
+	}
+
+	return r
+}
+
+func TestCacheCompression(t *testing.T) {
+	setupCacheTest()
+	t.Cleanup(cleanupCacheTest)
+	t.Cleanup(func() { i.SetCacheCompression(GzipCompression) })
+
+	big := []byte(`[` + strings.Repeat(`{"cust_id":123456,"display_name":"Ayrton Senna"},`, 1000) + `{}]`)
+
+	// gzip by default
+	assert.NoError(t, i.setCachedData("gzip", big, testTtl))
+
+	value, _ := i.cask.Get(hashKey("gzip"))
+
+	assert.Less(t, len(value), len(big)/10)
+
+	// none
+	i.SetCacheCompression(nil)
+
+	assert.NoError(t, i.setCachedData("none", big, testTtl))
+
+	value, _ = i.cask.Get(hashKey("none"))
+
+	assert.Greater(t, len(value), len(big))
+
+	// something else, entries written before can still be read
+	i.SetCacheCompression(reverseCompressor{})
+
+	assert.NoError(t, i.setCachedData("reverse", big, testTtl))
+
+	for _, key := range []string{"gzip", "none", "reverse"} {
+		data, err := i.getCachedData(key)
+
+		assert.NoError(t, err)
+		assert.Equal(t, big, data, key)
+	}
+
+	// unless the compression is unknown, then it's as if it wasn't cached
+	i.SetCacheCompression(nil)
+
+	data, err := i.getCachedData("reverse")
+
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func TestCacheEntryV1(t *testing.T) {
+	setupCacheTest()
+	t.Cleanup(cleanupCacheTest)
+
+	value := append([]byte{}, _cacheEntryMagicV1...)
+	value = binary.BigEndian.AppendUint64(value, uint64(time.Now().Add(time.Hour).UnixNano()))
+	value = append(value, testDataString1...)
+
+	assert.NoError(t, i.cask.Put(hashKey("v1"), value))
+
+	data, err := i.getCachedData("v1")
+
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal([]byte(testDataString1), data))
+}
@@ -2,8 +2,12 @@ package irdata
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/sirupsen/logrus"
 	"golang.org/x/term"
 )
 
@@ -33,3 +37,269 @@ func (CredsFromTerminal) GetCreds() ([]byte, []byte, error) {
 
 	return []byte(username), password_bytes, nil
 }
+
+// CredsFromPrompt is a CredsProvider that prompts for the username and
+// password on Out and reads them a line at a time from In, so prompting can
+// be embedded in other UIs or driven by tests.  A nil In reads os.Stdin and a
+// nil Out discards the prompts.
+//
+// When In is a terminal the password is read without echoing it.  Otherwise
+// the password is read like the username, which is refused unless
+// AllowEcho is set since whatever is feeding In may show it.
+type CredsFromPrompt struct {
+	In        io.Reader
+	Out       io.Writer
+	AllowEcho bool
+}
+
+func (p CredsFromPrompt) GetCreds() ([]byte, []byte, error) {
+	in := p.In
+	if in == nil {
+		in = os.Stdin
+	}
+
+	out := p.Out
+	if out == nil {
+		out = io.Discard
+	}
+
+	fmt.Fprintln(out, "Please provide creds for an active iRacing account")
+	fmt.Fprint(out, "username:")
+
+	username, err := readLine(in)
+	if err != nil {
+		return nil, nil, makeErrorf("unable to read username [%v]", err)
+	}
+
+	fmt.Fprint(out, "password:")
+
+	var password []byte
+
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		password, err = term.ReadPassword(int(f.Fd()))
+	} else if p.AllowEcho {
+		password, err = readLine(in)
+	} else {
+		err = makeErrorf("input is not a terminal, set AllowEcho to read the password from it")
+	}
+
+	fmt.Fprintf(out, "\n\n")
+
+	if err != nil {
+		return nil, nil, makeErrorf("unable to read password [%v]", err)
+	}
+
+	return username, password, nil
+}
+
+// readLine reads up to the end of the line a byte at a time so nothing past
+// it is consumed (a terminal may be read directly afterwards)
+func readLine(r io.Reader) ([]byte, error) {
+	var line []byte
+
+	b := make([]byte, 1)
+
+	for {
+		n, err := r.Read(b)
+
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+
+			line = append(line, b[0])
+		}
+
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []byte(strings.TrimRight(string(line), "\r")), nil
+}
+
+// Environment variables read by CredsFromEnv when it doesn't name others
+const (
+	DefaultUsernameEnv = "IRDATA_USERNAME"
+	DefaultPasswordEnv = "IRDATA_PASSWORD"
+)
+
+// CredsFromEnv is a CredsProvider that reads the username and password from
+// environment variables, e.g. in CI or containers.  Empty names default to
+// DefaultUsernameEnv and DefaultPasswordEnv.
+type CredsFromEnv struct {
+	UsernameVar string
+	PasswordVar string
+}
+
+func (e CredsFromEnv) GetCreds() ([]byte, []byte, error) {
+	usernameVar := e.UsernameVar
+	if usernameVar == "" {
+		usernameVar = DefaultUsernameEnv
+	}
+
+	passwordVar := e.PasswordVar
+	if passwordVar == "" {
+		passwordVar = DefaultPasswordEnv
+	}
+
+	username := os.Getenv(usernameVar)
+	password := os.Getenv(passwordVar)
+
+	if username == "" || password == "" {
+		return nil, nil, makeErrorf("%s and %s must both be set", usernameVar, passwordVar)
+	}
+
+	return []byte(username), []byte(password), nil
+}
+
+// Where CredsFromSecretFiles looks when it isn't told otherwise, i.e. Docker
+// and Kubernetes secrets mounted as /run/secrets/irdata_username and
+// /run/secrets/irdata_password
+const (
+	DefaultSecretsDir     = "/run/secrets"
+	DefaultUsernameSecret = "irdata_username"
+	DefaultPasswordSecret = "irdata_password"
+)
+
+// CredsFromSecretFiles is a CredsProvider that reads the username and
+// password from files in Dir, e.g. secrets mounted into a container.  A
+// trailing newline (as left by most editors and `echo`) is trimmed from
+// each.  Empty fields default to DefaultSecretsDir, DefaultUsernameSecret,
+// and DefaultPasswordSecret.
+type CredsFromSecretFiles struct {
+	Dir          string
+	UsernameFile string
+	PasswordFile string
+}
+
+func (f CredsFromSecretFiles) GetCreds() ([]byte, []byte, error) {
+	dir := f.Dir
+	if dir == "" {
+		dir = DefaultSecretsDir
+	}
+
+	usernameFile := f.UsernameFile
+	if usernameFile == "" {
+		usernameFile = DefaultUsernameSecret
+	}
+
+	passwordFile := f.PasswordFile
+	if passwordFile == "" {
+		passwordFile = DefaultPasswordSecret
+	}
+
+	username, err := readSecretFile(filepath.Join(dir, usernameFile))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	password, err := readSecretFile(filepath.Join(dir, passwordFile))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return username, password, nil
+}
+
+func readSecretFile(filename string) ([]byte, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, makeErrorf("unable to read %s [%v]", filename, err)
+	}
+
+	// slices rather than copies so there's no copy of the secret left behind
+	for len(content) > 0 && (content[len(content)-1] == '\n' || content[len(content)-1] == '\r') {
+		content = content[:len(content)-1]
+	}
+
+	if len(content) == 0 {
+		return nil, makeErrorf("%s is empty", filename)
+	}
+
+	return content, nil
+}
+
+// ChainCredsProvider is a CredsProvider that tries each of its providers in
+// order and returns the creds of the first that succeeds, so the same program
+// can take creds from the environment in CI and from the terminal
+// interactively:
+//
+//	irdata.ChainCredsProvider{irdata.CredsFromEnv{}, irdata.CredsFromTerminal{}}
+type ChainCredsProvider []CredsProvider
+
+func (c ChainCredsProvider) GetCreds() ([]byte, []byte, error) {
+	username, password, _, err := c.getMaskedCreds()
+
+	return username, password, err
+}
+
+func (c ChainCredsProvider) getMaskedCreds() ([]byte, []byte, bool, error) {
+	var failures []string
+
+	for _, provider := range c {
+		username, password, masked, err := getCreds(provider)
+		if err == nil {
+			return username, password, masked, nil
+		}
+
+		log.WithFields(logrus.Fields{
+			"provider": fmt.Sprintf("%T", provider),
+			"err":      err,
+		}).Debug("CredsProvider failed, trying the next")
+
+		failures = append(failures, fmt.Sprintf("%T: %v", provider, err))
+	}
+
+	return nil, nil, false, makeErrorf("no creds provider succeeded [%s]", strings.Join(failures, "; "))
+}
+
+// MaskedCreds is a CredsProvider for a password already masked with
+// MaskPassword (e.g. by a provisioning tool): the password Provider returns
+// is sent as is instead of being masked again.
+//
+//	irdata.MaskedCreds{Provider: irdata.CredsFromEnv{}}
+type MaskedCreds struct {
+	Provider CredsProvider
+}
+
+func (m MaskedCreds) GetCreds() ([]byte, []byte, error) {
+	return m.Provider.GetCreds()
+}
+
+func (m MaskedCreds) getMaskedCreds() ([]byte, []byte, bool, error) {
+	username, password, err := m.Provider.GetCreds()
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	if !isMasked(password) {
+		shred(&password)
+
+		return nil, nil, false, makeErrorf("password from %T isn't masked, see MaskPassword", m.Provider)
+	}
+
+	return username, password, true, nil
+}
+
+// maskedCredsProvider is a CredsProvider that knows whether the password it
+// returns is already masked
+type maskedCredsProvider interface {
+	getMaskedCreds() ([]byte, []byte, bool, error)
+}
+
+// getCreds calls provider and reports whether the password it returned is
+// already masked (see MaskedCreds)
+func getCreds(provider CredsProvider) ([]byte, []byte, bool, error) {
+	if p, ok := provider.(maskedCredsProvider); ok {
+		return p.getMaskedCreds()
+	}
+
+	username, password, err := provider.GetCreds()
+
+	return username, password, false, err
+}
@@ -0,0 +1,127 @@
+package irdata
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingCredsProvider struct{}
+
+func (failingCredsProvider) GetCreds() ([]byte, []byte, error) {
+	return nil, nil, makeErrorf("no creds here")
+}
+
+func TestCredsFromEnv(t *testing.T) {
+	t.Setenv(DefaultUsernameEnv, "user@example.com")
+	t.Setenv(DefaultPasswordEnv, "")
+
+	_, _, err := CredsFromEnv{}.GetCreds()
+	assert.Error(t, err)
+
+	t.Setenv(DefaultPasswordEnv, "hunter2")
+
+	username, password, err := CredsFromEnv{}.GetCreds()
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.com", string(username))
+	assert.Equal(t, "hunter2", string(password))
+
+	t.Setenv("CI_USER", "ci@example.com")
+	t.Setenv("CI_PASS", "s3cr3t")
+
+	username, _, err = CredsFromEnv{UsernameVar: "CI_USER", PasswordVar: "CI_PASS"}.GetCreds()
+	assert.NoError(t, err)
+	assert.Equal(t, "ci@example.com", string(username))
+}
+
+func TestChainCredsProvider(t *testing.T) {
+	username, _, err := ChainCredsProvider{failingCredsProvider{}, testCredsProvider{}}.GetCreds()
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.com", string(username))
+
+	_, _, err = ChainCredsProvider{failingCredsProvider{}, failingCredsProvider{}}.GetCreds()
+	assert.ErrorContains(t, err, "no creds here")
+
+	_, _, err = ChainCredsProvider{}.GetCreds()
+	assert.Error(t, err)
+}
+
+func TestCredsFromPrompt(t *testing.T) {
+	out := bytes.Buffer{}
+
+	username, password, err := CredsFromPrompt{
+		In:        strings.NewReader("user@example.com\r\nhunter2"),
+		Out:       &out,
+		AllowEcho: true,
+	}.GetCreds()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.com", string(username))
+	assert.Equal(t, "hunter2", string(password))
+	assert.Contains(t, out.String(), "username:")
+	assert.Contains(t, out.String(), "password:")
+
+	// not a terminal, the password would be echoed
+	_, _, err = CredsFromPrompt{In: strings.NewReader("user@example.com\nhunter2\n")}.GetCreds()
+	assert.Error(t, err)
+
+	_, _, err = CredsFromPrompt{In: strings.NewReader(""), AllowEcho: true}.GetCreds()
+	assert.Error(t, err)
+}
+
+func TestCredsFromPromptZeroValue(t *testing.T) {
+	stdin := filepath.Join(t.TempDir(), "stdin")
+
+	assert.NoError(t, os.WriteFile(stdin, []byte("user@example.com\nhunter2\n"), 0600))
+
+	f, err := os.Open(stdin)
+	assert.NoError(t, err)
+
+	defer f.Close()
+
+	saved := os.Stdin
+	os.Stdin = f
+
+	defer func() { os.Stdin = saved }()
+
+	// reads stdin, which isn't a terminal here, so the password is refused
+	assert.NotPanics(t, func() {
+		_, _, err = CredsFromPrompt{}.GetCreds()
+	})
+	assert.Error(t, err)
+
+	_, err = f.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+
+	username, password, err := CredsFromPrompt{AllowEcho: true}.GetCreds()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.com", string(username))
+	assert.Equal(t, "hunter2", string(password))
+}
+
+func TestCredsFromSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, DefaultUsernameSecret), []byte("user@example.com\n"), 0400))
+
+	_, _, err := CredsFromSecretFiles{Dir: dir}.GetCreds()
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, DefaultPasswordSecret), []byte("hunter2\r\n"), 0400))
+
+	username, password, err := CredsFromSecretFiles{Dir: dir}.GetCreds()
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.com", string(username))
+	assert.Equal(t, "hunter2", string(password))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "empty"), []byte("\n"), 0400))
+
+	_, _, err = CredsFromSecretFiles{Dir: dir, PasswordFile: "empty"}.GetCreds()
+	assert.Error(t, err)
+}
@@ -0,0 +1,450 @@
+package irdata
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Encrypted files (creds, etc) are base64 encoded and laid out as:
+//
+//	magic (4 bytes) | version (1) | cipher id (1) | kdf id (1) | flags (1) |
+//	key check (8) | [identity check (8)] | nonce | sealed data
+//
+// The header is authenticated along with additionalContext so it can't be
+// altered without detection.  The key check value is derived from the key and
+// lets us tell a wrong key apart from a corrupted file.  If the file is bound
+// to an identity (fileFlagIdentity) the identity is also mixed into the
+// additional data and the identity check value tells a mismatch apart from
+// corruption.
+//
+// Version 1 files have no flags or check values, version 2 files have no
+// flags and only the key check.  Files written before the header was
+// introduced are just nonce | sealed data using AES-GCM.  All of these are
+// still readable.
+var fileMagic = []byte("IRDF")
+
+const (
+	fileVersion1 byte = 1
+	fileVersion2 byte = 2
+	fileVersion3 byte = 3
+
+	fileVersionCurrent = fileVersion3
+)
+
+const _fileHeaderSizeV1 = 7
+const _checkSize = 8
+const _fileHeaderSizeV2 = _fileHeaderSizeV1 + _checkSize
+const _fileHeaderSizeV3 = _fileHeaderSizeV1 + 1 + _checkSize
+
+const (
+	// the file is bound to an identity
+	fileFlagIdentity byte = 1 << iota
+)
+
+var keyCheckContext = []byte("irdata.keycheck")
+var identityCheckContext = []byte("irdata.identity")
+
+type cipherID byte
+
+// Only AES-GCM is implemented.  XChaCha20-Poly1305 was planned as an
+// alternative but needs golang.org/x/crypto, which irdata doesn't depend on,
+// so it's left out for now; the cipher id is there so it (or another AEAD)
+// can be added without changing the format.
+const (
+	cipherAESGCM cipherID = 1
+)
+
+type kdfID byte
+
+const (
+	// the key file contents are used as the key as is
+	kdfNone kdfID = 0
+)
+
+// ciphers maps a cipher id to a constructor for its AEAD
+var ciphers = map[cipherID]func(key []byte) (cipher.AEAD, error){
+	cipherAESGCM: newAESGCM,
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		if errors.Is(err, aes.KeySizeError(0)) {
+			return nil, makeErrorf("key must be 16, 24, or 32 bytes long")
+		} else {
+			return nil, makeErrorf("unable to intialize AES cipher [%v]", err)
+		}
+	}
+
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, makeErrorf("unable to initialice GCM [%v]", err)
+	}
+
+	return aesgcm, nil
+}
+
+type fileHeaderT struct {
+	version       byte
+	cipher        cipherID
+	kdf           kdfID
+	flags         byte
+	keyCheck      []byte
+	identityCheck []byte
+}
+
+func (h fileHeaderT) bytes() []byte {
+	header := append(append([]byte{}, fileMagic...), h.version, byte(h.cipher), byte(h.kdf))
+
+	if h.version >= fileVersion3 {
+		header = append(header, h.flags)
+	}
+
+	header = append(header, h.keyCheck...)
+
+	return append(header, h.identityCheck...)
+}
+
+// parseFileHeader returns the header and its size, ok is false if data has no
+// header at all (i.e. it's a legacy file)
+func parseFileHeader(data []byte) (header fileHeaderT, size int, ok bool, err error) {
+	if len(data) < _fileHeaderSizeV1 || !bytes.Equal(data[:len(fileMagic)], fileMagic) {
+		return header, 0, false, nil
+	}
+
+	header = fileHeaderT{
+		version: data[4],
+		cipher:  cipherID(data[5]),
+		kdf:     kdfID(data[6]),
+	}
+
+	truncated := fmt.Errorf("%w: truncated header", ErrCorruptedFile)
+
+	switch header.version {
+	case fileVersion1:
+		return header, _fileHeaderSizeV1, true, nil
+	case fileVersion2:
+		if len(data) < _fileHeaderSizeV2 {
+			return header, 0, true, truncated
+		}
+
+		header.keyCheck = data[_fileHeaderSizeV1:_fileHeaderSizeV2]
+
+		return header, _fileHeaderSizeV2, true, nil
+	case fileVersion3:
+		if len(data) < _fileHeaderSizeV3 {
+			return header, 0, true, truncated
+		}
+
+		header.flags = data[_fileHeaderSizeV1]
+		header.keyCheck = data[_fileHeaderSizeV1+1 : _fileHeaderSizeV3]
+
+		size = _fileHeaderSizeV3
+
+		if header.flags&fileFlagIdentity != 0 {
+			if len(data) < size+_checkSize {
+				return header, 0, true, truncated
+			}
+
+			header.identityCheck = data[size : size+_checkSize]
+
+			size += _checkSize
+		}
+
+		return header, size, true, nil
+	default:
+		return header, 0, true, fmt.Errorf("%w: version %d", ErrUnsupportedFileVersion, header.version)
+	}
+}
+
+// checkValue derives a short value from key and context that is stored in
+// the header to detect the wrong key or identity being used
+func checkValue(key []byte, context ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+
+	for _, c := range context {
+		mac.Write(c)
+	}
+
+	return mac.Sum(nil)[:_checkSize]
+}
+
+func keyCheckValue(key []byte) []byte {
+	return checkValue(key, keyCheckContext)
+}
+
+func identityCheckValue(key []byte, identity string) []byte {
+	return checkValue(key, identityCheckContext, []byte(identity))
+}
+
+// additionalData returns the data authenticated along with the sealed data
+func additionalData(headerBytes []byte, identity string) []byte {
+	return append(append(append([]byte{}, additionalContext...), headerBytes...), identity...)
+}
+
+func newAEAD(id cipherID, key []byte) (cipher.AEAD, error) {
+	newCipher, ok := ciphers[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: cipher id %d", ErrUnsupportedFileVersion, id)
+	}
+
+	return newCipher(key)
+}
+
+// seal encrypts plaintext with key into the current file format.  If
+// identity isn't empty the file can only be unsealed with the same identity.
+func seal(key []byte, plaintext []byte, identity string) ([]byte, error) {
+	header := fileHeaderT{
+		version:  fileVersionCurrent,
+		cipher:   cipherAESGCM,
+		kdf:      kdfNone,
+		keyCheck: keyCheckValue(key),
+	}
+
+	if identity != "" {
+		header.flags |= fileFlagIdentity
+		header.identityCheck = identityCheckValue(key, identity)
+	}
+
+	aead, err := newAEAD(header.cipher, key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := makeNonce(aead)
+	if err != nil {
+		return nil, err
+	}
+
+	headerBytes := header.bytes()
+
+	data := append(headerBytes, nonce...)
+
+	return aead.Seal(data, nonce, plaintext, additionalData(headerBytes, identity)), nil
+}
+
+// unseal decrypts data written by seal or in the legacy headerless format.
+// identity is ignored unless the file is bound to one.
+func unseal(key []byte, data []byte, identity string) ([]byte, error) {
+	header, headerSize, ok, err := parseFileHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return unsealLegacy(key, data)
+	}
+
+	if header.kdf != kdfNone {
+		return nil, fmt.Errorf("%w: kdf id %d", ErrUnsupportedFileVersion, header.kdf)
+	}
+
+	if header.keyCheck != nil && !hmac.Equal(header.keyCheck, keyCheckValue(key)) {
+		return nil, ErrWrongKey
+	}
+
+	if header.flags&fileFlagIdentity == 0 {
+		identity = ""
+	} else if !hmac.Equal(header.identityCheck, identityCheckValue(key, identity)) {
+		return nil, ErrIdentityMismatch
+	}
+
+	aead, err := newAEAD(header.cipher, key)
+	if err != nil {
+		return nil, err
+	}
+
+	headerBytes, sealed := data[:headerSize], data[headerSize:]
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: encrypted data is too short", ErrCorruptedFile)
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData(headerBytes, identity))
+	if err != nil {
+		if header.keyCheck != nil {
+			// the key is right so the data must be bad
+			return nil, fmt.Errorf("%w [%v]", ErrCorruptedFile, err)
+		}
+
+		return nil, fmt.Errorf("%w [%v]", ErrDecryptionFailed, err)
+	}
+
+	return plaintext, nil
+}
+
+func unsealLegacy(key []byte, data []byte) ([]byte, error) {
+	aesgcm, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < aesgcm.NonceSize() {
+		return nil, fmt.Errorf("%w: encrypted data is too short", ErrCorruptedFile)
+	}
+
+	plaintext, err := aesgcm.Open(nil, data[:aesgcm.NonceSize()], data[aesgcm.NonceSize():], additionalContext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: legacy format file [%v]", ErrDecryptionFailed, err)
+	}
+
+	return plaintext, nil
+}
+
+// encrypted files are written with these perms unless SetCredsFileMode
+// says otherwise
+const _credsFileMode = 0600
+
+// fileOptsT controls how encrypted files are written and read
+type fileOptsT struct {
+	identity string      // see SetCredsIdentity
+	strict   bool        // see SetStrictSecurity
+	mode     os.FileMode // see SetCredsFileMode, zero for the default
+}
+
+// fileOpts returns the options for this client's encrypted files
+func (i *Irdata) fileOpts() fileOptsT {
+	return fileOptsT{
+		identity: i.credsIdentity,
+		strict:   i.strictSecurity,
+		mode:     i.credsFileMode,
+	}
+}
+
+// SetCredsFileMode sets the perms creds files are written with (0600 by
+// default), e.g. 0640 to let a group of service accounts read them.  It's
+// ignored in strict security mode, which always uses 0600.
+func (i *Irdata) SetCredsFileMode(mode os.FileMode) {
+	i.credsFileMode = mode.Perm()
+}
+
+// encryptToFile seals plaintext with the key from keyProvider and writes it to
+// filename
+func encryptToFile(keyProvider KeyProvider, filename string, plaintext []byte, opts fileOptsT) error {
+	key, err := strictKeys(keyProvider, opts.strict).GetKey()
+	if err != nil {
+		return err
+	}
+
+	data, err := seal(key, plaintext, opts.identity)
+
+	// not a defer because we want to do this right away
+	shred(&key)
+
+	if err != nil {
+		return err
+	}
+
+	base64data := base64.StdEncoding.Strict().EncodeToString(data)
+
+	mode := opts.mode
+	if mode == 0 || opts.strict {
+		mode = _credsFileMode
+	}
+
+	return writeFileAtomic(filename, []byte(base64data), mode)
+}
+
+// writeFileAtomic writes data to a temporary file next to filename, syncs
+// it, and renames it over filename, so a crash leaves either the old file or
+// the new one and never a truncated one.  The file ends up with exactly mode
+// for its perms whether or not it existed before.
+func writeFileAtomic(filename string, data []byte, mode os.FileMode) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return makeErrorf("unable to create temporary file for %s [%v]", filename, err)
+	}
+
+	tmpFilename := tmp.Name()
+
+	fail := func(format string, err error) error {
+		tmp.Close()
+		os.Remove(tmpFilename)
+
+		return makeErrorf(format, filename, err)
+	}
+
+	if err := tmp.Chmod(mode); err != nil {
+		return fail("unable to set perms of %s [%v]", err)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		return fail("unable to write %s [%v]", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return fail("unable to sync %s [%v]", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpFilename)
+		return makeErrorf("unable to write %s [%v]", filename, err)
+	}
+
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		os.Remove(tmpFilename)
+		return makeErrorf("unable to replace %s [%v]", filename, err)
+	}
+
+	syncDir(dir)
+
+	return nil
+}
+
+// syncDir makes a rename in dir durable, where the platform allows it
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+
+	// not supported everywhere (e.g. Windows), the rename has happened anyway
+	d.Sync()
+	d.Close()
+}
+
+// decryptFromFile reads filename and opens it with the key from keyProvider
+func decryptFromFile(keyProvider KeyProvider, filename string, opts fileOptsT) ([]byte, error) {
+	if opts.strict {
+		if err := checkStrictFile(filename); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := strictKeys(keyProvider, opts.strict).GetKey()
+	if err != nil {
+		return nil, err
+	}
+
+	defer shred(&key)
+
+	base64data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, makeErrorf("unable to read file %s [%v]", filename, err)
+	}
+
+	data, err := base64.StdEncoding.Strict().DecodeString(string(base64data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to decode base64 %s [%v]", ErrCorruptedFile, filename, err)
+	}
+
+	plaintext, err := unseal(key, data, opts.identity)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%s)", err, filename)
+	}
+
+	return plaintext, nil
+}
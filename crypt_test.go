@@ -0,0 +1,220 @@
+package irdata
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, 32)
+
+func TestSealUnseal(t *testing.T) {
+	data, err := seal(testKey, []byte(testDataString1), "")
+
+	assert.NoError(t, err)
+
+	header, size, ok, err := parseFileHeader(data)
+
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, _fileHeaderSizeV3, size)
+	assert.Equal(t, fileVersionCurrent, header.version)
+	assert.Equal(t, cipherAESGCM, header.cipher)
+	assert.Equal(t, kdfNone, header.kdf)
+	assert.Equal(t, keyCheckValue(testKey), header.keyCheck)
+
+	plaintext, err := unseal(testKey, data, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(testDataString1), plaintext)
+}
+
+func TestUnsealTamperedHeader(t *testing.T) {
+	data, err := seal(testKey, []byte(testDataString1), "")
+
+	assert.NoError(t, err)
+
+	// unknown version
+	tampered := append([]byte{}, data...)
+	tampered[4] = 99
+
+	_, err = unseal(testKey, tampered, "")
+
+	assert.ErrorIs(t, err, ErrUnsupportedFileVersion)
+
+	// unknown cipher
+	tampered = append([]byte{}, data...)
+	tampered[5] = 99
+
+	_, err = unseal(testKey, tampered, "")
+
+	assert.Error(t, err)
+}
+
+func TestEncryptDecryptFile(t *testing.T) {
+	setupAuthTest()
+	t.Cleanup(cleanupAuthTest)
+
+	fn := filepath.Join(testAuthDir, "test.enc")
+
+	assert.NoError(t, encryptToFile(KeyFromFile(testKeyFilename), fn, []byte(testDataString2), fileOptsT{}))
+
+	plaintext, err := decryptFromFile(KeyFromFile(testKeyFilename), fn, fileOptsT{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(testDataString2), plaintext)
+}
+
+func TestUnsealWrongKey(t *testing.T) {
+	data, err := seal(testKey, []byte(testDataString1), "")
+
+	assert.NoError(t, err)
+
+	_, err = unseal(bytes.Repeat([]byte{0x24}, 32), data, "")
+
+	assert.ErrorIs(t, err, ErrWrongKey)
+}
+
+func TestUnsealCorrupted(t *testing.T) {
+	data, err := seal(testKey, []byte(testDataString1), "")
+
+	assert.NoError(t, err)
+
+	data[len(data)-1] ^= 0xff
+
+	_, err = unseal(testKey, data, "")
+
+	assert.ErrorIs(t, err, ErrCorruptedFile)
+
+	_, err = unseal(testKey, data[:_fileHeaderSizeV3+2], "")
+
+	assert.ErrorIs(t, err, ErrCorruptedFile)
+}
+
+func TestUnsealLegacyWrongKey(t *testing.T) {
+	legacy, err := decryptFromFile(KeyFromFile(testKeyFilename), testCredsFilename, fileOptsT{})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, legacy)
+
+	_, err = unsealLegacy(testKey, []byte("this is definitely not a sealed legacy payload"))
+
+	assert.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+func TestSealIdentity(t *testing.T) {
+	data, err := seal(testKey, []byte(testDataString1), "senna@monaco")
+
+	assert.NoError(t, err)
+
+	header, _, _, err := parseFileHeader(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, fileFlagIdentity, header.flags&fileFlagIdentity)
+
+	plaintext, err := unseal(testKey, data, "senna@monaco")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(testDataString1), plaintext)
+
+	_, err = unseal(testKey, data, "prost@monaco")
+
+	assert.ErrorIs(t, err, ErrIdentityMismatch)
+
+	_, err = unseal(testKey, data, "")
+
+	assert.ErrorIs(t, err, ErrIdentityMismatch)
+
+	// unbound files don't care about the identity
+	data, err = seal(testKey, []byte(testDataString1), "")
+
+	assert.NoError(t, err)
+
+	_, err = unseal(testKey, data, "prost@monaco")
+
+	assert.NoError(t, err)
+}
+
+func TestUnsealVersion2(t *testing.T) {
+	header := fileHeaderT{
+		version:  fileVersion2,
+		cipher:   cipherAESGCM,
+		kdf:      kdfNone,
+		keyCheck: keyCheckValue(testKey),
+	}
+
+	aead, err := newAEAD(cipherAESGCM, testKey)
+
+	assert.NoError(t, err)
+
+	nonce, err := makeNonce(aead)
+
+	assert.NoError(t, err)
+
+	headerBytes := header.bytes()
+
+	assert.Len(t, headerBytes, _fileHeaderSizeV2)
+
+	data := aead.Seal(append(headerBytes, nonce...), nonce, []byte(testDataString1), additionalData(headerBytes, ""))
+
+	plaintext, err := unseal(testKey, data, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(testDataString1), plaintext)
+}
+
+func TestEncryptToFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "creds")
+
+	assert.NoError(t, os.WriteFile(filename, []byte("old"), 0644))
+
+	assert.NoError(t, encryptToFile(testKeyProvider{}, filename, []byte("new"), fileOptsT{}))
+
+	stat, err := os.Stat(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(_credsFileMode), stat.Mode().Perm())
+
+	plaintext, err := decryptFromFile(testKeyProvider{}, filename, fileOptsT{})
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(plaintext))
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	client := mustOpen()
+	client.SetCredsFileMode(0640)
+
+	assert.NoError(t, encryptToFile(testKeyProvider{}, filename, []byte("new"), client.fileOpts()))
+
+	stat, err = os.Stat(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), stat.Mode().Perm())
+
+	// strict security mode doesn't loosen perms
+	client.SetStrictSecurity(true)
+
+	assert.NoError(t, encryptToFile(testKeyProvider{}, filename, []byte("new"), client.fileOpts()))
+
+	stat, err = os.Stat(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(_strictFileMode), stat.Mode().Perm())
+}
+
+func TestUnsealUnknownCipher(t *testing.T) {
+	data, err := seal(testKey, []byte(testDataString1), "")
+
+	assert.NoError(t, err)
+
+	// e.g. written with a cipher this version doesn't implement
+	data[5] = 0x7f
+
+	_, err = unseal(testKey, data, "")
+
+	assert.ErrorIs(t, err, ErrUnsupportedFileVersion)
+}
@@ -0,0 +1,153 @@
+package irdata
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// how much of a response body is dumped
+const _maxDumpBytes = 1024 * 4 // 4K
+
+// EnableHTTPDump logs a dump of every request made and response received, at
+// the debug level (see EnableDebug).  Secrets are redacted from the dumps:
+// cookie and authorization headers, passwords and tokens in urls and bodies,
+// and S3 signatures.  Request bodies (only logins have them) are left out
+// altogether, and only the start of each response body is dumped.
+func (i *Irdata) EnableHTTPDump() {
+	i.httpDump = true
+}
+
+// DisableHTTPDump turns off the dumps turned on by EnableHTTPDump
+func (i *Irdata) DisableHTTPDump() {
+	i.httpDump = false
+}
+
+// do sends req with the http client along with the session cookies, keeping
+// the cookies the response sets (see keepCookies)
+func (i *Irdata) do(req *http.Request) (*http.Response, error) {
+	session := i.requestSession(req)
+
+	i.addCookies(req)
+
+	resp, err := i.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	i.keepCookies(session, req.URL, resp)
+
+	return resp, nil
+}
+
+// send sends req with the http client, dumping it and its response if asked
+// to
+func (i *Irdata) send(req *http.Request) (*http.Response, error) {
+	if !i.httpDump {
+		return i.httpClient.Do(req)
+	}
+
+	log.WithFields(logrus.Fields{"dump": dumpRequest(req)}).Debug("HTTP request")
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		log.WithFields(logrus.Fields{"url": req.URL.String(), "err": err}).Debug("HTTP request failed")
+
+		return nil, err
+	}
+
+	dump, err := dumpResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{"dump": dump}).Debug("HTTP response")
+
+	return resp, nil
+}
+
+func dumpRequest(req *http.Request) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %s\n", req.Method, redactString(req.URL.String()))
+
+	dumpHeader(&b, req.Header)
+
+	if req.Body != nil && req.Body != http.NoBody {
+		b.WriteString("\n[body not dumped]\n")
+	}
+
+	return b.String()
+}
+
+// dumpResponse dumps resp and the start of its body, leaving the body intact
+// for the caller to read
+func dumpResponse(resp *http.Response) (string, error) {
+	// dump what will be read, not the compressed bytes
+	if err := gunzipResponse(resp); err != nil {
+		return "", err
+	}
+
+	head, err := io.ReadAll(io.LimitReader(resp.Body, _maxDumpBytes))
+	if err != nil {
+		resp.Body.Close()
+
+		return "", err
+	}
+
+	resp.Body = dumpedBodyT{Reader: io.MultiReader(bytes.NewReader(head), resp.Body), body: resp.Body}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %s\n", resp.Proto, resp.Status)
+
+	dumpHeader(&b, resp.Header)
+
+	fmt.Fprintf(&b, "\n%s", redactString(string(head)))
+
+	if len(head) == _maxDumpBytes {
+		b.WriteString("...[truncated]")
+	}
+
+	b.WriteString("\n")
+
+	return b.String(), nil
+}
+
+// dumpHeader writes header in a stable order with the values of cookies,
+// authorization, and the like redacted
+func dumpHeader(b *strings.Builder, header http.Header) {
+	names := make([]string, 0, len(header))
+
+	for name := range header {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range header[name] {
+			if sensitiveKeyPattern.MatchString(name) {
+				value = redacted
+			}
+
+			fmt.Fprintf(b, "%s: %s\n", name, redactString(value))
+		}
+	}
+}
+
+// dumpedBodyT reads the part of the body already read for the dump and then
+// the rest of it
+type dumpedBodyT struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (b dumpedBodyT) Close() error {
+	return b.body.Close()
+}
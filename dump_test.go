@@ -0,0 +1,95 @@
+package irdata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpRequest(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/auth?token=abc123", strings.NewReader(`{"password":"hunter2"}`))
+	req.Header.Set("Cookie", "irsso=secret1; other=secret2")
+	req.Header.Set("Authorization", "Bearer secret3")
+	req.Header.Set("Accept", "application/json")
+
+	dump := dumpRequest(req)
+
+	assert.Contains(t, dump, "POST https://example.com/auth?token=[REDACTED]")
+	assert.Contains(t, dump, "Accept: application/json")
+	assert.Contains(t, dump, "[body not dumped]")
+
+	for _, secret := range []string{"abc123", "hunter2", "secret1", "secret2", "secret3"} {
+		assert.NotContains(t, dump, secret)
+	}
+}
+
+func TestDumpResponse(t *testing.T) {
+	var gzipped bytes.Buffer
+
+	zw := gzip.NewWriter(&gzipped)
+	fmt.Fprintf(zw, `{"link":"https://s3/x?X-Amz-Signature=abc123","pad":"%s"}`, strings.Repeat("x", _maxDumpBytes))
+	zw.Close()
+
+	resp := &http.Response{
+		Proto:  "HTTP/1.1",
+		Status: "200 OK",
+		Header: http.Header{
+			"Content-Encoding": {"gzip"},
+			"Set-Cookie":       {"irsso=secret1; Path=/"},
+		},
+		Body: io.NopCloser(&gzipped),
+	}
+
+	dump, err := dumpResponse(resp)
+
+	assert.NoError(t, err)
+	assert.Contains(t, dump, "HTTP/1.1 200 OK")
+	assert.Contains(t, dump, "X-Amz-Signature=[REDACTED]")
+	assert.Contains(t, dump, "...[truncated]")
+	assert.NotContains(t, dump, "abc123")
+	assert.NotContains(t, dump, "secret1")
+
+	// the body can still be read in full
+	body, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+	assert.Len(t, body, len(`{"link":"https://s3/x?X-Amz-Signature=abc123","pad":""}`)+_maxDumpBytes)
+}
+
+func TestHTTPDump(t *testing.T) {
+	var buf bytes.Buffer
+
+	level, out := log.GetLevel(), log.Out
+
+	log.SetOutput(&buf)
+	log.SetLevel(logrus.DebugLevel)
+
+	t.Cleanup(func() {
+		log.SetOutput(out)
+		log.SetLevel(level)
+	})
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+
+	_, err := fake.Get("/data/member/info")
+	assert.NoError(t, err)
+	assert.NotContains(t, buf.String(), "HTTP response")
+
+	fake.EnableHTTPDump()
+
+	data, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+	assert.Contains(t, buf.String(), "HTTP request")
+	assert.Contains(t, buf.String(), "HTTP response")
+}
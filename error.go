@@ -1,9 +1,144 @@
 package irdata
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
+// ErrVerificationRequired is returned by the Auth* functions when iRacing has
+// flagged the account for re-verification.  Retrying will not help; the user
+// must log in at https://members-ng.iracing.com and complete the verification
+// (e.g. CAPTCHA or emailed code) before irdata can authenticate again.
+var ErrVerificationRequired = errors.New("irdata: iRacing requires account verification, log in via a browser to complete it")
+
+// ErrUnauthorized is returned by Get when iRacing rejects the session and it
+// couldn't be renewed (e.g. the client was authed without saved creds)
+var ErrUnauthorized = errors.New("irdata: session is no longer authorized, auth again")
+
+// ErrLoggedOut is returned by an auth (including the one Get makes to renew
+// the session) that was still in progress when Logout was called
+var ErrLoggedOut = errors.New("irdata: logged out while authenticating")
+
+// ErrCircuitOpen is returned without making a request while the circuit
+// breaker is open, see SetCircuitBreaker
+var ErrCircuitOpen = errors.New("irdata: circuit breaker is open, iRacing is failing")
+
+// ErrResponseTooLarge is returned by Get when the response (including its
+// chunks) is larger than the limit set with SetMaxResponseBytes
+var ErrResponseTooLarge = errors.New("irdata: response is too large")
+
+// ErrLinkExpired is returned by Get when the s3 link (or data url) the API
+// returned was refused every time it was followed
+var ErrLinkExpired = errors.New("irdata: link to the data has expired")
+
 func makeErrorf(format string, a ...any) error {
 	return fmt.Errorf("irdata: %s", fmt.Sprintf(format, a...))
 }
+
+// Errors returned when an encrypted file (e.g. a creds file) can't be opened
+var (
+	// the file was encrypted with a different key
+	ErrWrongKey = errors.New("irdata: file was encrypted with a different key")
+	// the key is right but the file has been damaged or tampered with
+	ErrCorruptedFile = errors.New("irdata: encrypted file is corrupted")
+	// the file is bound to a different identity (see SetCredsIdentity)
+	ErrIdentityMismatch = errors.New("irdata: file is bound to a different identity")
+	// the file was written in a format this version of irdata doesn't know
+	ErrUnsupportedFileVersion = errors.New("irdata: unsupported encrypted file version")
+	// the file format has no key check value so it's either the wrong key or
+	// a corrupted file (rewriting the file upgrades it to the current format)
+	ErrDecryptionFailed = errors.New("irdata: unable to decrypt file, wrong key or corrupted file")
+)
+
+// RateLimitExceededError is returned by Get when the rate limit would hold a
+// request back for longer than allowed by SetMaxRateLimitWait
+type RateLimitExceededError struct {
+	Wait    time.Duration // how long the request would have had to wait
+	MaxWait time.Duration
+	Reset   time.Time // when the rate limit resets, zero if not reported
+}
+
+func (e *RateLimitExceededError) Error() string {
+	return fmt.Sprintf("irdata: rate limited for %s, more than the maximum wait of %s", e.Wait, e.MaxWait)
+}
+
+// StatusError is returned by Get when the /data API keeps failing with a
+// server error or rate limiting (once retries have given up)
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("irdata: %s failed with status %d", e.URL, e.StatusCode)
+}
+
+// StaleDataError is returned by GetWithCache along with expired cached data
+// when fetching fresh data failed, see SetStaleCacheFallback
+type StaleDataError struct {
+	Expired time.Time // when the data expired
+	Err     error     // why fresh data couldn't be fetched
+}
+
+func (e *StaleDataError) Error() string {
+	return fmt.Sprintf("irdata: serving data that expired at %s [%v]", e.Expired.Format(time.RFC3339), e.Err)
+}
+
+func (e *StaleDataError) Unwrap() error {
+	return e.Err
+}
+
+// ChunkError is returned when one of the chunks of a chunked response
+// could not be fetched or decoded.
+type ChunkError struct {
+	Index      int    // position of the chunk in chunk_file_names
+	URL        string // url the chunk was fetched from
+	StatusCode int    // http status returned for the chunk (0 if no response)
+	Err        error  // underlying error, if any
+}
+
+func (e *ChunkError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("irdata: chunk %d (%s) failed [%v]", e.Index, e.URL, e.Err)
+	}
+
+	return fmt.Sprintf("irdata: chunk %d (%s) failed with status %d", e.Index, e.URL, e.StatusCode)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// ChunkRowCountError is returned when the rows merged from a chunked
+// response don't add up to the row count advertised in its chunk_info.
+type ChunkRowCountError struct {
+	Expected int
+	Actual   int
+}
+
+func (e *ChunkRowCountError) Error() string {
+	return fmt.Sprintf("irdata: chunked response has %d rows, expected %d", e.Actual, e.Expected)
+}
+
+// PartialError is returned alongside the successfully retrieved data when
+// only some of the pieces of a composite result (e.g. the chunks of a chunked
+// response) could be fetched.  Errors holds one error per failed piece.
+type PartialError struct {
+	Errors []error
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, len(e.Errors))
+
+	for n, err := range e.Errors {
+		msgs[n] = err.Error()
+	}
+
+	return fmt.Sprintf("irdata: %d piece(s) failed [%s]", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *PartialError) Unwrap() []error {
+	return e.Errors
+}
@@ -21,6 +21,7 @@ var (
 	cacheDuration time.Duration
 	logDebug      bool
 	authAndStop   bool
+	sanitize      bool
 )
 
 func init() {
@@ -32,6 +33,7 @@ func init() {
 	flag.DurationVar(&cacheDuration, "cachettl", time.Duration(15)*time.Minute, "cache TTL for this call")
 	flag.BoolVar(&logDebug, "v", false, "log verbosely")
 	flag.BoolVar(&authAndStop, "a", false, "just run auth and stop (will generate creds file)")
+	flag.BoolVar(&sanitize, "sanitize", false, "remap cust_ids, fake names, and strip secrets (for test fixtures)")
 }
 
 func main() {
@@ -81,7 +83,10 @@ Example:
 
 	keyFn, credsFn, apiUri := flag.Arg(0), flag.Arg(1), flag.Arg(2)
 
-	api := irdata.Open(context.Background())
+	api, err := irdata.Open(context.Background())
+	if err != nil {
+		log.Panic(err)
+	}
 
 	defer api.Close()
 
@@ -122,6 +127,13 @@ Example:
 		log.Panic(err)
 	}
 
+	if sanitize {
+		data, err = irdata.NewSanitizer().Sanitize(data)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
 	writer := bufio.NewWriter(os.Stdout)
 
 	_, err = writer.Write(data)
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/popmonkey/irdata"
+	"github.com/popmonkey/irdata/schemas"
+)
+
+const toolName = "irschema"
+
+var (
+	showHelp bool
+	outDir   string
+	logDebug bool
+)
+
+func init() {
+	flag.BoolVar(&showHelp, "h", false, "show help")
+	flag.BoolVar(&showHelp, "help", false, "show help")
+	flag.StringVar(&outDir, "o", "schemas", "directory to write the schemas to")
+	flag.BoolVar(&logDebug, "v", false, "log verbosely")
+}
+
+func main() {
+	flag.Parse()
+
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage: %s [options] <path to keyfile> <path to credsfile> <api uri>...\n", toolName)
+		flag.PrintDefaults()
+	}
+
+	if showHelp {
+		fmt.Fprintf(flag.CommandLine.Output(), `
+%[1]s samples /data API endpoints and writes a JSON Schema for each one.
+
+Each uri is fetched with irdata (so s3 links are followed and chunks merged)
+and uris for the same endpoint with different parameters are combined into a
+single schema, so passing several samples of an endpoint gives a more
+complete schema.
+
+Example:
+%[1]s -o schemas ~/my.key ~/ir.creds /data/member/info "/data/results/get?subsession_id=1"
+
+
+
+`, toolName)
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	if len(flag.Args()) < 3 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	keyFn, credsFn, uris := flag.Arg(0), flag.Arg(1), flag.Args()[2:]
+
+	api, err := irdata.Open(context.Background())
+	if err != nil {
+		log.Panic(err)
+	}
+
+	defer api.Close()
+
+	if logDebug {
+		api.SetLogLevel(irdata.LogLevelDebug)
+	} else {
+		api.SetLogLevel(irdata.LogLevelWarn)
+	}
+
+	err = api.AuthWithCredsFromFile(keyFn, credsFn)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// samples grouped by the schema they belong to
+	samples := map[string][][]byte{}
+	order := []string{}
+
+	for _, uri := range uris {
+		data, err := api.Get(uri)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		filename := schemas.Filename(uri)
+
+		if _, ok := samples[filename]; !ok {
+			order = append(order, filename)
+		}
+
+		samples[filename] = append(samples[filename], data)
+	}
+
+	err = os.MkdirAll(outDir, 0755)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	for _, filename := range order {
+		schema, err := schemas.Infer(filename[:len(filename)-len(".schema.json")], samples[filename]...)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		err = os.WriteFile(filepath.Join(outDir, filename), append(schema, '\n'), 0644)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		fmt.Println(filepath.Join(outDir, filename))
+	}
+}
@@ -23,7 +23,10 @@ var credsProvider irdata.CredsFromTerminal
 
 func main() {
 	// get an instance of irdata
-	i := irdata.Open(context.Background())
+	i, err := irdata.Open(context.Background())
+	if err != nil {
+		log.Panic(err)
+	}
 
 	defer i.Close()
 
@@ -0,0 +1,191 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fake cust_ids start here so they are easy to spot
+const _firstFakeCustID = 100000
+
+// keys whose values identify a member by id
+var custIDKeyPattern = regexp.MustCompile(`(?i)^(.*_)?cust_?id$`)
+
+// keys whose values are a person's name or contact details
+var personKeyPattern = regexp.MustCompile(`(?i)^(.*_)?(display_name|first_name|last_name|name_mid|email)$`)
+
+// Sanitizer replaces personal data in API results with fakes so the results
+// can be committed as test fixtures.  cust_ids are remapped and names are
+// replaced consistently across everything sanitized by the same Sanitizer so
+// relationships between fixtures are preserved.  Secrets (tokens, signed
+// urls, etc) are redacted.
+type Sanitizer struct {
+	mu      sync.Mutex
+	custIDs map[string]int64
+	names   map[string]string
+}
+
+func NewSanitizer() *Sanitizer {
+	return &Sanitizer{
+		custIDs: map[string]int64{},
+		names:   map[string]string{},
+	}
+}
+
+// Sanitize returns a sanitized copy of the JSON in data
+func (s *Sanitizer) Sanitize(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+
+	if err := dec.Decode(&v); err != nil {
+		return nil, makeErrorf("unable to decode data to sanitize [%v]", err)
+	}
+
+	s.mu.Lock()
+	v = s.sanitizeValue("", v)
+	s.mu.Unlock()
+
+	sanitized, err := json.Marshal(v)
+	if err != nil {
+		return nil, makeErrorf("unable to encode sanitized data [%v]", err)
+	}
+
+	return sanitized, nil
+}
+
+// SanitizeURI remaps cust_ids found in the query parameters of uri
+func (s *Sanitizer) SanitizeURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return redactString(uri)
+	}
+
+	query := u.Query()
+
+	s.mu.Lock()
+
+	for k, values := range query {
+		for n, value := range values {
+			if custIDKeyPattern.MatchString(k) {
+				values[n] = strconv.FormatInt(s.fakeCustID(value), 10)
+			}
+		}
+	}
+
+	s.mu.Unlock()
+
+	u.RawQuery = query.Encode()
+
+	return redactString(u.String())
+}
+
+func (s *Sanitizer) sanitizeValue(key string, v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		// walk in a stable order so the fakes handed out are deterministic
+		keys := make([]string, 0, len(value))
+
+		for k := range value {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			value[k] = s.sanitizeValue(k, value[k])
+		}
+		return value
+	case []interface{}:
+		for n, child := range value {
+			// elements of an array take on the array's key (e.g. cust_ids)
+			value[n] = s.sanitizeValue(strings.TrimSuffix(key, "s"), child)
+		}
+		return value
+	case json.Number:
+		if custIDKeyPattern.MatchString(key) {
+			return json.Number(strconv.FormatInt(s.fakeCustID(value.String()), 10))
+		}
+		return value
+	case string:
+		if sensitiveKeyPattern.MatchString(key) {
+			return redacted
+		}
+		if personKeyPattern.MatchString(key) && value != "" {
+			return s.fakeName(value)
+		}
+		return redactString(value)
+	default:
+		return v
+	}
+}
+
+func (s *Sanitizer) fakeCustID(custID string) int64 {
+	fake, ok := s.custIDs[custID]
+	if !ok {
+		fake = int64(_firstFakeCustID + len(s.custIDs))
+		s.custIDs[custID] = fake
+	}
+
+	return fake
+}
+
+func (s *Sanitizer) fakeName(name string) string {
+	fake, ok := s.names[name]
+	if !ok {
+		fake = fmt.Sprintf("Driver %d", len(s.names)+1)
+		s.names[name] = fake
+	}
+
+	return fake
+}
+
+// fixtureFilename turns a (sanitized) uri into a file name
+var fixtureFilenamePattern = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func fixtureFilename(uri string) string {
+	return strings.Trim(fixtureFilenamePattern.ReplaceAllString(uri, "_"), "_") + ".json"
+}
+
+// WriteFixtures fetches each of the uris and writes the results, sanitized by
+// sanitizer, as JSON files in dir.  The files are named after the sanitized
+// uri (e.g. /data/member/info becomes data_member_info.json).
+func (i *Irdata) WriteFixtures(dir string, uris []string, sanitizer *Sanitizer) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return makeErrorf("unable to create %s [%v]", dir, err)
+	}
+
+	for _, uri := range uris {
+		data, err := i.Get(uri)
+		if err != nil {
+			return err
+		}
+
+		sanitized, err := sanitizer.Sanitize(data)
+		if err != nil {
+			return err
+		}
+
+		fn := filepath.Join(dir, fixtureFilename(sanitizer.SanitizeURI(uri)))
+
+		if err := os.WriteFile(fn, sanitized, 0644); err != nil {
+			return makeErrorf("unable to write %s [%v]", fn, err)
+		}
+
+		log.WithFields(logrus.Fields{"uri": uri, "fn": fn}).Debug("Wrote fixture")
+	}
+
+	return nil
+}
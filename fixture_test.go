@@ -0,0 +1,51 @@
+package irdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	s := NewSanitizer()
+
+	data, err := s.Sanitize([]byte(`{
+		"cust_id": 123456,
+		"display_name": "Ayrton Senna",
+		"track": {"track_name": "Monaco"},
+		"results": [
+			{"cust_id": 654321, "display_name": "Alain Prost", "helmet": {"color1": "ffffff"}},
+			{"cust_id": 123456, "display_name": "Ayrton Senna"}
+		],
+		"owner_cust_id": 654321,
+		"admin_cust_ids": [123456],
+		"link": "https://s3/x?X-Amz-Signature=abc"
+	}`))
+
+	assert.NoError(t, err)
+
+	o := getJsonObject(t, data)
+
+	assert.Equal(t, float64(100000), o["cust_id"])
+	assert.Equal(t, "Driver 1", o["display_name"])
+	assert.Equal(t, "Monaco", o["track"].(map[string]interface{})["track_name"])
+
+	results := o["results"].([]interface{})
+
+	assert.Equal(t, float64(100001), results[0].(map[string]interface{})["cust_id"])
+	assert.Equal(t, "Driver 2", results[0].(map[string]interface{})["display_name"])
+	assert.Equal(t, float64(100000), results[1].(map[string]interface{})["cust_id"])
+	assert.Equal(t, "Driver 1", results[1].(map[string]interface{})["display_name"])
+	assert.Equal(t, float64(100001), o["owner_cust_id"])
+	assert.Equal(t, []interface{}{float64(100000)}, o["admin_cust_ids"])
+	assert.NotContains(t, string(data), "abc")
+	assert.NotContains(t, string(data), "Senna")
+
+	// the mapping carries over to uris
+	assert.Equal(t, "/data/member/info?cust_id=100001", s.SanitizeURI("/data/member/info?cust_id=654321"))
+}
+
+func TestFixtureFilename(t *testing.T) {
+	assert.Equal(t, "data_member_info.json", fixtureFilename("/data/member/info"))
+	assert.Equal(t, "data_results_get_subsession_id_1234.json", fixtureFilename("/data/results/get?subsession_id=1234"))
+}
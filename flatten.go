@@ -0,0 +1,172 @@
+package irdata
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// ArrayMode controls how Flatten handles arrays nested in a row
+type ArrayMode int
+
+const (
+	// ArraysAsJSON stores the array as a JSON encoded string
+	ArraysAsJSON ArrayMode = iota
+	// ArraysIndexed flattens each element into a column suffixed with its index
+	ArraysIndexed
+	// ArraysIgnored drops the array
+	ArraysIgnored
+)
+
+// FlattenOptions controls how Flatten turns nested objects into columns
+type FlattenOptions struct {
+	// Separator joins the keys of nested objects into a column name, "_" if
+	// empty
+	Separator string
+	Arrays    ArrayMode
+}
+
+// Flatten turns a result into rows suitable for tabular export.  A top level
+// array gives a row per element, a chunked result (see ChunkDataKey) gives a
+// row per chunk row, and any other object is a single row.
+//
+// Nested objects become columns named by joining their path with
+// opts.Separator (e.g. car_class_name).  The returned columns are the sorted
+// union of the columns of all rows.
+func Flatten(data []byte, opts FlattenOptions) ([]map[string]interface{}, []string, error) {
+	var raw interface{}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, makeErrorf("unable to decode data to flatten [%v]", err)
+	}
+
+	var list []interface{}
+
+	switch value := raw.(type) {
+	case []interface{}:
+		list = value
+	case map[string]interface{}:
+		var chunked bool
+
+		if list, chunked = chunkData(value); !chunked {
+			list = []interface{}{value}
+		}
+	default:
+		return nil, nil, makeErrorf("unable to flatten %T", raw)
+	}
+
+	rows := make([]map[string]interface{}, 0, len(list))
+	seen := map[string]bool{}
+	columns := []string{}
+
+	for _, rowI := range list {
+		o, ok := rowI.(map[string]interface{})
+		if !ok {
+			o = map[string]interface{}{"value": rowI}
+		}
+
+		row := FlattenRow(o, opts)
+
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	sort.Strings(columns)
+
+	return rows, columns, nil
+}
+
+// FlattenRow flattens a single decoded object the same way Flatten does
+func FlattenRow(o map[string]interface{}, opts FlattenOptions) map[string]interface{} {
+	separator := opts.Separator
+	if separator == "" {
+		separator = "_"
+	}
+
+	row := map[string]interface{}{}
+
+	var walk func(path string, v interface{})
+
+	walk = func(path string, v interface{}) {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			for k, inner := range value {
+				walk(joinPath(path, k, separator), inner)
+			}
+		case []interface{}:
+			switch opts.Arrays {
+			case ArraysIndexed:
+				for n, inner := range value {
+					walk(joinPath(path, strconv.Itoa(n), separator), inner)
+				}
+			case ArraysIgnored:
+			default:
+				data, _ := json.Marshal(value)
+				row[path] = string(data)
+			}
+		default:
+			row[path] = v
+		}
+	}
+
+	for k, v := range o {
+		walk(k, v)
+	}
+
+	return row
+}
+
+func joinPath(path string, k string, separator string) string {
+	if path == "" {
+		return k
+	}
+
+	return path + separator + k
+}
+
+// chunkData returns the rows of a chunked result, which are either at the top
+// level or inside of "data" depending on the endpoint, and whether o is one
+func chunkData(o map[string]interface{}) ([]interface{}, bool) {
+	rows, ok := o[ChunkDataKey]
+	if !ok {
+		inner, isMap := o["data"].(map[string]interface{})
+		if !isMap {
+			return nil, false
+		}
+
+		if rows, ok = inner[ChunkDataKey]; !ok {
+			return nil, false
+		}
+	}
+
+	list, _ := rows.([]interface{})
+
+	return list, true
+}
+
+// chunkRows returns the object rows of a chunked result
+func chunkRows(data []byte) ([]map[string]interface{}, error) {
+	var raw map[string]interface{}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, makeErrorf("unable to decode chunked result [%v]", err)
+	}
+
+	list, _ := chunkData(raw)
+
+	rows := make([]map[string]interface{}, 0, len(list))
+
+	for _, rowI := range list {
+		if row, ok := rowI.(map[string]interface{}); ok {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,53 @@
+package irdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlattenObject(t *testing.T) {
+	rows, columns, err := Flatten([]byte(`{"a":1,"b":{"c":"x","d":{"e":true}},"f":[1,2]}`), FlattenOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b_c", "b_d_e", "f"}, columns)
+	assert.Equal(t, []map[string]interface{}{
+		{"a": 1.0, "b_c": "x", "b_d_e": true, "f": "[1,2]"},
+	}, rows)
+}
+
+func TestFlattenArrayModes(t *testing.T) {
+	data := []byte(`[{"a":{"b":[{"c":1},{"c":2}]}}]`)
+
+	rows, columns, err := Flatten(data, FlattenOptions{Separator: ".", Arrays: ArraysIndexed})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.b.0.c", "a.b.1.c"}, columns)
+	assert.Equal(t, 2.0, rows[0]["a.b.1.c"])
+
+	_, columns, err = Flatten(data, FlattenOptions{Arrays: ArraysIgnored})
+
+	assert.NoError(t, err)
+	assert.Empty(t, columns)
+}
+
+func TestFlattenChunked(t *testing.T) {
+	rows, columns, err := Flatten([]byte(`{"data":{"_chunk_data":[{"a":1},{"b":2}]}}`), FlattenOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, columns)
+	assert.Len(t, rows, 2)
+
+	rows, _, err = Flatten([]byte(`{"_chunk_data":[{"a":1}]}`), FlattenOptions{})
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+}
+
+func TestFlattenInvalid(t *testing.T) {
+	_, _, err := Flatten([]byte(`{`), FlattenOptions{})
+	assert.Error(t, err)
+
+	_, _, err = Flatten([]byte(`3`), FlattenOptions{})
+	assert.Error(t, err)
+}
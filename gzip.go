@@ -0,0 +1,50 @@
+package irdata
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptGzip asks for a gzipped response.  Setting the header ourselves
+// turns off net/http's transparent decompression, so responses are
+// decompressed by gunzipResponse instead, which works with any transport.
+func acceptGzip(req *http.Request) {
+	req.Header.Set("Accept-Encoding", "gzip")
+}
+
+type gzipBodyT struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b gzipBodyT) Close() error {
+	b.Reader.Close()
+
+	return b.body.Close()
+}
+
+// gunzipResponse replaces a gzipped body with a reader of its decompressed
+// content
+func gunzipResponse(resp *http.Response) error {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		drainAndClose(resp)
+
+		return makeErrorf("unable to decompress response [%v]", err)
+	}
+
+	resp.Body = gzipBodyT{Reader: zr, body: resp.Body}
+
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return nil
+}
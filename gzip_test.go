@@ -0,0 +1,60 @@
+package irdata
+
+import (
+	"compress/gzip"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzipResponses(t *testing.T) {
+	setupRetryTest(t)
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write([]byte("not gzip"))
+			return
+		}
+
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Write([]byte(`{"compressed":false}`))
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(`{"compressed":true}`))
+		zw.Close()
+	}))
+
+	data, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"compressed":true}`, string(data))
+
+	_, err = fake.Get("/broken")
+	assert.Error(t, err)
+
+	// cassettes hold the decompressed body
+	cassetteFn := filepath.Join(t.TempDir(), "cassette.json")
+
+	fake.StartRecording(cassetteFn)
+
+	data, err = fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"compressed":true}`, string(data))
+	assert.NoError(t, fake.StopRecording())
+
+	cassette, err := os.ReadFile(cassetteFn)
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(cassette), `compressed`)
+	assert.NotContains(t, string(cassette), `"Content-Encoding"`)
+}
@@ -0,0 +1,53 @@
+package irdata
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// sent unless SetUserAgent says otherwise
+const _defaultUserAgent = "irdata (+https://github.com/popmonkey/irdata)"
+
+// SetUserAgent sets the User-Agent sent with every request so iRacing can
+// tell which tool the traffic comes from.  Empty restores the default.
+func (i *Irdata) SetUserAgent(userAgent string) {
+	i.SetDefaultHeader("User-Agent", userAgent)
+}
+
+// SetDefaultHeader sets a header sent with every request (logins, /data
+// requests, and the s3 links, data urls, and chunks they lead to).  An empty
+// value stops sending it.
+func (i *Irdata) SetDefaultHeader(key string, value string) {
+	i.headersMu.Lock()
+	defer i.headersMu.Unlock()
+
+	if i.headers == nil {
+		i.headers = http.Header{}
+	}
+
+	if value == "" {
+		i.headers.Del(key)
+	} else {
+		i.headers.Set(key, value)
+	}
+}
+
+// newRequest is http.NewRequestWithContext with the default headers set
+func (i *Irdata) newRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", _defaultUserAgent)
+
+	i.headersMu.Lock()
+	defer i.headersMu.Unlock()
+
+	for key, values := range i.headers {
+		req.Header[key] = append([]string{}, values...)
+	}
+
+	return req, nil
+}
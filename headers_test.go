@@ -0,0 +1,51 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultHeaders(t *testing.T) {
+	setupRetryTest(t)
+
+	seen := map[string]http.Header{}
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen[r.URL.Path] = r.Header.Clone()
+		fmt.Fprint(w, `{}`)
+	}))
+
+	_, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.Equal(t, _defaultUserAgent, seen["/data/member/info"].Get("User-Agent"))
+
+	fake.SetUserAgent("league-bot/1.0")
+	fake.SetDefaultHeader("X-Contact", "admin@example.com")
+
+	fake.isAuthed = false
+
+	assert.NoError(t, fake.AuthWithProvideCreds(testCredsProvider{}))
+	assert.NoError(t, fake.ValidateCreds(testCredsProvider{}))
+
+	_, err = fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+
+	for _, path := range []string{"/auth", testURI, "/data/member/info"} {
+		assert.Equal(t, "league-bot/1.0", seen[path].Get("User-Agent"), path)
+		assert.Equal(t, "admin@example.com", seen[path].Get("X-Contact"), path)
+	}
+
+	fake.SetUserAgent("")
+	fake.SetDefaultHeader("X-Contact", "")
+
+	_, err = fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.Equal(t, _defaultUserAgent, seen["/data/member/info"].Get("User-Agent"))
+	assert.Empty(t, seen["/data/member/info"].Get("X-Contact"))
+}
@@ -0,0 +1,110 @@
+package irdata
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetDownloadHedging makes s3 link, data url, and chunk downloads that
+// haven't responded within delay start a second, identical download and use
+// whichever responds first, trimming the long tail of slow S3 fetches at the
+// cost of some extra requests.  Zero (the default) turns hedging off.
+func (i *Irdata) SetDownloadHedging(delay time.Duration) {
+	i.hedgeDelay = delay
+}
+
+type hedgeResultT struct {
+	n    int
+	resp *http.Response
+	err  error
+}
+
+// hedgedFetch is retryingFetch racing a second fetch started after delay
+func (i *Irdata) hedgedFetch(ctx context.Context, kind hostKindT, url string, policy RetryPolicy, delay time.Duration) (*http.Response, error) {
+	results := make(chan hedgeResultT, 2)
+
+	var cancels []context.CancelFunc
+
+	start := func() {
+		fetchCtx, cancel := context.WithCancel(ctx)
+
+		n := len(cancels)
+		cancels = append(cancels, cancel)
+
+		go func() {
+			resp, err := i.retryingFetch(fetchCtx, kind, url, policy)
+			results <- hedgeResultT{n: n, resp: resp, err: err}
+		}()
+	}
+
+	start()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	pending := 1
+
+	for {
+		select {
+		case <-timer.C:
+			log.WithFields(logrus.Fields{"url": url, "delay": delay}).Info("Slow download, hedging")
+
+			start()
+
+			pending++
+
+		case r := <-results:
+			pending--
+
+			failed := r.err != nil || r.resp.StatusCode >= 400
+
+			// the other may still succeed
+			if failed && pending > 0 {
+				drainAndClose(r.resp)
+				cancels[r.n]()
+
+				continue
+			}
+
+			// abandon the other, if there is one
+			for n, cancel := range cancels {
+				if n != r.n {
+					cancel()
+				}
+			}
+
+			for ; pending > 0; pending-- {
+				go func() {
+					loser := <-results
+					drainAndClose(loser.resp)
+				}()
+			}
+
+			if r.err != nil {
+				cancels[r.n]()
+
+				return nil, r.err
+			}
+
+			r.resp.Body = cancelBodyT{ReadCloser: r.resp.Body, cancel: cancels[r.n]}
+
+			return r.resp, nil
+		}
+	}
+}
+
+// cancelBodyT cancels the request's context once the body is closed
+type cancelBodyT struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBodyT) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,65 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadHedging(t *testing.T) {
+	var linkBase string
+
+	var downloads int32
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/member/info":
+			fmt.Fprintf(w, `{"link":"%s/s3/info"}`, linkBase)
+		case "/s3/info":
+			// the first download is slow
+			if atomic.AddInt32(&downloads, 1) == 1 {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(2 * time.Second):
+				}
+
+				fmt.Fprint(w, `{"slow":true}`)
+
+				return
+			}
+
+			fmt.Fprint(w, `{"ok":true}`)
+		default:
+			// fails fast
+			http.Error(w, "gone", http.StatusNotFound)
+		}
+	}))
+
+	linkBase = fake.baseURL.String()
+
+	fake.SetDownloadHedging(20 * time.Millisecond)
+
+	started := time.Now()
+
+	data, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+	assert.Less(t, time.Since(started), time.Second)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&downloads))
+
+	// a download that fails before the delay isn't hedged
+	resp, err := fake.retryingDownload(context.Background(), linkBase+"/missing")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&downloads))
+}
@@ -0,0 +1,124 @@
+package irdata
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// requests go to one of two kinds of host: the /data API itself, which is
+// rate limited, and the S3 hosts its s3 links, data urls, and chunks point
+// at, which aren't
+type hostKindT int
+
+const (
+	_apiHost hostKindT = iota
+	_downloadHost
+)
+
+func (k hostKindT) String() string {
+	if k == _downloadHost {
+		return "download"
+	}
+
+	return "api"
+}
+
+// SetDownloadRetryPolicy sets the policy for retrying s3 links, data urls,
+// and chunks separately from the /data API requests.  nil (the default) uses
+// the policy set with SetRetryPolicy for both.
+func (i *Irdata) SetDownloadRetryPolicy(policy RetryPolicy) {
+	i.retryPolicyMu.Lock()
+	defer i.retryPolicyMu.Unlock()
+
+	i.downloadRetryPolicy = policy
+}
+
+func (i *Irdata) getDownloadRetryPolicy() RetryPolicy {
+	i.retryPolicyMu.Lock()
+	policy := i.downloadRetryPolicy
+	i.retryPolicyMu.Unlock()
+
+	if policy == nil {
+		return i.getRetryPolicy()
+	}
+
+	return policy
+}
+
+// SetMaxConcurrentRequests limits how many /data API requests may be in
+// flight at once, across all goroutines using this client.  Zero (the
+// default) is no limit.
+func (i *Irdata) SetMaxConcurrentRequests(n int) {
+	i.apiSlots.setLimit(n)
+}
+
+// SetMaxConcurrentDownloads limits how many s3 link, data url, and chunk
+// downloads may be in flight at once, across all goroutines using this
+// client.  Zero (the default) is no limit.
+func (i *Irdata) SetMaxConcurrentDownloads(n int) {
+	i.downloadSlots.setLimit(n)
+}
+
+func (i *Irdata) slots(kind hostKindT) *slotsT {
+	if kind == _downloadHost {
+		return &i.downloadSlots
+	}
+
+	return &i.apiSlots
+}
+
+// slotsT limits how many requests are in flight at once.  A request holds
+// its slot until its body is closed.
+type slotsT struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func (s *slotsT) setLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n < 1 {
+		s.ch = nil
+		return
+	}
+
+	s.ch = make(chan struct{}, n)
+}
+
+// acquire waits for a slot (or for ctx to be done) and returns the func that
+// gives it back
+func (s *slotsT) acquire(ctx context.Context) (release func(), err error) {
+	s.mu.Lock()
+	ch := s.ch
+	s.mu.Unlock()
+
+	if ch == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case ch <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() { <-ch })
+	}, nil
+}
+
+// slotBodyT gives the request's slot back when the body is closed
+type slotBodyT struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b slotBodyT) Close() error {
+	defer b.release()
+
+	return b.ReadCloser.Close()
+}
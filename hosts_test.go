@@ -0,0 +1,145 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadRetryPolicy(t *testing.T) {
+	var linkBase string
+
+	var linkCalls int32
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/member/info":
+			fmt.Fprintf(w, `{"link":"%s/s3/info"}`, linkBase)
+		default:
+			if atomic.AddInt32(&linkCalls, 1) < 3 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+
+	linkBase = fake.baseURL.String()
+
+	var apiAttempts, downloadAttempts int
+
+	fake.SetRetryPolicy(retryPolicyFunc(func(attempt RetryAttempt) (time.Duration, bool) {
+		apiAttempts++
+		return 0, false
+	}))
+
+	fake.SetDownloadRetryPolicy(retryPolicyFunc(func(attempt RetryAttempt) (time.Duration, bool) {
+		downloadAttempts++
+		return time.Millisecond, true
+	}))
+
+	data, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+	assert.Equal(t, 0, apiAttempts)
+	assert.Equal(t, 2, downloadAttempts)
+
+	// without one the downloads use the api policy
+	atomic.StoreInt32(&linkCalls, 0)
+
+	fake.SetDownloadRetryPolicy(nil)
+
+	fake.Get("/data/member/info")
+
+	assert.Equal(t, 1, apiAttempts)
+	assert.Equal(t, 2, downloadAttempts)
+}
+
+func TestSlots(t *testing.T) {
+	var s slotsT
+
+	// no limit
+	release, err := s.acquire(context.Background())
+	assert.NoError(t, err)
+	release()
+
+	s.setLimit(1)
+
+	release, err = s.acquire(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = s.acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// releasing twice gives back one slot
+	release()
+	release()
+
+	release, err = s.acquire(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = s.acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+}
+
+func TestMaxConcurrentDownloads(t *testing.T) {
+	var linkBase string
+
+	var inFlight, maxInFlight int32
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/s3/info" {
+			fmt.Fprintf(w, `{"link":"%s/s3/info"}`, linkBase)
+			return
+		}
+
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		w.Write([]byte(`{}`))
+	}))
+
+	linkBase = fake.baseURL.String()
+
+	fake.SetMaxConcurrentDownloads(1)
+
+	done := make(chan error)
+
+	for n := 0; n < 4; n++ {
+		go func(n int) {
+			// different uris so the Gets aren't shared
+			_, err := fake.Get(fmt.Sprintf("/data/member/info?n=%d", n))
+			done <- err
+		}(n)
+	}
+
+	for n := 0; n < 4; n++ {
+		assert.NoError(t, <-done)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxInFlight))
+}
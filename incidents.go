@@ -0,0 +1,183 @@
+package irdata
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// car contacts this close together are considered the same incident
+const _contactWindow = 2 * time.Second
+
+// Incident is an incident from a subsession's event log
+type Incident struct {
+	SimsessionNumber int
+	Lap              int
+	SessionTime      time.Duration
+	CustID           int64
+	GroupID          int64
+	DisplayName      string
+	Points           int
+	Description      string
+	// the other drivers with a car contact at about the same time
+	Involved []int64
+}
+
+// DriverIncidents is the incidents of one driver
+type DriverIncidents struct {
+	CustID      int64
+	DisplayName string
+	Count       int
+	Points      int
+	Incidents   []Incident
+}
+
+type eventLogT struct {
+	SimsessionNumber int    `json:"simsession_number"`
+	SessionTime      int64  `json:"session_time"`
+	EventSeq         int64  `json:"event_seq"`
+	GroupID          int64  `json:"group_id"`
+	CustID           int64  `json:"cust_id"`
+	DisplayName      string `json:"display_name"`
+	LapNumber        int    `json:"lap_number"`
+	Description      string `json:"description"`
+	Message          string `json:"message"`
+}
+
+var incidentPointsPattern = regexp.MustCompile(`(\d+)x`)
+
+// incident points by the kind of incident, for descriptions without an Nx
+var incidentKindPoints = []struct {
+	kind   string
+	points int
+}{
+	// longest first so "car contact" isn't taken for "contact"
+	{LapEventCarContact, 4},
+	{LapEventLostControl, 2},
+	{LapEventOffTrack, 1},
+	{LapEventContact, 0},
+}
+
+// incidentPoints returns the points of an event log entry, ok is false if the
+// entry isn't an incident
+func incidentPoints(text string) (points int, ok bool) {
+	if match := incidentPointsPattern.FindStringSubmatch(text); match != nil {
+		points, _ = strconv.Atoi(match[1])
+		return points, true
+	}
+
+	lower := strings.ToLower(text)
+
+	for _, kind := range incidentKindPoints {
+		if strings.Contains(lower, kind.kind) {
+			return kind.points, true
+		}
+	}
+
+	return 0, false
+}
+
+// ParseIncidents returns the incidents in a /data/results/event_log result in
+// the order they happened
+func ParseIncidents(data []byte) ([]Incident, error) {
+	var raw struct {
+		ChunkData []eventLogT `json:"_chunk_data"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, makeErrorf("unable to decode event log [%v]", err)
+	}
+
+	sort.SliceStable(raw.ChunkData, func(a, b int) bool {
+		return raw.ChunkData[a].EventSeq < raw.ChunkData[b].EventSeq
+	})
+
+	var incidents []Incident
+
+	for _, event := range raw.ChunkData {
+		description := strings.TrimSpace(event.Description + " " + event.Message)
+
+		points, ok := incidentPoints(description)
+		if !ok {
+			continue
+		}
+
+		incidents = append(incidents, Incident{
+			SimsessionNumber: event.SimsessionNumber,
+			Lap:              event.LapNumber,
+			SessionTime:      time.Duration(event.SessionTime) * _lapTimeUnit,
+			CustID:           event.CustID,
+			GroupID:          event.GroupID,
+			DisplayName:      event.DisplayName,
+			Points:           points,
+			Description:      description,
+		})
+	}
+
+	linkContacts(incidents)
+
+	return incidents, nil
+}
+
+// linkContacts fills in Involved for car contacts that happened together
+func linkContacts(incidents []Incident) {
+	isContact := func(incident Incident) bool {
+		return strings.Contains(strings.ToLower(incident.Description), LapEventCarContact)
+	}
+
+	for a := range incidents {
+		if !isContact(incidents[a]) {
+			continue
+		}
+
+		for b := range incidents {
+			if a == b || !isContact(incidents[b]) ||
+				incidents[a].SimsessionNumber != incidents[b].SimsessionNumber ||
+				incidents[a].CustID == incidents[b].CustID {
+				continue
+			}
+
+			gap := incidents[a].SessionTime - incidents[b].SessionTime
+			if gap < 0 {
+				gap = -gap
+			}
+
+			if gap <= _contactWindow {
+				incidents[a].Involved = append(incidents[a].Involved, incidents[b].CustID)
+			}
+		}
+	}
+}
+
+// IncidentsByDriver totals incidents per driver, most points first
+func IncidentsByDriver(incidents []Incident) []DriverIncidents {
+	byDriver := map[int64]*DriverIncidents{}
+
+	var order []int64
+
+	for _, incident := range incidents {
+		driver, ok := byDriver[incident.CustID]
+		if !ok {
+			driver = &DriverIncidents{CustID: incident.CustID, DisplayName: incident.DisplayName}
+			byDriver[incident.CustID] = driver
+			order = append(order, incident.CustID)
+		}
+
+		driver.Count++
+		driver.Points += incident.Points
+		driver.Incidents = append(driver.Incidents, incident)
+	}
+
+	drivers := make([]DriverIncidents, 0, len(order))
+
+	for _, custID := range order {
+		drivers = append(drivers, *byDriver[custID])
+	}
+
+	sort.SliceStable(drivers, func(a, b int) bool { return drivers[a].Points > drivers[b].Points })
+
+	return drivers
+}
@@ -0,0 +1,56 @@
+package irdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncidentPoints(t *testing.T) {
+	for text, want := range map[string]int{
+		"4x (car contact)": 4,
+		"Car Contact":      4,
+		"lost control":     2,
+		"Off Track":        1,
+		"contact":          0,
+	} {
+		points, ok := incidentPoints(text)
+		assert.True(t, ok, text)
+		assert.Equal(t, want, points, text)
+	}
+
+	_, ok := incidentPoints("pitted")
+	assert.False(t, ok)
+}
+
+func TestParseIncidents(t *testing.T) {
+	incidents, err := ParseIncidents([]byte(`{"_chunk_data":[
+		{"event_seq":3,"cust_id":2,"display_name":"B","lap_number":5,"session_time":1010000,"description":"4x car contact"},
+		{"event_seq":2,"cust_id":1,"display_name":"A","lap_number":5,"session_time":1000000,"description":"4x car contact"},
+		{"event_seq":1,"cust_id":1,"display_name":"A","lap_number":2,"session_time":400000,"description":"1x off track"},
+		{"event_seq":4,"cust_id":3,"display_name":"C","lap_number":6,"session_time":2000000,"description":"entered pits"}
+	]}`))
+
+	assert.NoError(t, err)
+	assert.Len(t, incidents, 3)
+
+	assert.Equal(t, 2, incidents[0].Lap)
+	assert.Equal(t, 40*time.Second, incidents[0].SessionTime)
+	assert.Empty(t, incidents[0].Involved)
+
+	assert.Equal(t, []int64{2}, incidents[1].Involved)
+	assert.Equal(t, []int64{1}, incidents[2].Involved)
+
+	drivers := IncidentsByDriver(incidents)
+
+	assert.Len(t, drivers, 2)
+	assert.Equal(t, int64(1), drivers[0].CustID)
+	assert.Equal(t, 5, drivers[0].Points)
+	assert.Equal(t, 2, drivers[0].Count)
+	assert.Equal(t, int64(2), drivers[1].CustID)
+	assert.Equal(t, 4, drivers[1].Points)
+
+	_, err = ParseIncidents([]byte(`{`))
+	assert.Error(t, err)
+}
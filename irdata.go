@@ -16,21 +16,138 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"sync"
 	"time"
 
 	"git.mills.io/prologic/bitcask"
-	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus"
 )
 
 type Irdata struct {
-	httpClient http.Client
-	isAuthed   bool
-	cask       *bitcask.Bitcask
+	httpClient     http.Client
+	jar            *sessionJarT // the session cookies, see do
+	isAuthed       bool
+	cask           *bitcask.Bitcask
+	secureMemory   bool
+	credsMaxAge    time.Duration
+	credsAgeHook   func(CredsAgeWarning)
+	credsIdentity  string
+	credsFileMode  os.FileMode
+	strictSecurity bool
+	recorder       *recorderT
+
+	keyFilePermissionPolicy KeyFilePermissionPolicy
+
+	// where requests go, see SetBaseURL and SetLoginURL
+	baseURL  *url.URL
+	loginURL string
+
+	// guards isAuthed, authData, authPassword, authFlight, session, authEvents, and the
+	// auth retry policy
+	authMu sync.Mutex
+
+	// kept from the last successful auth to renew the session, see Get.  Its
+	// EncodedPassword is moved to authPassword, see keepAuthData.
+	authData     *authDataT
+	authPassword *secret
+
+	// the login in progress, if any
+	authFlight *authFlightT
+
+	// the session generation, counting logins and logouts, so a request
+	// that failed with an old session doesn't expire a newer one and the
+	// results of requests and logins started before a Logout are dropped
+	session uint64
+
+	// see AuthEvents
+	authEvents chan AuthEvent
+
+	// see SetAuthRetryPolicy
+	authMaxAttempts int
+	authBackoff     BackoffFunc
+
+	// see SetRetryPolicy
+	retryPolicyMu sync.Mutex
+	retryPolicy   RetryPolicy
+
+	// see SetDownloadRetryPolicy
+	downloadRetryPolicy RetryPolicy
+
+	// see SetMaxConcurrentRequests and SetMaxConcurrentDownloads
+	apiSlots      slotsT
+	downloadSlots slotsT
+
+	// see SetDownloadHedging
+	hedgeDelay time.Duration
+
+	// see EnableHTTPDump
+	httpDump bool
+
+	// see RateLimitStatus
+	rateLimit rateLimitStateT
+
+	// see SetTargetRequestRate, nil when pacing is off
+	pacer *pacerT
+
+	// see SetMaxRateLimitWait
+	maxRateLimitWait time.Duration
+
+	// see SetRateLimitStore
+	rateLimitStore RateLimitStore
+
+	// see SetLowPriorityThreshold
+	lowPriorityThreshold int
+
+	// see SetRateBudgets, nil when there are none
+	budgets *budgetsT
+
+	// see SetAdaptivePacing, nil when it's off
+	adaptivePacer *adaptivePacerT
+
+	// see SetStaleCacheFallback
+	cacheMaxStale time.Duration
+
+	// see SetCacheCompression, nil for the default
+	cacheCompressor CacheCompressor
+
+	// see SetCachePolicy
+	cachePoliciesMu sync.Mutex
+	cachePolicies   map[string]time.Duration
+
+	// see StartCacheRefresh
+	refresherMu sync.Mutex
+	refresher   *refresherT
+
+	// see SetCacheIgnoredParams
+	cacheIgnoredParams []string
+
+	// see SetNegativeCacheTTL
+	negativeCacheTTL time.Duration
+
+	// see SetCircuitBreaker, nil when it's off
+	breaker *breakerT
+
+	// see SetDefaultHeader
+	headersMu sync.Mutex
+	headers   http.Header
+
+	// see SetTracer
+	tracer Tracer
+
+	// see SetMetrics
+	metrics Metrics
+
+	// see SetMaxResponseBytes
+	maxResponseBytes int64
+
+	// Gets in progress by uri, see sharedGet
+	getFlightsMu sync.Mutex
+	getFlights   map[string]*getFlightT
 }
 
 type LogLevel int8
@@ -47,6 +164,9 @@ type s3LinkT struct {
 	Link string
 }
 
+// how many times Get asks for a new link when the one it got has expired
+const _maxLinkRefetches = 3
+
 const ChunkDataKey = "_chunk_data"
 
 type dataUrlT struct {
@@ -62,45 +182,119 @@ type dataUrlT struct {
 
 const rootURL = "https://members-ng.iracing.com"
 
+// the default base url of new clients, see SetBaseURL
 var urlBase *url.URL
 
-func init() {
-	log.SetFormatter(&log.TextFormatter{
-		FullTimestamp: true,
-	})
+// reported by Open rather than panicking during init
+var urlBaseErr error
 
-	var err error
-	urlBase, err = url.Parse(rootURL)
-	if err != nil {
-		log.Panic(err)
-	}
+func init() {
+	urlBase, urlBaseErr = url.Parse(rootURL)
+}
 
-	log.SetLevel(log.ErrorLevel)
+// Open returns a new irdata client, or an error if it couldn't be initialized
+func Open(ctx context.Context) (*Irdata, error) {
+	return OpenWithOptions(ctx)
 }
 
-func Open(ctx context.Context) *Irdata {
-	jar, err := cookiejar.New(nil)
+// OpenWithOptions is Open that also configures the client with opts, in
+// order, returning the error of the first one that fails
+func OpenWithOptions(ctx context.Context, opts ...Option) (*Irdata, error) {
+	if urlBaseErr != nil {
+		return nil, makeErrorf("unable to parse %s [%v]", rootURL, urlBaseErr)
+	}
+
+	jar, err := newSessionJar()
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 
 	client := http.Client{
-		Jar: jar,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
 	}
 
-	return &Irdata{
+	i := &Irdata{
 		httpClient: client,
+		jar:        jar,
 		isAuthed:   false,
 		cask:       nil,
+		baseURL:    urlBase,
+		loginURL:   loginURL,
+	}
+
+	for _, opt := range opts {
+		if err := opt(i); err != nil {
+			i.Close()
+
+			return nil, err
+		}
+	}
+
+	return i, nil
+}
+
+// SetBaseURL sets the url that /data uris are resolved against, e.g. to talk
+// to a test server.  It defaults to https://members-ng.iracing.com.
+func (i *Irdata) SetBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return makeErrorf("unable to parse %s [%v]", baseURL, err)
+	}
+
+	if !u.IsAbs() {
+		return makeErrorf("base url %s must be absolute", baseURL)
+	}
+
+	i.baseURL = u
+
+	return nil
+}
+
+// SetLoginURL sets the url credentials are posted to.  It defaults to
+// https://members-ng.iracing.com/auth.
+func (i *Irdata) SetLoginURL(loginURL string) error {
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		return makeErrorf("unable to parse %s [%v]", loginURL, err)
+	}
+
+	if !u.IsAbs() {
+		return makeErrorf("login url %s must be absolute", loginURL)
+	}
+
+	i.loginURL = loginURL
+
+	return nil
+}
+
+// SetTransport makes irdata send its requests through transport, e.g. one
+// set up for a corporate proxy or custom TLS, or an instrumented one.  nil
+// restores http.DefaultTransport.  While recording (see StartRecording) the
+// recorder wraps transport.
+func (i *Irdata) SetTransport(transport http.RoundTripper) {
+	if i.recorder != nil {
+		i.recorder.transport = transport
+		return
 	}
+
+	i.httpClient.Transport = transport
+}
+
+// SetHTTPClient makes irdata use client's transport and timeout.  irdata
+// keeps its own cookie jar (it holds the session) and doesn't follow
+// redirects, so client's Jar and CheckRedirect aren't used.
+func (i *Irdata) SetHTTPClient(client *http.Client) {
+	i.SetTransport(client.Transport)
+	i.httpClient.Timeout = client.Timeout
 }
 
 // Close
 // Calling Close when done is important when using caching - this will compact the cache.
 func (i *Irdata) Close() {
+	i.StopCacheRefresh()
+
 	if i.cask != nil {
 		i.cacheClose()
 	}
@@ -108,34 +302,40 @@ func (i *Irdata) Close() {
 
 // EnableCache enables on the optional caching layer which will
 // use the directory path provided as cacheDir
+//
+// The cache is not encrypted so it can't be enabled in strict security mode.
 func (i *Irdata) EnableCache(cacheDir string) error {
-	log.WithFields(log.Fields{"cacheDir": cacheDir}).Debug("Enabling cache")
+	if i.strictSecurity {
+		return makeErrorf("the cache is not encrypted and can't be used in strict security mode")
+	}
+
+	log.WithFields(logrus.Fields{"cacheDir": cacheDir}).Debug("Enabling cache")
 	return i.cacheOpen(cacheDir)
 }
 
 // EnableDebug enables debug logging which uses the logrus module
 func (i *Irdata) EnableDebug() {
-	log.SetLevel(log.DebugLevel)
+	log.SetLevel(logrus.DebugLevel)
 }
 
 // DisableDebug disables debug logging
 func (i *Irdata) DisableDebug() {
-	log.SetLevel(log.ErrorLevel)
+	log.SetLevel(logrus.ErrorLevel)
 }
 
 // SetLogLevel sets the loging level using the logrus module
 func (i *Irdata) SetLogLevel(logLevel LogLevel) {
 	switch logLevel {
 	case LogLevelFatal:
-		log.SetLevel(log.FatalLevel)
+		log.SetLevel(logrus.FatalLevel)
 	case LogLevelError:
-		log.SetLevel(log.ErrorLevel)
+		log.SetLevel(logrus.ErrorLevel)
 	case LogLevelInfo:
-		log.SetLevel(log.InfoLevel)
+		log.SetLevel(logrus.InfoLevel)
 	case LogLevelWarn:
-		log.SetLevel(log.WarnLevel)
+		log.SetLevel(logrus.WarnLevel)
 	case LogLevelDebug:
-		log.SetLevel(log.DebugLevel)
+		log.SetLevel(logrus.DebugLevel)
 	}
 }
 
@@ -143,73 +343,92 @@ func (i *Irdata) SetLogLevel(logLevel LogLevel) {
 //
 // The value returned is a JSON byte array and a potential error.
 //
-// Get will automatically retry 5 times if iRacing returns 500 errors
+// Get will automatically retry 5 times if iRacing returns 500 or 429 errors (see
+// SetRetryPolicy), returning a *StatusError if they keep coming.
+//
+// If some of the chunks of a chunked response can't be fetched, Get returns
+// the data it did retrieve along with a *PartialError listing the failures.
+//
+// If the session has expired Get authenticates again with the credentials it
+// was authed with and retries the request once.
+//
+// If the s3 link the API returned has expired by the time it's followed, Get
+// asks the API for a new one.
+//
+// Concurrent Gets of the same uri share a single request.
+//
+// If the cache is enabled and uri matches a policy set with SetCachePolicy,
+// Get is GetWithCache with the policy's ttl.
 func (i *Irdata) Get(uri string) ([]byte, error) {
-	if !i.isAuthed {
-		return nil, makeErrorf("must auth first")
+	return i.GetCtx(context.Background(), uri)
+}
+
+// GetCtx is Get with a context: requests are made with ctx and once it's
+// done no further requests (or retries) are made and its error is returned.
+func (i *Irdata) GetCtx(ctx context.Context, uri string) ([]byte, error) {
+	if ttl, ok := i.cachePolicyFor(uri); ok && i.cask != nil {
+		return i.GetWithCacheCtx(ctx, uri, ttl)
 	}
 
-	uriRef, err := url.Parse(uri)
-	if err != nil {
-		return nil, err
+	data, err := i.getUncachedCtx(ctx, uri)
+
+	// not found is only of interest to the cache
+	if errors.Is(err, errNotFound) {
+		err = nil
 	}
 
-	url := urlBase.ResolveReference(uriRef)
+	return data, err
+}
 
-	log.WithFields(log.Fields{"url": url}).Debug("Fetching")
+func (i *Irdata) getUncachedCtx(ctx context.Context, uri string) ([]byte, error) {
+	ctx, span := i.startSpan(ctx, TraceGet, map[string]any{"uri": uri})
 
-	resp, err := i.retryingGet(url.String())
-	if err != nil {
-		return nil, err
-	}
+	data, shared, err := i.sharedGet(ctx, uri, func(ctx context.Context) ([]byte, error) {
+		return i.getCtx(i.withResponseBudget(ctx), uri)
+	})
 
-	defer resp.Body.Close()
+	span.SetAttribute("shared", shared)
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if errors.Is(err, errNotFound) {
+		span.End(nil)
+	} else {
+		span.End(err)
 	}
 
-	var s3Link s3LinkT
+	return data, err
+}
 
-	log.WithFields(log.Fields{"url": url}).Debug("Unmarshalling")
+func (i *Irdata) getCtx(ctx context.Context, uri string) ([]byte, error) {
+	if err := i.ensureAuthed(ctx); err != nil {
+		return nil, err
+	}
 
-	err = json.Unmarshal(data, &s3Link)
+	uriRef, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
 
-	// there's a link
-	if err == nil && s3Link.Link != "" {
-		log.WithFields(log.Fields{"s3Link.Link": s3Link.Link}).Debug("Following s3link")
+	url := i.baseURL.ResolveReference(uriRef)
 
-		s3Resp, err := i.retryingGet(s3Link.Link)
-		if err != nil {
-			return nil, err
-		}
+	var data []byte
 
-		defer s3Resp.Body.Close()
+	// s3 links expire quickly, if we were too slow following one get another
+	for attempt := 1; ; attempt++ {
+		data, err = i.getLinked(ctx, url.String())
 
-		data, err = io.ReadAll(s3Resp.Body)
-		if err != nil {
-			return nil, err
+		if !errors.Is(err, ErrLinkExpired) || attempt >= _maxLinkRefetches {
+			break
 		}
-	} else {
-		// there's no link, check for data url
-		var dataUrl dataUrlT
 
-		err = json.Unmarshal(data, &dataUrl)
-
-		if err == nil && dataUrl.Data_Url != "" {
-			log.WithFields(log.Fields{"dataUrl.Data_Url": dataUrl.Data_Url}).Debug("Following dataUrl")
+		log.WithFields(logrus.Fields{"url": url, "attempt": attempt}).Warn("Link expired, fetching a new one")
+	}
 
-			dataUrlResp, err := i.retryingGet(dataUrl.Data_Url)
-			if err != nil {
-				return nil, err
-			}
+	if errors.Is(err, errNotFound) {
+		return data, err
+	}
 
-			data, err = io.ReadAll(dataUrlResp.Body)
-			if err != nil {
-				return nil, err
-			}
-		}
+	if err != nil {
+		return nil, err
 	}
 
 	// quick check for chunk info
@@ -222,64 +441,259 @@ func (i *Irdata) Get(uri string) ([]byte, error) {
 		}
 
 		// walk the object looking for chunks
-		err = i.resolveChunks(raw)
-		if err != nil {
-			return nil, err
+		chunkErr := i.resolveChunks(ctx, raw)
+
+		var partialErr *PartialError
+
+		if chunkErr != nil && !errors.As(chunkErr, &partialErr) {
+			return nil, chunkErr
 		}
 
 		data, err = json.Marshal(raw)
 		if err != nil {
 			return nil, err
 		}
+
+		if partialErr != nil {
+			return data, partialErr
+		}
+	}
+
+	return data, nil
+}
+
+// getLinked gets url from the /data API and, if the response is an s3 link
+// or a data url, follows it.  It returns ErrLinkExpired when the link was
+// refused.
+func (i *Irdata) getLinked(ctx context.Context, url string) ([]byte, error) {
+	log.WithFields(logrus.Fields{"url": url}).Debug("Fetching")
+
+	resp, err := i.authedGet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &StatusError{URL: redactString(url), StatusCode: resp.StatusCode}
+	}
+
+	data, err := readBody(ctx, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return data, errNotFound
+	}
+
+	if link := linkIn(data); link != "" {
+		return i.followLink(ctx, link)
 	}
 
 	return data, nil
 }
 
-func (i *Irdata) resolveChunks(raw map[string]interface{}) error {
+// linkIn returns the s3 link or data url in an API response, if it is one
+func linkIn(data []byte) string {
+	var s3Link s3LinkT
+
+	log.Debug("Unmarshalling")
+
+	err := json.Unmarshal(data, &s3Link)
+
+	// there's a link
+	if err == nil && s3Link.Link != "" {
+		log.WithFields(logrus.Fields{"s3Link.Link": s3Link.Link}).Debug("Following s3link")
+
+		return s3Link.Link
+	}
+
+	// there's no link, check for data url
+	var dataUrl dataUrlT
+
+	err = json.Unmarshal(data, &dataUrl)
+
+	if err == nil && dataUrl.Data_Url != "" {
+		log.WithFields(logrus.Fields{"dataUrl.Data_Url": dataUrl.Data_Url}).Debug("Following dataUrl")
+
+		return dataUrl.Data_Url
+	}
+
+	return ""
+}
+
+// followLink downloads the s3 link or data url link
+func (i *Irdata) followLink(ctx context.Context, link string) ([]byte, error) {
+	resp, err := i.retryingDownload(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	// a presigned link that has expired is refused with an AccessDenied
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrLinkExpired
+	}
+
+	return readBody(ctx, resp.Body)
+}
+
+// ensureAuthed fails unless the client has been authed.  If renewing the
+// session failed earlier it tries again with the creds it was authed with
+// rather than leave the client unusable until the caller auths again.
+func (i *Irdata) ensureAuthed(ctx context.Context) error {
+	i.authMu.Lock()
+	isAuthed, authData := i.isAuthed, i.authData
+	i.authMu.Unlock()
+
+	if isAuthed {
+		return nil
+	}
+
+	if authData == nil {
+		return makeErrorf("must auth first")
+	}
+
+	log.Info("Session wasn't renewed, authenticating again")
+
+	return i.renew(ctx)
+}
+
+// authedGet gets url from the /data API, renewing the session if it has
+// expired.  When several goroutines find the session expired at once only
+// one of them logs in again, the rest wait for it.
+func (i *Irdata) authedGet(ctx context.Context, url string) (*http.Response, error) {
+	i.authMu.Lock()
+	session := i.session
+	i.authMu.Unlock()
+
+	resp, err := i.retryingGet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	drainAndClose(resp)
+
+	i.authMu.Lock()
+
+	authData := i.authData
+
+	// someone else may have renewed the session already
+	if authData != nil && i.session == session {
+		i.isAuthed = false
+	}
+
+	i.authMu.Unlock()
+
+	if authData == nil {
+		return nil, ErrUnauthorized
+	}
+
+	log.WithFields(logrus.Fields{"url": url}).Info("Session expired, authenticating again")
+
+	if err := i.renew(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err = i.retryingGet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		drainAndClose(resp)
+
+		return nil, ErrUnauthorized
+	}
+
+	return resp, nil
+}
+
+// resolveChunks walks raw looking for chunk_info blocks and merges the rows
+// of their chunks into ChunkDataKey.  Chunks that can't be fetched don't stop
+// the walk, instead they are collected and returned in a *PartialError.
+func (i *Irdata) resolveChunks(ctx context.Context, raw map[string]interface{}) error {
+	var failures []error
+
 	for k, v := range raw {
 		if k == "chunk_info" {
-			log.WithFields(log.Fields{
+			log.WithFields(logrus.Fields{
 				"chunk_info": v,
 			}).Debug("Chunked data found")
 
 			var results []interface{}
 
+			failuresBefore := len(failures)
+
 			if v != nil {
-				chunkInfo := v.(map[string]interface{})
+				chunkInfo, ok := v.(map[string]interface{})
+				if !ok {
+					return makeErrorf("unexpected chunk_info %v", v)
+				}
+
+				chunkFileNames, ok := chunkInfo["chunk_file_names"].([]interface{})
+				if !ok {
+					return makeErrorf("unexpected chunk_file_names %v", chunkInfo["chunk_file_names"])
+				}
 
-				for chunkNumber, chunkFileName := range chunkInfo["chunk_file_names"].([]interface{}) {
+				chunksCtx, span := i.startSpan(ctx, TraceChunks, map[string]any{"chunk_count": len(chunkFileNames)})
+
+				for chunkNumber, chunkFileName := range chunkFileNames {
 					chunkUrl := fmt.Sprintf("%s%s", chunkInfo["base_download_url"], chunkFileName)
 
-					log.WithFields(log.Fields{
+					log.WithFields(logrus.Fields{
 						"chunkNumber": chunkNumber,
 						"chunkUrl":    chunkUrl,
 					}).Debug("Fetching chunk")
 
-					chunkResp, err := i.retryingGet(chunkUrl)
-					if err != nil {
+					r, err := i.fetchChunk(chunksCtx, chunkNumber, chunkUrl)
+
+					// no point fetching the rest
+					if errors.Is(err, ErrResponseTooLarge) {
+						span.End(err)
+
 						return err
 					}
 
-					chunkData, err := io.ReadAll(chunkResp.Body)
 					if err != nil {
-						return err
-					}
+						log.WithFields(logrus.Fields{
+							"chunkNumber": chunkNumber,
+							"err":         err,
+						}).Warn("Unable to fetch chunk")
 
-					var r []interface{}
+						failures = append(failures, err)
 
-					err = json.Unmarshal(chunkData, &r)
-					if err != nil {
-						return err
+						continue
 					}
 
-					log.WithFields(log.Fields{
-						"len(chunkData)": len(chunkData),
-						"len(r)":         len(r),
-					}).Debug("Got chunk bytes")
-
 					results = append(results, r...)
 				}
+
+				span.SetAttribute("chunk_failures", len(failures)-failuresBefore)
+
+				// chunk_info advertises how many rows we should have ended up with
+				rows, ok := chunkInfo["rows"].(float64)
+				if ok && len(failures) == failuresBefore && int(rows) != len(results) {
+					log.WithFields(logrus.Fields{
+						"rows":         rows,
+						"len(results)": len(results),
+					}).Warn("Chunk row count mismatch")
+
+					rowCountErr := &ChunkRowCountError{Expected: int(rows), Actual: len(results)}
+
+					span.End(rowCountErr)
+
+					return rowCountErr
+				}
+
+				span.End(nil)
 			}
 
 			// insert the results in the special ChunkDataKey key
@@ -288,15 +702,70 @@ func (i *Irdata) resolveChunks(raw map[string]interface{}) error {
 			// recurse deeper into objects
 			o, ok := v.(map[string]interface{})
 			if ok {
-				i.resolveChunks(o)
+				err := i.resolveChunks(ctx, o)
+
+				var partialErr *PartialError
+
+				if errors.As(err, &partialErr) {
+					failures = append(failures, partialErr.Errors...)
+				} else if err != nil {
+					return err
+				}
 			}
 			// TODO: Do we need to walk arrays?  could an array have chunks?
 		}
 	}
 
+	if len(failures) > 0 {
+		return &PartialError{Errors: failures}
+	}
+
 	return nil
 }
 
+// fetchChunk fetches a single chunk file and returns the rows it contains
+func (i *Irdata) fetchChunk(ctx context.Context, chunkNumber int, chunkUrl string) ([]interface{}, error) {
+	chunkResp, err := i.retryingDownload(ctx, chunkUrl)
+	if err != nil {
+		return nil, &ChunkError{Index: chunkNumber, URL: chunkUrl, Err: err}
+	}
+
+	defer chunkResp.Body.Close()
+
+	if chunkResp.StatusCode != http.StatusOK {
+		log.WithFields(logrus.Fields{
+			"chunkNumber":          chunkNumber,
+			"chunkUrl":             chunkUrl,
+			"chunkResp.StatusCode": chunkResp.StatusCode,
+		}).Warn("Unexpected status fetching chunk")
+
+		return nil, &ChunkError{Index: chunkNumber, URL: chunkUrl, StatusCode: chunkResp.StatusCode}
+	}
+
+	chunkData, err := readBody(ctx, chunkResp.Body)
+	if err != nil {
+		return nil, &ChunkError{Index: chunkNumber, URL: chunkUrl, StatusCode: chunkResp.StatusCode, Err: err}
+	}
+
+	if i.metrics != nil {
+		i.metrics.AddChunkBytes(len(chunkData))
+	}
+
+	var r []interface{}
+
+	err = json.Unmarshal(chunkData, &r)
+	if err != nil {
+		return nil, &ChunkError{Index: chunkNumber, URL: chunkUrl, StatusCode: chunkResp.StatusCode, Err: err}
+	}
+
+	log.WithFields(logrus.Fields{
+		"len(chunkData)": len(chunkData),
+		"len(r)":         len(r),
+	}).Debug("Got chunk bytes")
+
+	return r, nil
+}
+
 // GetWithCache will first check the local cache for an unexpired result
 // and will the call Get with the uri provided.
 //
@@ -306,41 +775,91 @@ func (i *Irdata) resolveChunks(raw map[string]interface{}) error {
 // NOTE: If data is fetched this will return the data even
 // if it can't be written to the cache (along with an error)
 func (i *Irdata) GetWithCache(uri string, ttl time.Duration) ([]byte, error) {
+	return i.GetWithCacheCtx(context.Background(), uri, ttl)
+}
+
+// GetWithCacheCtx is GetWithCache fetching with GetCtx
+func (i *Irdata) GetWithCacheCtx(ctx context.Context, uri string, ttl time.Duration) ([]byte, error) {
+	ctx, span := i.startSpan(ctx, TraceGetWithCache, map[string]any{"uri": uri})
+
+	data, err := i.getWithCacheCtx(ctx, span, uri, ttl)
+
+	span.End(err)
+
+	return data, err
+}
+
+func (i *Irdata) getWithCacheCtx(ctx context.Context, span Span, uri string, ttl time.Duration) ([]byte, error) {
 	if i.cask == nil {
 		return nil, makeErrorf("cache must be enabled")
 	}
 
-	log.WithFields(log.Fields{"uri": uri}).Debug("Checking for cached data")
+	log.WithFields(logrus.Fields{"uri": uri}).Debug("Checking for cached data")
+
+	key := i.cacheKey(uri)
 
-	data, err := i.getCachedData(uri)
+	entry, found, err := i.getCacheEntry(key)
 	if err != nil {
-		log.WithFields(log.Fields{
+		log.WithFields(logrus.Fields{
 			"err": err,
 			"uri": uri,
 		}).Error("Unable to get cached data")
 		return nil, err
 	}
 
-	if data != nil {
-		log.WithFields(log.Fields{"uri": uri}).Debug("Cached data found")
-		return data, nil
+	hit := found && !entry.stale(time.Now())
+
+	span.SetAttribute("cache.hit", hit)
+
+	if i.metrics != nil {
+		i.metrics.ObserveCacheLookup(hit)
+	}
+
+	if hit {
+		log.WithFields(logrus.Fields{"uri": uri}).Debug("Cached data found")
+
+		i.trackCacheUse(uri, ttl, entry.expires, time.Now())
+
+		return entry.data, nil
 	}
 
-	log.WithFields(log.Fields{"uri": uri}).Debug("Nothing in cache")
+	log.WithFields(logrus.Fields{"uri": uri}).Debug("Nothing in cache")
+
+	data, err := i.getUncachedCtx(ctx, uri)
+
+	notFound := errors.Is(err, errNotFound)
+	if notFound {
+		err = nil
+	}
+
+	// better late than never
+	if err != nil && found && isUpstreamFailure(err) {
+		log.WithFields(logrus.Fields{
+			"uri":     uri,
+			"expired": entry.expires,
+			"err":     err,
+		}).Warn("Unable to refresh, serving stale cached data")
+
+		span.SetAttribute("cache.stale", true)
+
+		return entry.data, &StaleDataError{Expired: entry.expires, Err: err}
+	}
 
-	data, err = i.Get(uri)
 	if err != nil {
-		return nil, err
+		// don't cache partial results
+		return data, err
 	}
 
-	log.WithFields(log.Fields{
-		"ttl": ttl,
+	cachedTTL := i.negativeCacheTTLFor(data, notFound, ttl)
+
+	log.WithFields(logrus.Fields{
+		"ttl": cachedTTL,
 		"uri": uri,
 	}).Debug("Got data, writing to cache")
 
-	err = i.setCachedData(uri, data, ttl)
+	err = i.setCachedData(key, data, cachedTTL)
 	if err != nil {
-		log.WithFields(log.Fields{
+		log.WithFields(logrus.Fields{
 			"uri":       uri,
 			"err":       err,
 			"len(data)": len(data),
@@ -349,36 +868,9 @@ func (i *Irdata) GetWithCache(uri string, ttl time.Duration) ([]byte, error) {
 		return data, err
 	}
 
-	return data, nil
-}
-
-func (i *Irdata) retryingGet(url string) (resp *http.Response, err error) {
-	retries := 5
-
-	for retries > 0 {
-		log.WithFields(log.Fields{
-			"url":     url,
-			"retries": retries,
-		}).Info("httpClient.Get")
+	now := time.Now()
 
-		resp, err = i.httpClient.Get(url)
+	i.trackCacheUse(uri, ttl, now.Add(cachedTTL), now)
 
-		if resp.StatusCode < 500 {
-			break
-		}
-
-		retries--
-
-		backoff := time.Duration((6-retries)*5) * time.Second
-
-		log.WithFields(log.Fields{
-			"url":             url,
-			"resp.StatusCode": resp.StatusCode,
-			"backoff":         backoff,
-		}).Warn("*** Retrying")
-
-		time.Sleep(backoff)
-	}
-
-	return resp, err
+	return data, nil
 }
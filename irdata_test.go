@@ -3,15 +3,30 @@ package irdata
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
-var i *Irdata = Open(context.Background())
+var i *Irdata = mustOpen()
+
+func mustOpen() *Irdata {
+	i, err := Open(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	return i
+}
 
 var authed bool = false
 
@@ -37,6 +52,22 @@ func auth() bool {
 	return false
 }
 
+// fakeAPI returns a client that is already authed and talks to handler
+// instead of iRacing
+func fakeAPI(t *testing.T, handler http.Handler) *Irdata {
+	ts := httptest.NewServer(handler)
+
+	t.Cleanup(ts.Close)
+
+	fake := mustOpen()
+	fake.isAuthed = true
+
+	assert.NoError(t, fake.SetBaseURL(ts.URL))
+	assert.NoError(t, fake.SetLoginURL(ts.URL+"/auth"))
+
+	return fake
+}
+
 func getJsonObject(t *testing.T, data []byte) map[string]interface{} {
 	var jsonData map[string]interface{}
 
@@ -59,7 +90,7 @@ func TestResolveChunksEmpty(t *testing.T) {
 
 	raw["chunk_info"] = nil
 
-	assert.NoError(t, i.resolveChunks(raw))
+	assert.NoError(t, i.resolveChunks(context.Background(), raw))
 
 	v, ok := raw[ChunkDataKey]
 
@@ -67,6 +98,96 @@ func TestResolveChunksEmpty(t *testing.T) {
 	assert.Nil(t, v)
 }
 
+// test resolveChunks merges chunk rows
+func TestResolveChunks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"chunk":"%s"}]`, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	raw := map[string]interface{}{
+		"chunk_info": map[string]interface{}{
+			"base_download_url": ts.URL + "/",
+			"chunk_file_names":  []interface{}{"a", "b"},
+		},
+	}
+
+	assert.NoError(t, i.resolveChunks(context.Background(), raw))
+
+	rows := raw[ChunkDataKey].([]interface{})
+
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "/a", rows[0].(map[string]interface{})["chunk"])
+	assert.Equal(t, "/b", rows[1].(map[string]interface{})["chunk"])
+}
+
+// test resolveChunks surfaces chunk failures
+func TestResolveChunksBadStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/b" {
+			http.Error(w, "<Error>AccessDenied</Error>", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `[{}]`)
+	}))
+	defer ts.Close()
+
+	raw := map[string]interface{}{
+		"data": map[string]interface{}{
+			"chunk_info": map[string]interface{}{
+				"base_download_url": ts.URL + "/",
+				"chunk_file_names":  []interface{}{"a", "b"},
+			},
+		},
+	}
+
+	err := i.resolveChunks(context.Background(), raw)
+
+	var partialErr *PartialError
+
+	assert.True(t, errors.As(err, &partialErr))
+	assert.Len(t, partialErr.Errors, 1)
+
+	var chunkErr *ChunkError
+
+	assert.True(t, errors.As(err, &chunkErr))
+	assert.Equal(t, 1, chunkErr.Index)
+	assert.Equal(t, http.StatusForbidden, chunkErr.StatusCode)
+
+	// the chunk that was fetched is still delivered
+	rows := raw["data"].(map[string]interface{})[ChunkDataKey].([]interface{})
+
+	assert.Len(t, rows, 1)
+}
+
+// test resolveChunks verifies the advertised row count
+func TestResolveChunksRowCount(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{},{}]`)
+	}))
+	defer ts.Close()
+
+	raw := map[string]interface{}{
+		"chunk_info": map[string]interface{}{
+			"base_download_url": ts.URL + "/",
+			"chunk_file_names":  []interface{}{"a", "b"},
+			"rows":              float64(4),
+		},
+	}
+
+	assert.NoError(t, i.resolveChunks(context.Background(), raw))
+
+	raw["chunk_info"].(map[string]interface{})["rows"] = float64(5)
+
+	err := i.resolveChunks(context.Background(), raw)
+
+	var rowCountErr *ChunkRowCountError
+
+	assert.True(t, errors.As(err, &rowCountErr))
+	assert.Equal(t, 5, rowCountErr.Expected)
+	assert.Equal(t, 4, rowCountErr.Actual)
+}
+
 // event_types returns json directly
 func TestGetBasic(t *testing.T) {
 	if auth() {
@@ -148,3 +269,295 @@ func TestDataUrl(t *testing.T) {
 		assert.NotNil(t, o["roster"])
 	}
 }
+
+func TestGetReauthsOnUnauthorized(t *testing.T) {
+	setupRetryTest(t)
+
+	// the session has expired until the client logs in again
+	loggedIn := false
+	logins := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			logins++
+			loggedIn = true
+			fmt.Fprint(w, `{}`)
+		default:
+			if !loggedIn {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			fmt.Fprint(w, `{"ok":true}`)
+		}
+	}))
+
+	// no creds to renew the session with
+	_, err := fake.Get("/data/member/info")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	fake.keepAuthData(authDataT{Username: "user"}, fake.passwordSecret("encoded"))
+
+	data, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+	assert.Equal(t, 1, logins)
+	assert.True(t, fake.isAuthed)
+}
+
+func TestGetReauthsOnce(t *testing.T) {
+	setupRetryTest(t)
+
+	var mu sync.Mutex
+
+	loggedIn := false
+	logins := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/auth":
+			logins++
+			loggedIn = true
+			fmt.Fprint(w, `{}`)
+		default:
+			if !loggedIn {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			fmt.Fprint(w, `{"ok":true}`)
+		}
+	}))
+
+	fake.keepAuthData(authDataT{Username: "user"}, fake.passwordSecret("encoded"))
+
+	var wg sync.WaitGroup
+
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := fake.Get("/data/member/info")
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 1, logins)
+}
+
+func TestGetRefetchesExpiredLink(t *testing.T) {
+	var linkBase string
+
+	links := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/member/info":
+			links++
+			fmt.Fprintf(w, `{"link":"%s/s3/%d"}`, linkBase, links)
+		case "/s3/1":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<Error><Code>AccessDenied</Code><Message>Request has expired</Message></Error>`)
+		default:
+			fmt.Fprint(w, `{"ok":true}`)
+		}
+	}))
+
+	linkBase = fake.baseURL.String()
+
+	data, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+	assert.Equal(t, 2, links)
+}
+
+func TestGetLinkAlwaysExpired(t *testing.T) {
+	var linkBase string
+
+	links := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/data/member/info" {
+			links++
+			fmt.Fprintf(w, `{"link":"%s/s3/info"}`, linkBase)
+			return
+		}
+
+		w.WriteHeader(http.StatusForbidden)
+	}))
+
+	linkBase = fake.baseURL.String()
+
+	_, err := fake.Get("/data/member/info")
+
+	assert.ErrorIs(t, err, ErrLinkExpired)
+	assert.Equal(t, _maxLinkRefetches, links)
+}
+
+func TestSetBaseURL(t *testing.T) {
+	client := mustOpen()
+
+	assert.Error(t, client.SetBaseURL("/relative"))
+	assert.Error(t, client.SetLoginURL("::"))
+	assert.Equal(t, rootURL, client.baseURL.String())
+	assert.Equal(t, loginURL, client.loginURL)
+
+	// other clients keep the defaults
+	fake := fakeAPI(t, http.NotFoundHandler())
+
+	assert.NotEqual(t, rootURL, fake.baseURL.String())
+	assert.Equal(t, rootURL, client.baseURL.String())
+}
+
+// countingTransport counts the requests it sends
+type countingTransport struct {
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests++
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestSetTransport(t *testing.T) {
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+
+	transport := &countingTransport{}
+
+	fake.SetHTTPClient(&http.Client{Transport: transport, Timeout: time.Minute})
+
+	_, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, transport.requests)
+	assert.Equal(t, time.Minute, fake.httpClient.Timeout)
+
+	// the session is still kept in irdata's own jar
+	assert.NotNil(t, fake.jar)
+
+	// recording wraps the transport
+	fake.StartRecording(filepath.Join(t.TempDir(), "cassette.json"))
+	fake.SetTransport(transport)
+
+	_, err = fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, transport.requests)
+	assert.NoError(t, fake.StopRecording())
+	assert.Equal(t, transport, fake.httpClient.Transport)
+}
+
+func TestGetRetriesFailedRenewal(t *testing.T) {
+	setupRetryTest(t)
+
+	loggedIn := false
+	logins := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			logins++
+
+			// the first renewal fails
+			if logins == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			loggedIn = true
+			fmt.Fprint(w, `{}`)
+		default:
+			if !loggedIn {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			fmt.Fprint(w, `{"ok":true}`)
+		}
+	}))
+
+	fake.SetAuthRetryPolicy(1, nil)
+	fake.keepAuthData(authDataT{Username: "user"}, fake.passwordSecret("encoded"))
+
+	_, err := fake.Get("/data/member/info")
+	assert.Error(t, err)
+	assert.False(t, fake.isAuthed)
+
+	// the next Get renews the session rather than fail with "must auth first"
+	data, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+	assert.Equal(t, 2, logins)
+	assert.True(t, fake.isAuthed)
+}
+
+func TestGetDeadlineWhileRenewing(t *testing.T) {
+	var loggedIn, logins int32
+
+	release := make(chan struct{})
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			atomic.AddInt32(&logins, 1)
+			<-release
+			atomic.StoreInt32(&loggedIn, 1)
+			fmt.Fprint(w, `{}`)
+		default:
+			if atomic.LoadInt32(&loggedIn) == 0 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			fmt.Fprint(w, `{"ok":true}`)
+		}
+	}))
+
+	fake.keepAuthData(authDataT{Username: "user"}, fake.passwordSecret("encoded"))
+
+	errs := make(chan error)
+
+	// one starts the renewal and the other joins it, neither waits past its
+	// deadline for the login
+	for n := 0; n < 2; n++ {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			_, err := fake.GetCtx(ctx, "/data/member/info")
+			errs <- err
+		}()
+	}
+
+	for n := 0; n < 2; n++ {
+		select {
+		case err := <-errs:
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		case <-time.After(5 * time.Second):
+			t.Fatal("GetCtx waited past its deadline for the login")
+		}
+	}
+
+	// the shared login carries on and the next Get uses it
+	close(release)
+
+	data, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logins))
+}
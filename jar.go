@@ -0,0 +1,105 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sessionJarT holds the session cookies.  irdata adds and keeps them itself
+// (see do) rather than through http.Client's Jar so that the cookies set in
+// response to a request sent before a Logout can be dropped.
+type sessionJarT struct {
+	mu  sync.RWMutex
+	jar *cookiejar.Jar
+}
+
+func newSessionJar() (*sessionJarT, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, makeErrorf("unable to create cookie jar [%v]", err)
+	}
+
+	return &sessionJarT{jar: jar}, nil
+}
+
+func (j *sessionJarT) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	j.jar.SetCookies(u, cookies)
+}
+
+func (j *sessionJarT) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.jar.Cookies(u)
+}
+
+// reset drops every cookie
+func (j *sessionJarT) reset() error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return makeErrorf("unable to create cookie jar [%v]", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.jar = jar
+
+	return nil
+}
+
+type sessionKeyT struct{}
+
+// withSession makes the requests sent with ctx belong to session rather than
+// to the session current when each is sent
+func withSession(ctx context.Context, session uint64) context.Context {
+	return context.WithValue(ctx, sessionKeyT{}, session)
+}
+
+// requestSession returns the session generation req is sent under
+func (i *Irdata) requestSession(req *http.Request) uint64 {
+	if session, ok := req.Context().Value(sessionKeyT{}).(uint64); ok {
+		return session
+	}
+
+	i.authMu.Lock()
+	defer i.authMu.Unlock()
+
+	return i.session
+}
+
+// addCookies adds the session cookies for req's url to req
+func (i *Irdata) addCookies(req *http.Request) {
+	for _, cookie := range i.jar.Cookies(req.URL) {
+		req.AddCookie(cookie)
+	}
+}
+
+// keepCookies keeps the cookies set by resp, the response to a request for u
+// sent under session.  If the client has logged out (or in again) since, they
+// belong to a session that's gone and are dropped.
+func (i *Irdata) keepCookies(session uint64, u *url.URL, resp *http.Response) {
+	cookies := resp.Cookies()
+	if len(cookies) == 0 {
+		return
+	}
+
+	i.authMu.Lock()
+	defer i.authMu.Unlock()
+
+	if i.session != session {
+		log.WithFields(logrus.Fields{"url": redactString(u.String())}).Debug("Dropping cookies from an old session")
+
+		return
+	}
+
+	i.jar.SetCookies(u, cookies)
+}
@@ -0,0 +1,117 @@
+package irdata
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// the OS credential store, replaced in tests
+var keychainGet = osKeychainGet
+var keychainSet = osKeychainSet
+
+// CredsFromKeychain is a CredsProvider that reads the password for Username
+// from the OS credential store (the macOS Keychain, Windows Credential
+// Manager, or the Secret Service on Linux), so no key or creds files are
+// needed.  Save the creds with AuthAndSaveProvidedCredsToKeychain.
+type CredsFromKeychain struct {
+	Service  string
+	Username string
+}
+
+func (k CredsFromKeychain) GetCreds() ([]byte, []byte, error) {
+	password, err := keychainGet(k.Service, k.Username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []byte(k.Username), password, nil
+}
+
+// KeyFromKeychain is a KeyProvider that reads a base64 encoded key stored
+// under Service and Account in the OS credential store (the macOS Keychain,
+// Windows Credential Manager, or the Secret Service on Linux), so the key
+// never sits in a plain file on disk.  On macOS a key can be created with:
+//
+//	security add-generic-password -s irdata -a $USER -w "$(openssl rand -base64 32)"
+//
+// and on Linux with:
+//
+//	openssl rand -base64 32 | secret-tool store --label=irdata service irdata username $USER
+type KeyFromKeychain struct {
+	Service string
+	Account string
+}
+
+func (k KeyFromKeychain) GetKey() ([]byte, error) {
+	out, err := keychainGet(k.Service, k.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	defer shred(&out)
+
+	key, err := base64.StdEncoding.Strict().DecodeString(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, makeErrorf("unable to base64 decode key %s/%s from keychain [%v]", k.Service, k.Account, err)
+	}
+
+	return key, nil
+}
+
+// AuthAndSaveProvidedCredsToKeychain calls the provided function for the
+// username and password, verifies auth, and then saves the password in the
+// OS credential store under service and the username.  Use
+// CredsFromKeychain to auth with them later.  Masked passwords (see
+// MaskedCreds) can't be saved this way as CredsFromKeychain's are masked
+// when used.
+func (i *Irdata) AuthAndSaveProvidedCredsToKeychain(service string, authSource CredsProvider) error {
+	log.WithFields(logrus.Fields{"authSource": fmt.Sprintf("%T", authSource)}).Debug("Calling CredsProvider")
+
+	keeper := &keepingCredsProvider{provider: authSource}
+
+	defer shred(&keeper.password)
+
+	authData, err := i.authDataFromProvider(keeper)
+	if err != nil {
+		return err
+	}
+
+	err = i.auth(authData)
+	if err != nil {
+		return err
+	}
+
+	return keychainSet(service, authData.Username, keeper.password)
+}
+
+// keepingCredsProvider keeps a copy of the password provider returns, which
+// authDataFromProvider may shred, so it can be saved once auth is verified
+type keepingCredsProvider struct {
+	provider CredsProvider
+	password []byte
+}
+
+func (k *keepingCredsProvider) GetCreds() ([]byte, []byte, error) {
+	username, password, _, err := k.getMaskedCreds()
+
+	return username, password, err
+}
+
+func (k *keepingCredsProvider) getMaskedCreds() ([]byte, []byte, bool, error) {
+	username, password, masked, err := getCreds(k.provider)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	if masked {
+		shred(&password)
+		return nil, nil, false, makeErrorf("unable to save a masked password from %T to the keychain", k.provider)
+	}
+
+	k.password = append([]byte{}, password...)
+
+	return username, password, false, nil
+}
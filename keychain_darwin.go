@@ -0,0 +1,40 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func osKeychainGet(service string, account string) ([]byte, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		return nil, makeErrorf("unable to read %s/%s from keychain [%v]", service, account, err)
+	}
+
+	return bytes.TrimRight(out, "\n"), nil
+}
+
+func osKeychainSet(service string, account string, password []byte) error {
+	if strings.ContainsAny(service+account, "\"\n") {
+		return makeErrorf("keychain service and account can't contain quotes or newlines")
+	}
+
+	// pass the password on stdin (as hex so it needs no quoting) to keep it
+	// out of the process list
+	command := []byte(fmt.Sprintf("add-generic-password -U -s \"%s\" -a \"%s\" -X %s\n", service, account, hex.EncodeToString(password)))
+
+	defer shred(&command)
+
+	cmd := exec.Command("security", "-i")
+
+	cmd.Stdin = bytes.NewReader(command)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return makeErrorf("unable to save %s/%s to keychain [%v] %s", service, account, err, out)
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package irdata
+
+import (
+	"bytes"
+	"os/exec"
+)
+
+// uses secret-tool (libsecret) to talk to the Secret Service
+
+func osKeychainGet(service string, account string) ([]byte, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "username", account).Output()
+	if err != nil {
+		return nil, makeErrorf("unable to read %s/%s from the secret service [%v]", service, account, err)
+	}
+
+	if len(out) == 0 {
+		return nil, makeErrorf("no secret for %s/%s in the secret service", service, account)
+	}
+
+	return bytes.TrimRight(out, "\n"), nil
+}
+
+func osKeychainSet(service string, account string, password []byte) error {
+	cmd := exec.Command("secret-tool", "store", "--label=irdata "+service, "service", service, "username", account)
+
+	// the password is read from stdin so it stays out of the process list
+	cmd.Stdin = bytes.NewReader(password)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return makeErrorf("unable to save %s/%s to the secret service [%v] %s", service, account, err, out)
+	}
+
+	return nil
+}
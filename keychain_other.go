@@ -0,0 +1,13 @@
+//go:build !darwin && !linux && !windows
+
+package irdata
+
+import "runtime"
+
+func osKeychainGet(service string, account string) ([]byte, error) {
+	return nil, makeErrorf("no OS credential store support on %s", runtime.GOOS)
+}
+
+func osKeychainSet(service string, account string, password []byte) error {
+	return makeErrorf("no OS credential store support on %s", runtime.GOOS)
+}
@@ -0,0 +1,91 @@
+package irdata
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCredsProvider struct{}
+
+func (testCredsProvider) GetCreds() ([]byte, []byte, error) {
+	return []byte("user@example.com"), []byte("hunter2"), nil
+}
+
+func TestKeychain(t *testing.T) {
+	store := map[string]string{}
+
+	savedGet, savedSet := keychainGet, keychainSet
+
+	keychainGet = func(service string, account string) ([]byte, error) {
+		password, ok := store[service+"/"+account]
+		if !ok {
+			return nil, makeErrorf("not found")
+		}
+
+		return []byte(password), nil
+	}
+
+	keychainSet = func(service string, account string, password []byte) error {
+		store[service+"/"+account] = string(password)
+		return nil
+	}
+
+	t.Cleanup(func() {
+		keychainGet, keychainSet = savedGet, savedSet
+	})
+
+	_, _, err := CredsFromKeychain{Service: "irdata", Username: "user@example.com"}.GetCreds()
+	assert.Error(t, err)
+
+	fake := fakeAPI(t, http.NotFoundHandler())
+
+	assert.NoError(t, fake.AuthAndSaveProvidedCredsToKeychain("irdata", testCredsProvider{}))
+	assert.Equal(t, map[string]string{"irdata/user@example.com": "hunter2"}, store)
+
+	username, password, err := CredsFromKeychain{Service: "irdata", Username: "user@example.com"}.GetCreds()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("user@example.com"), username)
+	assert.Equal(t, []byte("hunter2"), password)
+
+	// CredsFromKeychain would mask it again
+	assert.Error(t, fake.AuthAndSaveProvidedCredsToKeychain("irdata", MaskedCreds{Provider: maskedTestCredsProvider{}}))
+	assert.Len(t, store, 1)
+}
+
+type maskedTestCredsProvider struct{}
+
+func (maskedTestCredsProvider) GetCreds() ([]byte, []byte, error) {
+	return []byte("other@example.com"), []byte(MaskPassword("hunter2", "other@example.com")), nil
+}
+
+func TestKeyFromKeychain(t *testing.T) {
+	saved := keychainGet
+
+	keychainGet = func(service string, account string) ([]byte, error) {
+		switch account {
+		case "key":
+			return []byte(base64.StdEncoding.EncodeToString(testKey) + "\n"), nil
+		case "garbage":
+			return []byte("not base64!"), nil
+		}
+
+		return nil, makeErrorf("not found")
+	}
+
+	t.Cleanup(func() { keychainGet = saved })
+
+	key, err := KeyFromKeychain{Service: "irdata", Account: "key"}.GetKey()
+
+	assert.NoError(t, err)
+	assert.Equal(t, testKey, key)
+
+	_, err = KeyFromKeychain{Service: "irdata", Account: "garbage"}.GetKey()
+	assert.Error(t, err)
+
+	_, err = KeyFromKeychain{Service: "irdata", Account: "missing"}.GetKey()
+	assert.Error(t, err)
+}
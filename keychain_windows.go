@@ -0,0 +1,92 @@
+package irdata
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Windows Credential Manager via advapi32
+
+const (
+	_credTypeGeneric         = 1
+	_credPersistLocalMachine = 2
+)
+
+var (
+	advapi32      = syscall.NewLazyDLL("advapi32.dll")
+	procCredRead  = advapi32.NewProc("CredReadW")
+	procCredWrite = advapi32.NewProc("CredWriteW")
+	procCredFree  = advapi32.NewProc("CredFree")
+)
+
+// CREDENTIALW
+type credentialW struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func credTarget(service string, account string) string {
+	return service + ":" + account
+}
+
+func osKeychainGet(service string, account string) ([]byte, error) {
+	target, err := syscall.UTF16PtrFromString(credTarget(service, account))
+	if err != nil {
+		return nil, makeErrorf("invalid credential name %s/%s [%v]", service, account, err)
+	}
+
+	var cred *credentialW
+
+	ret, _, err := procCredRead.Call(uintptr(unsafe.Pointer(target)), _credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		return nil, makeErrorf("unable to read %s/%s from the credential manager [%v]", service, account, err)
+	}
+
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+
+	return append([]byte{}, blob...), nil
+}
+
+func osKeychainSet(service string, account string, password []byte) error {
+	target, err := syscall.UTF16PtrFromString(credTarget(service, account))
+	if err != nil {
+		return makeErrorf("invalid credential name %s/%s [%v]", service, account, err)
+	}
+
+	userName, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return makeErrorf("invalid account %s [%v]", account, err)
+	}
+
+	if len(password) == 0 {
+		return makeErrorf("password must not be empty")
+	}
+
+	cred := credentialW{
+		Type:               _credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(password)),
+		CredentialBlob:     &password[0],
+		Persist:            _credPersistLocalMachine,
+		UserName:           userName,
+	}
+
+	ret, _, err := procCredWrite.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return makeErrorf("unable to save %s/%s to the credential manager [%v]", service, account, err)
+	}
+
+	return nil
+}
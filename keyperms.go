@@ -0,0 +1,65 @@
+package irdata
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// KeyFilePermissionPolicy controls what happens when a key file can be read
+// by others
+type KeyFilePermissionPolicy int
+
+const (
+	// reject the key file (the default).  On unix the perms must be 0400, on
+	// Windows the file's ACL must not grant Everyone, Users, Authenticated
+	// Users, or Guests read access.
+	KeyFilePermsRequire KeyFilePermissionPolicy = iota
+	// log a warning and use the key file anyway
+	KeyFilePermsWarn
+	// don't check the key file's permissions
+	KeyFilePermsIgnore
+)
+
+// SetKeyFilePermissionPolicy sets how the permissions of key files passed to
+// the auth functions and RotateKey are checked
+func (i *Irdata) SetKeyFilePermissionPolicy(policy KeyFilePermissionPolicy) {
+	i.keyFilePermissionPolicy = policy
+}
+
+// keyFileT is KeyFromFile with a permission policy
+type keyFileT struct {
+	filename string
+	policy   KeyFilePermissionPolicy
+}
+
+func (k keyFileT) GetKey() ([]byte, error) {
+	return getKeyWithPolicy(k.filename, k.policy)
+}
+
+// keyFromFile returns a KeyProvider for keyFilename that uses this client's
+// permission policy
+func (i *Irdata) keyFromFile(keyFilename string) KeyProvider {
+	return keyFileT{filename: keyFilename, policy: i.keyFilePermissionPolicy}
+}
+
+// applyKeyFilePolicy checks the perms of keyFilename according to policy
+func applyKeyFilePolicy(keyFilename string, policy KeyFilePermissionPolicy) error {
+	if policy == KeyFilePermsIgnore {
+		return nil
+	}
+
+	err := checkKeyFilePerms(keyFilename)
+	if err == nil {
+		return nil
+	}
+
+	if policy == KeyFilePermsWarn {
+		log.WithFields(logrus.Fields{
+			"keyFilename": keyFilename,
+			"err":         err,
+		}).Warn("Key file is not protected")
+
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package irdata
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyFilePermissionPolicy(t *testing.T) {
+	keyFilename := filepath.Join(t.TempDir(), "open.key")
+
+	assert.NoError(t, os.WriteFile(keyFilename, []byte(base64.StdEncoding.EncodeToString(testKey)), 0644))
+
+	client := mustOpen()
+
+	_, err := client.keyFromFile(keyFilename).GetKey()
+	assert.Error(t, err)
+
+	for _, policy := range []KeyFilePermissionPolicy{KeyFilePermsWarn, KeyFilePermsIgnore} {
+		client.SetKeyFilePermissionPolicy(policy)
+
+		key, err := client.keyFromFile(keyFilename).GetKey()
+		assert.NoError(t, err)
+		assert.Equal(t, testKey, key)
+	}
+
+	// KeyFromFile always requires the perms
+	_, err = KeyFromFile(keyFilename).GetKey()
+	assert.Error(t, err)
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package irdata
+
+import (
+	"os"
+)
+
+func checkKeyFilePerms(keyFilename string) error {
+	stat, err := os.Stat(keyFilename)
+	if err != nil {
+		return makeErrorf("unable to stat %s [%v]", keyFilename, err)
+	}
+
+	if (stat.Mode() & os.ModePerm) != 0400 {
+		return makeErrorf("key file %v must have perms set to 0400", keyFilename)
+	}
+
+	return nil
+}
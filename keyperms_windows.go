@@ -0,0 +1,73 @@
+package irdata
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// FILE_READ_DATA
+const _fileReadData = 0x0001
+
+var procGetEffectiveRightsFromAcl = windows.NewLazySystemDLL("advapi32.dll").NewProc("GetEffectiveRightsFromAclW")
+
+// groups that mustn't be able to read a key file
+var broadGroups = map[windows.WELL_KNOWN_SID_TYPE]string{
+	windows.WinWorldSid:             "Everyone",
+	windows.WinAuthenticatedUserSid: "Authenticated Users",
+	windows.WinBuiltinUsersSid:      "Users",
+	windows.WinBuiltinGuestsSid:     "Guests",
+}
+
+// checkKeyFilePerms checks the file's ACL since NTFS has no unix perms
+func checkKeyFilePerms(keyFilename string) error {
+	return checkACL(keyFilename, "key file "+keyFilename)
+}
+
+// checkACL checks that the ACL of filename doesn't let broad groups read it,
+// the errors refer to the file as description
+func checkACL(filename string, description string) error {
+	sd, err := windows.GetNamedSecurityInfo(filename, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return makeErrorf("unable to read the ACL of %s [%v]", filename, err)
+	}
+
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return makeErrorf("unable to read the ACL of %s [%v]", filename, err)
+	}
+
+	if dacl == nil {
+		return makeErrorf("%s has no ACL so everyone can read it", description)
+	}
+
+	for sidType, name := range broadGroups {
+		sid, err := windows.CreateWellKnownSid(sidType)
+		if err != nil {
+			return makeErrorf("unable to create SID for %s [%v]", name, err)
+		}
+
+		trustee := windows.TRUSTEE{
+			TrusteeForm:  windows.TRUSTEE_IS_SID,
+			TrusteeType:  windows.TRUSTEE_IS_WELL_KNOWN_GROUP,
+			TrusteeValue: windows.TrusteeValueFromSID(sid),
+		}
+
+		var rights windows.ACCESS_MASK
+
+		ret, _, _ := procGetEffectiveRightsFromAcl.Call(
+			uintptr(unsafe.Pointer(dacl)),
+			uintptr(unsafe.Pointer(&trustee)),
+			uintptr(unsafe.Pointer(&rights)),
+		)
+		if ret != 0 {
+			return makeErrorf("unable to check the ACL of %s [%v]", filename, windows.Errno(ret))
+		}
+
+		if rights&(_fileReadData|windows.GENERIC_READ|windows.GENERIC_ALL) != 0 {
+			return makeErrorf("%s must not be readable by %s", description, name)
+		}
+	}
+
+	return nil
+}
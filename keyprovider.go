@@ -0,0 +1,19 @@
+package irdata
+
+// KeyProvider supplies the secret key used to encrypt and decrypt creds
+// files.  GetKey must return a fresh copy of the key each time as irdata
+// shreds it after use.
+type KeyProvider interface {
+	GetKey() ([]byte, error)
+}
+
+// KeyFromFile is a KeyProvider that reads a base64 encoded key from the file
+// it names.  The file must have its perms set to 0400 (on Windows it must
+// not be readable by broad groups, see KeyFilePermsRequire).
+//
+// See: https://github.com/popmonkey/irdata#creating-and-protecting-the-keyfile
+type KeyFromFile string
+
+func (f KeyFromFile) GetKey() ([]byte, error) {
+	return getKey(string(f))
+}
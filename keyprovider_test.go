@@ -0,0 +1,52 @@
+package irdata
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testKeyProvider hands out copies of testKey
+type testKeyProvider struct{}
+
+func (testKeyProvider) GetKey() ([]byte, error) {
+	return append([]byte{}, testKey...), nil
+}
+
+func TestKeyFromFile(t *testing.T) {
+	expected, err := getKey(testKeyFilename)
+
+	assert.NoError(t, err)
+
+	key, err := KeyFromFile(testKeyFilename).GetKey()
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, key)
+
+	_, err = KeyFromFile(filepath.Join("testdata", "missing.key")).GetKey()
+
+	assert.Error(t, err)
+}
+
+func TestCustomKeyProvider(t *testing.T) {
+	setupAuthTest()
+	t.Cleanup(cleanupAuthTest)
+
+	credsFn := filepath.Join(testAuthDir, "test.creds")
+
+	assert.NoError(t, writeCreds(testKeyProvider{}, credsFn, authDataT{Username: string(testUsername)}, fileOptsT{}))
+
+	authData, err := readCreds(testKeyProvider{}, credsFn, fileOptsT{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, string(testUsername), authData.Username)
+
+	// the file key can't open it
+	_, err = readCreds(KeyFromFile(testKeyFilename), credsFn, fileOptsT{})
+
+	assert.ErrorIs(t, err, ErrWrongKey)
+
+	// and the provider's key is still intact after being shredded
+	assert.NotEqual(t, byte(0x69), testKey[0])
+}
@@ -0,0 +1,127 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const _vaultTimeout = 30 * time.Second
+
+// KeyFromVault is a KeyProvider that keeps the key wrapped by a HashiCorp
+// Vault transit key, so the plaintext key never touches disk.  Ciphertext is
+// the wrapped key, create one with NewDataKey:
+//
+//	vault := irdata.KeyFromVault{Address: "https://vault:8200", KeyName: "irdata"}
+//	vault.Ciphertext, err = vault.NewDataKey()
+//
+// and store Ciphertext with the rest of the configuration.  Each GetKey asks
+// Vault to unwrap it.
+type KeyFromVault struct {
+	Address    string // e.g. https://vault:8200
+	Token      string // VAULT_TOKEN if empty
+	Mount      string // where the transit engine is mounted, transit if empty
+	KeyName    string
+	Ciphertext string
+}
+
+func (k KeyFromVault) GetKey() ([]byte, error) {
+	if k.Ciphertext == "" {
+		return nil, makeErrorf("vault key %s has no ciphertext, see NewDataKey", k.KeyName)
+	}
+
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+
+	if err := k.call("decrypt/"+k.KeyName, map[string]string{"ciphertext": k.Ciphertext}, &resp); err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, makeErrorf("unable to base64 decode key from vault [%v]", err)
+	}
+
+	return key, nil
+}
+
+// NewDataKey asks Vault for a new 256 bit key wrapped by the transit key and
+// returns the wrapped key to use as Ciphertext.  The plaintext key is never
+// returned.
+func (k KeyFromVault) NewDataKey() (string, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+
+	if err := k.call("datakey/wrapped/"+k.KeyName, map[string]interface{}{"bits": 256}, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Data.Ciphertext, nil
+}
+
+// call POSTs body to a transit endpoint and decodes the response into out
+func (k KeyFromVault) call(path string, body interface{}, out interface{}) error {
+	mount := k.Mount
+	if mount == "" {
+		mount = "transit"
+	}
+
+	token := k.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return makeErrorf("unable to encode vault request [%v]", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/%s", strings.TrimRight(k.Address, "/"), mount, path)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return makeErrorf("invalid vault url %s [%v]", url, err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", token)
+
+	client := http.Client{Timeout: _vaultTimeout}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return makeErrorf("vault request to %s failed [%v]", url, err)
+	}
+
+	defer resp.Body.Close()
+
+	respData, err := io.ReadAll(io.LimitReader(resp.Body, _maxDrainBytes))
+	if err != nil {
+		return makeErrorf("unable to read vault response [%v]", err)
+	}
+
+	// the response can hold the key
+	defer shred(&respData)
+
+	if resp.StatusCode != http.StatusOK {
+		return makeErrorf("vault request to %s returned %s", url, resp.Status)
+	}
+
+	if err := json.Unmarshal(respData, out); err != nil {
+		return makeErrorf("unable to decode vault response [%v]", err)
+	}
+
+	return nil
+}
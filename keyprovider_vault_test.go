@@ -0,0 +1,55 @@
+package irdata
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyFromVault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var body map[string]interface{}
+
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch r.URL.Path {
+		case "/v1/kv-transit/datakey/wrapped/irdata":
+			assert.Equal(t, 256.0, body["bits"])
+			fmt.Fprint(w, `{"data":{"ciphertext":"vault:v1:wrapped"}}`)
+		case "/v1/kv-transit/decrypt/irdata":
+			assert.Equal(t, "vault:v1:wrapped", body["ciphertext"])
+			fmt.Fprintf(w, `{"data":{"plaintext":%q}}`, base64.StdEncoding.EncodeToString(testKey))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	vault := KeyFromVault{Address: ts.URL + "/", Token: "s.token", Mount: "kv-transit", KeyName: "irdata"}
+
+	_, err := vault.GetKey()
+	assert.Error(t, err)
+
+	vault.Ciphertext, err = vault.NewDataKey()
+	assert.NoError(t, err)
+	assert.Equal(t, "vault:v1:wrapped", vault.Ciphertext)
+
+	key, err := vault.GetKey()
+	assert.NoError(t, err)
+	assert.Equal(t, testKey, key)
+
+	vault.Token = "wrong"
+
+	_, err = vault.GetKey()
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package irdata
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// lap data times are in 1/10000ths of a second
+const _lapTimeUnit = 100 * time.Microsecond
+
+// lap events reported in lap_events
+const (
+	LapEventPitted      = "pitted"
+	LapEventInvalid     = "invalid"
+	LapEventOffTrack    = "off track"
+	LapEventContact     = "contact"
+	LapEventCarContact  = "car contact"
+	LapEventLostControl = "lost control"
+)
+
+// Lap is a lap from /data/results/lap_data
+type Lap struct {
+	CustID      int64
+	GroupID     int64 // the team in team events
+	DisplayName string
+	LapNumber   int
+	LapTime     time.Duration // zero if the lap has no time
+	SessionTime time.Duration // when the lap was completed
+	LapPosition int
+	Incident    bool
+	LapEvents   []string
+}
+
+// Valid returns true if the lap has a time and wasn't invalidated
+func (l Lap) Valid() bool {
+	return l.LapTime > 0 && !l.HasEvent(LapEventInvalid)
+}
+
+// HasEvent returns true if event is one of the lap's events
+func (l Lap) HasEvent(event string) bool {
+	for _, e := range l.LapEvents {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+type lapT struct {
+	CustID      int64    `json:"cust_id"`
+	GroupID     int64    `json:"group_id"`
+	DisplayName string   `json:"display_name"`
+	LapNumber   int      `json:"lap_number"`
+	LapTime     int64    `json:"lap_time"`
+	SessionTime int64    `json:"session_time"`
+	LapPosition int      `json:"lap_position"`
+	Incident    bool     `json:"incident"`
+	LapEvents   []string `json:"lap_events"`
+}
+
+// ParseLaps returns the laps of a /data/results/lap_data result
+func ParseLaps(data []byte) ([]Lap, error) {
+	var raw struct {
+		ChunkData []lapT `json:"_chunk_data"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, makeErrorf("unable to decode lap data [%v]", err)
+	}
+
+	laps := make([]Lap, 0, len(raw.ChunkData))
+
+	for _, rawLap := range raw.ChunkData {
+		lap := Lap{
+			CustID:      rawLap.CustID,
+			GroupID:     rawLap.GroupID,
+			DisplayName: rawLap.DisplayName,
+			LapNumber:   rawLap.LapNumber,
+			SessionTime: time.Duration(rawLap.SessionTime) * _lapTimeUnit,
+			LapPosition: rawLap.LapPosition,
+			Incident:    rawLap.Incident,
+			LapEvents:   rawLap.LapEvents,
+		}
+
+		if rawLap.LapTime > 0 {
+			lap.LapTime = time.Duration(rawLap.LapTime) * _lapTimeUnit
+		}
+
+		laps = append(laps, lap)
+	}
+
+	return laps, nil
+}
+
+// LapsOf returns the laps driven by id, which is either a cust_id or (in team
+// events) a team's group_id
+func LapsOf(laps []Lap, id int64) []Lap {
+	var of []Lap
+
+	for _, lap := range laps {
+		if lap.CustID == id || lap.GroupID == id {
+			of = append(of, lap)
+		}
+	}
+
+	return of
+}
@@ -0,0 +1,68 @@
+package irdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLaps(t *testing.T) {
+	laps, err := ParseLaps([]byte(`{"_chunk_data":[
+		{"cust_id":1,"group_id":1,"display_name":"A","lap_number":0,"lap_time":-1,"session_time":12345},
+		{"cust_id":1,"group_id":1,"lap_number":1,"lap_time":905000,"lap_events":["pitted"],"incident":true}
+	]}`))
+
+	assert.NoError(t, err)
+	assert.Len(t, laps, 2)
+
+	assert.Equal(t, "A", laps[0].DisplayName)
+	assert.Equal(t, time.Duration(0), laps[0].LapTime)
+	assert.Equal(t, 1234500*time.Microsecond, laps[0].SessionTime)
+	assert.False(t, laps[0].Valid())
+
+	assert.Equal(t, 90500*time.Millisecond, laps[1].LapTime)
+	assert.True(t, laps[1].HasEvent(LapEventPitted))
+	assert.True(t, laps[1].Incident)
+	assert.True(t, laps[1].Valid())
+
+	_, err = ParseLaps([]byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestLapsOf(t *testing.T) {
+	laps := []Lap{{CustID: 1, GroupID: -10}, {CustID: 2, GroupID: -10}, {CustID: 3, GroupID: 3}}
+
+	assert.Len(t, LapsOf(laps, 1), 1)
+	assert.Len(t, LapsOf(laps, -10), 2)
+	assert.Len(t, LapsOf(laps, 4), 0)
+}
+
+func makeLaps(times ...float64) []Lap {
+	laps := []Lap{{LapNumber: 0}}
+
+	for n, seconds := range times {
+		laps = append(laps, Lap{LapNumber: n + 1, LapTime: time.Duration(seconds * float64(time.Second))})
+	}
+
+	return laps
+}
+
+func TestAnalyzeStints(t *testing.T) {
+	laps := makeLaps(90, 91, 92, 120, 110, 90, 90)
+
+	// pitted at the end of lap 4
+	laps[4].LapEvents = []string{LapEventPitted}
+
+	analysis := AnalyzeStints(laps)
+
+	assert.Equal(t, []PitStop{{Lap: 4, TimeLost: 50 * time.Second}}, analysis.PitStops)
+	assert.Equal(t, 4, analysis.FuelWindowLaps)
+
+	assert.Equal(t, []Stint{
+		{Number: 1, StartLap: 1, EndLap: 4, Laps: 4, AveragePace: 91 * time.Second, BestLap: 90 * time.Second},
+		{Number: 2, StartLap: 5, EndLap: 7, Laps: 3, AveragePace: 90 * time.Second, BestLap: 90 * time.Second},
+	}, analysis.Stints)
+
+	assert.Equal(t, StintAnalysis{}, AnalyzeStints(nil))
+}
@@ -0,0 +1,66 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// SetMaxResponseBytes limits how much a single Get may download: the API
+// response plus whatever it links to (s3 link, data url, and chunks).  Once
+// the limit is passed the Get is aborted with ErrResponseTooLarge.  Zero (the
+// default) is no limit.
+func (i *Irdata) SetMaxResponseBytes(maxBytes int64) {
+	i.maxResponseBytes = maxBytes
+}
+
+// responseBudgetT is what's left of a Get's download limit
+type responseBudgetT struct {
+	mu        sync.Mutex
+	limit     int64
+	remaining int64
+}
+
+type responseBudgetKey struct{}
+
+// withResponseBudget returns ctx carrying a download budget if a limit is set
+func (i *Irdata) withResponseBudget(ctx context.Context) context.Context {
+	if i.maxResponseBytes <= 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, responseBudgetKey{}, &responseBudgetT{
+		limit:     i.maxResponseBytes,
+		remaining: i.maxResponseBytes,
+	})
+}
+
+// readBody reads r, charging what it reads to ctx's download budget (if it
+// has one) and failing with ErrResponseTooLarge rather than read past it
+func readBody(ctx context.Context, r io.Reader) ([]byte, error) {
+	budget, ok := ctx.Value(responseBudgetKey{}).(*responseBudgetT)
+	if !ok {
+		return io.ReadAll(r)
+	}
+
+	budget.mu.Lock()
+	remaining := budget.remaining
+	budget.mu.Unlock()
+
+	data, err := io.ReadAll(io.LimitReader(r, remaining+1))
+	if err != nil {
+		return nil, err
+	}
+
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	budget.remaining -= int64(len(data))
+
+	if budget.remaining < 0 {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, budget.limit)
+	}
+
+	return data, nil
+}
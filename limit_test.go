@@ -0,0 +1,58 @@
+package irdata
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxResponseBytes(t *testing.T) {
+	var chunkBase string
+
+	chunkFetches := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/results/search_series":
+			fmt.Fprintf(w, `{"chunk_info":{"base_download_url":"%s/chunks/","chunk_file_names":["a","b","c"]}}`, chunkBase)
+		case "/data/big":
+			fmt.Fprintf(w, `{"big":"%s"}`, strings.Repeat("x", 1000))
+		default:
+			chunkFetches++
+			fmt.Fprintf(w, `[{"row":"%s"}]`, strings.Repeat("x", 100))
+		}
+	}))
+
+	chunkBase = fake.baseURL.String()
+
+	// no limit by default
+	_, err := fake.Get("/data/results/search_series")
+	assert.NoError(t, err)
+
+	fake.SetMaxResponseBytes(500)
+
+	_, err = fake.Get("/data/big")
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+
+	// the limit covers the chunks too, and stops fetching them
+	chunkFetches = 0
+
+	fake.SetMaxResponseBytes(300)
+
+	_, err = fake.Get("/data/results/search_series")
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	assert.Less(t, chunkFetches, 3)
+
+	var partialErr *PartialError
+
+	assert.False(t, errors.As(err, &partialErr))
+
+	fake.SetMaxResponseBytes(1000)
+
+	_, err = fake.Get("/data/results/search_series")
+	assert.NoError(t, err)
+}
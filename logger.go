@@ -0,0 +1,35 @@
+package irdata
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// log is irdata's own logger so that its level (see SetLogLevel), format,
+// and the scrubbing of secrets from it leave the application's logging
+// alone.  It writes wherever logrus' standard logger writes.
+var log = newLogger()
+
+func newLogger() *logrus.Logger {
+	logger := logrus.New()
+
+	logger.Out = standardOutT{}
+
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// scrub secrets from anything we log
+	logger.AddHook(redactHook{})
+
+	return logger
+}
+
+// standardOutT writes to the output of logrus' standard logger, whatever the
+// application has set it to
+type standardOutT struct{}
+
+func (standardOutT) Write(p []byte) (int, error) {
+	return logrus.StandardLogger().Out.Write(p)
+}
@@ -0,0 +1,175 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Metrics is told about the requests irdata makes, see SetMetrics.
+// MetricsCollector is an implementation that Prometheus can scrape.
+type Metrics interface {
+	// ObserveRequest is called for every HTTP attempt, statusCode is zero
+	// when the request failed without a response
+	ObserveRequest(statusCode int, duration time.Duration)
+	ObserveCacheLookup(hit bool)
+	// SetRateLimitRemaining reports the x-ratelimit-remaining of the last
+	// /data response
+	SetRateLimitRemaining(remaining int)
+	AddChunkBytes(n int)
+}
+
+// SetMetrics sets where metrics are reported, nil (the default) turns them
+// off
+func (i *Irdata) SetMetrics(metrics Metrics) {
+	i.metrics = metrics
+}
+
+// observeResponse reports an HTTP attempt to the metrics, if they're on
+func (i *Irdata) observeResponse(resp *http.Response, started time.Time) {
+	if i.metrics == nil {
+		return
+	}
+
+	if resp == nil {
+		i.metrics.ObserveRequest(0, time.Since(started))
+		return
+	}
+
+	i.metrics.ObserveRequest(resp.StatusCode, time.Since(started))
+
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-Ratelimit-Remaining")); err == nil {
+		i.metrics.SetRateLimitRemaining(remaining)
+	}
+}
+
+// upper bounds of the request duration histogram buckets, in seconds
+var _durationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// MetricsCollector is a Metrics that keeps counts in memory and serves them
+// in the Prometheus text format, so it can be scraped without irdata
+// depending on the Prometheus client:
+//
+//	metrics := irdata.NewMetricsCollector()
+//	api.SetMetrics(metrics)
+//	http.Handle("/metrics/irdata", metrics)
+type MetricsCollector struct {
+	mu sync.Mutex
+
+	requests        map[int]int64 // by status code, 0 for failed requests
+	durationBuckets []int64
+	durationSum     float64
+	durationCount   int64
+	cacheHits       int64
+	cacheMisses     int64
+	rateLimitSeen   bool
+	rateLimitLeft   int
+	chunkBytes      int64
+}
+
+func NewMetricsCollector() *MetricsCollector {
+	return &MetricsCollector{
+		requests:        map[int]int64{},
+		durationBuckets: make([]int64, len(_durationBuckets)),
+	}
+}
+
+func (m *MetricsCollector) ObserveRequest(statusCode int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[statusCode]++
+
+	seconds := duration.Seconds()
+
+	for n, bound := range _durationBuckets {
+		if seconds <= bound {
+			m.durationBuckets[n]++
+		}
+	}
+
+	m.durationSum += seconds
+	m.durationCount++
+}
+
+func (m *MetricsCollector) ObserveCacheLookup(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+func (m *MetricsCollector) SetRateLimitRemaining(remaining int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rateLimitSeen = true
+	m.rateLimitLeft = remaining
+}
+
+func (m *MetricsCollector) AddChunkBytes(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.chunkBytes += int64(n)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP irdata_requests_total HTTP requests made by irdata by status (error when there was no response).")
+	fmt.Fprintln(w, "# TYPE irdata_requests_total counter")
+
+	statusCodes := make([]int, 0, len(m.requests))
+	for statusCode := range m.requests {
+		statusCodes = append(statusCodes, statusCode)
+	}
+
+	sort.Ints(statusCodes)
+
+	for _, statusCode := range statusCodes {
+		status := strconv.Itoa(statusCode)
+		if statusCode == 0 {
+			status = "error"
+		}
+
+		fmt.Fprintf(w, "irdata_requests_total{status=%q} %d\n", status, m.requests[statusCode])
+	}
+
+	fmt.Fprintln(w, "# HELP irdata_request_duration_seconds Duration of HTTP requests made by irdata.")
+	fmt.Fprintln(w, "# TYPE irdata_request_duration_seconds histogram")
+
+	for n, bound := range _durationBuckets {
+		fmt.Fprintf(w, "irdata_request_duration_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(bound, 'g', -1, 64), m.durationBuckets[n])
+	}
+
+	fmt.Fprintf(w, "irdata_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.durationCount)
+	fmt.Fprintf(w, "irdata_request_duration_seconds_sum %g\n", m.durationSum)
+	fmt.Fprintf(w, "irdata_request_duration_seconds_count %d\n", m.durationCount)
+
+	fmt.Fprintln(w, "# HELP irdata_cache_lookups_total Cache lookups by GetWithCache by result.")
+	fmt.Fprintln(w, "# TYPE irdata_cache_lookups_total counter")
+	fmt.Fprintf(w, "irdata_cache_lookups_total{result=\"hit\"} %d\n", m.cacheHits)
+	fmt.Fprintf(w, "irdata_cache_lookups_total{result=\"miss\"} %d\n", m.cacheMisses)
+
+	if m.rateLimitSeen {
+		fmt.Fprintln(w, "# HELP irdata_ratelimit_remaining Requests left in the current rate limit window.")
+		fmt.Fprintln(w, "# TYPE irdata_ratelimit_remaining gauge")
+		fmt.Fprintf(w, "irdata_ratelimit_remaining %d\n", m.rateLimitLeft)
+	}
+
+	fmt.Fprintln(w, "# HELP irdata_chunk_bytes_total Bytes of chunk files downloaded.")
+	fmt.Fprintln(w, "# TYPE irdata_chunk_bytes_total counter")
+	fmt.Fprintf(w, "irdata_chunk_bytes_total %d\n", m.chunkBytes)
+}
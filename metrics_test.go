@@ -0,0 +1,58 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsCollector(t *testing.T) {
+	var chunkBase string
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/results/search_series":
+			w.Header().Set("X-Ratelimit-Remaining", "239")
+			fmt.Fprintf(w, `{"chunk_info":{"base_download_url":"%s/chunks/","chunk_file_names":["a"]}}`, chunkBase)
+		case "/chunks/a":
+			fmt.Fprint(w, `[{"a":1}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	chunkBase = fake.baseURL.String()
+
+	metrics := NewMetricsCollector()
+
+	fake.SetMetrics(metrics)
+
+	_, err := fake.Get("/data/results/search_series")
+	assert.NoError(t, err)
+
+	_, err = fake.Get("/data/missing")
+	assert.NoError(t, err)
+
+	metrics.ObserveCacheLookup(true)
+	metrics.ObserveRequest(0, 20*time.Second)
+
+	rec := httptest.NewRecorder()
+
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+
+	assert.Contains(t, body, `irdata_requests_total{status="200"} 2`)
+	assert.Contains(t, body, `irdata_requests_total{status="404"} 1`)
+	assert.Contains(t, body, `irdata_requests_total{status="error"} 1`)
+	assert.Contains(t, body, `irdata_request_duration_seconds_bucket{le="30"} 4`)
+	assert.Contains(t, body, `irdata_request_duration_seconds_bucket{le="10"} 3`)
+	assert.Contains(t, body, `irdata_request_duration_seconds_count 4`)
+	assert.Contains(t, body, `irdata_cache_lookups_total{result="hit"} 1`)
+	assert.Contains(t, body, `irdata_ratelimit_remaining 239`)
+	assert.Contains(t, body, `irdata_chunk_bytes_total 9`)
+}
@@ -0,0 +1,39 @@
+package irdata
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+// errNotFound is returned along with the body when the API responds 404,
+// Get returns the body as it always has but the cache needs to know
+var errNotFound = errors.New("irdata: not found")
+
+// SetNegativeCacheTTL limits how long GetWithCache caches not found (404)
+// responses and empty results ("[]", "{}" or "null") to ttl, so looking up
+// a subsession or member that doesn't exist (yet) doesn't cost a request
+// every time but isn't cached for as long as real data.  Zero (the default)
+// caches them like any other result.
+func (i *Irdata) SetNegativeCacheTTL(ttl time.Duration) {
+	i.negativeCacheTTL = ttl
+}
+
+// negativeCacheTTLFor returns the ttl to cache data for, no longer than the
+// negative cache ttl if it's not found or an empty result
+func (i *Irdata) negativeCacheTTLFor(data []byte, notFound bool, ttl time.Duration) time.Duration {
+	if i.negativeCacheTTL <= 0 || i.negativeCacheTTL >= ttl {
+		return ttl
+	}
+
+	if notFound {
+		return i.negativeCacheTTL
+	}
+
+	switch string(bytes.TrimSpace(data)) {
+	case "", "[]", "{}", "null":
+		return i.negativeCacheTTL
+	}
+
+	return ttl
+}
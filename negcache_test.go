@@ -0,0 +1,84 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegativeCache(t *testing.T) {
+	requests := map[string]int{}
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+
+		switch r.URL.Path {
+		case "/data/results/get":
+			http.Error(w, `{"error":"Not Found"}`, http.StatusNotFound)
+		case "/data/results/search_series":
+			fmt.Fprint(w, `[]`)
+		default:
+			fmt.Fprint(w, `{"ok":true}`)
+		}
+	}))
+
+	assert.NoError(t, fake.EnableCache(filepath.Join(t.TempDir(), "cache")))
+	t.Cleanup(fake.Close)
+
+	// off by default, cached like anything else
+	for n := 0; n < 2; n++ {
+		data, err := fake.GetWithCache("/data/results/get", 10*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "Not Found")
+	}
+
+	assert.Equal(t, 1, requests["/data/results/get"])
+
+	time.Sleep(20 * time.Millisecond)
+
+	ttl := 50 * time.Millisecond
+
+	fake.SetNegativeCacheTTL(ttl)
+
+	requests = map[string]int{}
+
+	for n := 0; n < 3; n++ {
+		data, err := fake.GetWithCache("/data/results/get", time.Hour)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "Not Found")
+
+		_, err = fake.GetWithCache("/data/results/search_series", time.Hour)
+		assert.NoError(t, err)
+
+		_, err = fake.GetWithCache("/data/member/info", time.Hour)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, map[string]int{
+		"/data/results/get":           1,
+		"/data/results/search_series": 1,
+		"/data/member/info":           1,
+	}, requests)
+
+	// not found and empty results expire with the negative ttl
+	time.Sleep(2 * ttl)
+
+	_, err := fake.GetWithCache("/data/results/get", time.Hour)
+	assert.NoError(t, err)
+
+	_, err = fake.GetWithCache("/data/results/search_series", time.Hour)
+	assert.NoError(t, err)
+
+	_, err = fake.GetWithCache("/data/member/info", time.Hour)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]int{
+		"/data/results/get":           2,
+		"/data/results/search_series": 2,
+		"/data/member/info":           1,
+	}, requests)
+}
@@ -0,0 +1,492 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WatchKind is what a Watch is watching
+type WatchKind string
+
+const (
+	WatchDriver WatchKind = "driver" // ID is a cust_id
+	WatchTeam   WatchKind = "team"   // ID is a team_id
+	WatchLeague WatchKind = "league" // ID is a league_id
+)
+
+// Watch selects the subsessions (and, for leagues, standings) a Notifier
+// reports on.  SeasonID is only used by league watches and enables standings
+// change notifications for that league season.
+type Watch struct {
+	Kind     WatchKind `json:"kind"`
+	ID       int64     `json:"id"`
+	SeasonID int64     `json:"season_id,omitempty"`
+}
+
+// NotifierEventType is the type of a NotifierEvent
+type NotifierEventType string
+
+const (
+	EventNewSubsession    NotifierEventType = "new_subsession"
+	EventStandingsChanged NotifierEventType = "standings_changed"
+	EventRateLimited      NotifierEventType = "rate_limited" // see NotifyRateLimits
+)
+
+// NotifierEvent is the JSON payload POSTed to webhooks
+type NotifierEvent struct {
+	Type         NotifierEventType `json:"type"`
+	Watch        Watch             `json:"watch"`
+	SubsessionID int64             `json:"subsession_id,omitempty"`
+	Data         json.RawMessage   `json:"data"`
+	At           time.Time         `json:"at"`
+}
+
+// RateLimitWarning is the Data of an EventRateLimited event
+type RateLimitWarning struct {
+	Remaining int       `json:"remaining"` // see RateLimitStatus
+	Reset     time.Time `json:"reset"`
+	// how long the request was held back, zero if iRacing didn't say
+	WaitedSeconds float64 `json:"waited_seconds"`
+}
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the payload, keyed
+// with the webhook's secret, in the form "sha256=<hex>"
+const SignatureHeader = "X-Irdata-Signature"
+
+type webhookT struct {
+	url    string
+	secret []byte
+}
+
+// Notifier polls the /data API for new subsessions of watched drivers, teams,
+// and leagues (and for changes to league standings) and POSTs signed events
+// to the registered webhooks and publishers (see AddPublisher).
+//
+// The first poll only records what already exists, events are sent for what
+// shows up after that.  An event that can't be delivered to every webhook and
+// publisher is sent again by the next poll, so receivers may see it more than
+// once.
+type Notifier struct {
+	i *Irdata
+
+	// how far back the subsession searches look
+	lookback time.Duration
+
+	// how long to cache results for, see SetCacheTTL
+	cacheTTL time.Duration
+
+	mu         sync.Mutex
+	webhooks   []webhookT
+	publishers []Publisher
+	handlers   []func(NotifierEvent)
+	deliverers []func(NotifierEvent) error // like handlers but may fail
+	watches    []Watch
+	primed     map[Watch]bool
+	seen       map[Watch]map[int64]bool
+	standings  map[Watch][32]byte
+
+	// see NotifyRateLimits
+	notifyRateLimits bool
+	rateLimitEvent   *NotifierEvent
+
+	webhookClient http.Client
+}
+
+// NewNotifier returns a Notifier that uses i to poll.  Searches look back
+// lookback from the time of each poll (the /data API allows up to 90 days).
+func (i *Irdata) NewNotifier(lookback time.Duration) *Notifier {
+	return &Notifier{
+		i:         i,
+		lookback:  lookback,
+		primed:    map[Watch]bool{},
+		seen:      map[Watch]map[int64]bool{},
+		standings: map[Watch][32]byte{},
+		webhookClient: http.Client{
+			Timeout: time.Duration(30) * time.Second,
+		},
+	}
+}
+
+// AddWebhook registers a url to POST events to, signed with secret
+func (n *Notifier) AddWebhook(url string, secret []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.webhooks = append(n.webhooks, webhookT{url: url, secret: append([]byte{}, secret...)})
+}
+
+// OnEvent registers a function that is called with every event
+func (n *Notifier) OnEvent(handler func(NotifierEvent)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.handlers = append(n.handlers, handler)
+}
+
+// SetCacheTTL caches the results of polls for ttl when the client's cache is
+// enabled, so several notifiers (or programs) sharing a cache don't repeat
+// the same searches.  Zero (the default) always fetches.
+func (n *Notifier) SetCacheTTL(ttl time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.cacheTTL = ttl
+}
+
+// Watch adds w to the things being watched, watching it again does nothing
+func (n *Notifier) Watch(w Watch) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.seen[w]; ok {
+		return
+	}
+
+	n.watches = append(n.watches, w)
+	n.seen[w] = map[int64]bool{}
+}
+
+// NotifyRateLimits sends EventRateLimited events (with a RateLimitWarning,
+// and no Watch) when the client's /data requests are held back by the rate
+// limit, see SetRateLimitCallback.  They're sent at the end of the poll
+// they happen in (or the next poll if they happen between polls) and only
+// the latest is sent if there were several.
+func (n *Notifier) NotifyRateLimits() {
+	n.mu.Lock()
+
+	if n.notifyRateLimits {
+		n.mu.Unlock()
+		return
+	}
+
+	n.notifyRateLimits = true
+
+	n.mu.Unlock()
+
+	n.i.observeRateLimits(n.rateLimited)
+}
+
+// rateLimited keeps a rate limit event to send, it runs on the requesting
+// goroutine so it doesn't send it itself
+func (n *Notifier) rateLimited(remaining int, reset time.Time, waited time.Duration) {
+	data, err := json.Marshal(RateLimitWarning{
+		Remaining:     remaining,
+		Reset:         reset,
+		WaitedSeconds: waited.Seconds(),
+	})
+	if err != nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.rateLimitEvent = &NotifierEvent{
+		Type: EventRateLimited,
+		Data: data,
+		At:   time.Now().UTC(),
+	}
+}
+
+// Run polls every interval until ctx is done
+func (n *Notifier) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := n.Poll(); err != nil {
+			log.WithField("err", err).Warn("Notifier poll failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks every watch once and sends events for anything new
+func (n *Notifier) Poll() error {
+	n.mu.Lock()
+	watches := append([]Watch{}, n.watches...)
+	n.mu.Unlock()
+
+	var failures []error
+
+	for _, w := range watches {
+		events, err := n.pollWatch(w)
+		if err != nil {
+			failures = append(failures, err)
+			continue
+		}
+
+		for _, event := range events {
+			if err := n.send(event); err != nil {
+				failures = append(failures, err)
+				continue
+			}
+
+			n.delivered(event)
+		}
+	}
+
+	if err := n.sendRateLimited(); err != nil {
+		failures = append(failures, err)
+	}
+
+	if len(failures) > 0 {
+		return &PartialError{Errors: failures}
+	}
+
+	return nil
+}
+
+func (n *Notifier) pollWatch(w Watch) ([]NotifierEvent, error) {
+	now := time.Now().UTC()
+
+	n.mu.Lock()
+	cacheTTL := n.cacheTTL
+	n.mu.Unlock()
+
+	// fetch everything before touching what's been seen so a failed poll is
+	// repeated in full by the next one.  What's new is only recorded once
+	// its event is delivered, see delivered.
+	rows, err := n.searchSubsessions(w, now.Add(-n.lookback), cacheTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	var standings []byte
+
+	if w.Kind == WatchLeague && w.SeasonID != 0 {
+		standings, err = n.get(fmt.Sprintf("/data/league/season_standings?league_id=%d&season_id=%d", w.ID, w.SeasonID), cacheTTL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	primed := n.primed[w]
+
+	var events []NotifierEvent
+
+	seen := map[int64]bool{}
+
+	for _, row := range rows {
+		subsessionID, ok := row["subsession_id"].(float64)
+		if !ok || n.seen[w][int64(subsessionID)] || seen[int64(subsessionID)] {
+			continue
+		}
+
+		seen[int64(subsessionID)] = true
+
+		if !primed {
+			continue
+		}
+
+		data, err := json.Marshal(row)
+		if err != nil {
+			return nil, makeErrorf("unable to encode subsession %d [%v]", int64(subsessionID), err)
+		}
+
+		events = append(events, NotifierEvent{
+			Type:         EventNewSubsession,
+			Watch:        w,
+			SubsessionID: int64(subsessionID),
+			Data:         data,
+			At:           now,
+		})
+	}
+
+	if standings != nil {
+		sum := sha256.Sum256(standings)
+
+		previous, ok := n.standings[w]
+
+		if !primed {
+			n.standings[w] = sum
+		} else if !ok || previous != sum {
+			events = append(events, NotifierEvent{
+				Type:  EventStandingsChanged,
+				Watch: w,
+				Data:  standings,
+				At:    now,
+			})
+		}
+	}
+
+	if !primed {
+		for subsessionID := range seen {
+			n.seen[w][subsessionID] = true
+		}
+
+		n.primed[w] = true
+	}
+
+	return events, nil
+}
+
+// delivered records that event has been sent so it isn't sent again
+func (n *Notifier) delivered(event NotifierEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch event.Type {
+	case EventNewSubsession:
+		n.seen[event.Watch][event.SubsessionID] = true
+	case EventStandingsChanged:
+		n.standings[event.Watch] = sha256.Sum256(event.Data)
+	}
+}
+
+// sendRateLimited sends the rate limit event kept by rateLimited, if there is
+// one.  It's kept to send again if it isn't delivered.
+func (n *Notifier) sendRateLimited() error {
+	n.mu.Lock()
+	event := n.rateLimitEvent
+	n.mu.Unlock()
+
+	if event == nil {
+		return nil
+	}
+
+	if err := n.send(*event); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+
+	// unless there's been another since
+	if n.rateLimitEvent == event {
+		n.rateLimitEvent = nil
+	}
+
+	n.mu.Unlock()
+
+	return nil
+}
+
+func (n *Notifier) searchSubsessions(w Watch, since time.Time, cacheTTL time.Duration) ([]map[string]interface{}, error) {
+	startRangeBegin := since.Format("2006-01-02T15:04Z")
+
+	var uri string
+
+	switch w.Kind {
+	case WatchDriver:
+		uri = fmt.Sprintf("/data/results/search_series?cust_id=%d&start_range_begin=%s", w.ID, startRangeBegin)
+	case WatchTeam:
+		uri = fmt.Sprintf("/data/results/search_series?team_id=%d&start_range_begin=%s", w.ID, startRangeBegin)
+	case WatchLeague:
+		uri = fmt.Sprintf("/data/results/search_hosted?league_id=%d&start_range_begin=%s", w.ID, startRangeBegin)
+	default:
+		return nil, makeErrorf("unknown watch kind %s", w.Kind)
+	}
+
+	data, err := n.get(uri, cacheTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return chunkRows(data)
+}
+
+// get fetches uri through the cache if it's enabled and cacheTTL is set
+func (n *Notifier) get(uri string, cacheTTL time.Duration) ([]byte, error) {
+	if cacheTTL > 0 && n.i.cask != nil {
+		return n.i.GetWithCache(uri, cacheTTL)
+	}
+
+	return n.i.Get(uri)
+}
+
+// send calls the handlers with event, POSTs it to every webhook, and
+// publishes it to every publisher
+func (n *Notifier) send(event NotifierEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return makeErrorf("unable to encode event [%v]", err)
+	}
+
+	n.mu.Lock()
+	webhooks := append([]webhookT{}, n.webhooks...)
+	publishers := append([]Publisher{}, n.publishers...)
+	handlers := append([]func(NotifierEvent){}, n.handlers...)
+	deliverers := append([]func(NotifierEvent) error{}, n.deliverers...)
+	n.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+
+	var failures []error
+
+	for _, deliverer := range deliverers {
+		if err := deliverer(event); err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	for _, webhook := range webhooks {
+		if err := n.post(webhook, payload); err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	subject := publishSubject(event.Type)
+
+	for _, publisher := range publishers {
+		if err := publisher.Publish(context.Background(), subject, payload); err != nil {
+			failures = append(failures, makeErrorf("publish to %s failed [%v]", subject, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return &PartialError{Errors: failures}
+	}
+
+	return nil
+}
+
+func (n *Notifier) post(webhook webhookT, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, webhook.url, bytes.NewReader(payload))
+	if err != nil {
+		return makeErrorf("invalid webhook url %s [%v]", webhook.url, err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, "sha256="+SignPayload(webhook.secret, payload))
+
+	resp, err := n.webhookClient.Do(req)
+	if err != nil {
+		return makeErrorf("webhook %s failed [%v]", webhook.url, err)
+	}
+
+	drainAndClose(resp)
+
+	if resp.StatusCode >= 300 {
+		return makeErrorf("webhook %s returned %s", webhook.url, resp.Status)
+	}
+
+	return nil
+}
+
+// SignPayload returns the hex encoded HMAC-SHA256 of payload keyed with
+// secret, receivers can use it to verify the SignatureHeader
+func SignPayload(secret []byte, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
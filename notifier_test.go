@@ -0,0 +1,306 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifier(t *testing.T) {
+	var mu sync.Mutex
+
+	subsessionIDs := []int{1001}
+	standings := `{"standings":[1]}`
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/data/results/search_series":
+			rows := []map[string]int{}
+			for _, id := range subsessionIDs {
+				rows = append(rows, map[string]int{"subsession_id": id})
+			}
+			data, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{ChunkDataKey: rows}})
+			w.Write(data)
+		case "/data/results/search_hosted":
+			fmt.Fprint(w, `{"data":{"_chunk_data":[]}}`)
+		case "/data/league/season_standings":
+			fmt.Fprint(w, standings)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	secret := []byte("s3cr3t")
+
+	var events []NotifierEvent
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+
+		assert.Equal(t, "sha256="+SignPayload(secret, payload), r.Header.Get(SignatureHeader))
+
+		var event NotifierEvent
+
+		assert.NoError(t, json.Unmarshal(payload, &event))
+
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	n := fake.NewNotifier(24 * time.Hour)
+
+	var subjects []string
+
+	n.AddWebhook(hook.URL, secret)
+	n.AddPublisher(PublisherFunc(func(ctx context.Context, subject string, payload []byte) error {
+		assert.True(t, json.Valid(payload))
+
+		subjects = append(subjects, subject)
+
+		return nil
+	}))
+	n.Watch(Watch{Kind: WatchDriver, ID: 42})
+	n.Watch(Watch{Kind: WatchLeague, ID: 7, SeasonID: 9})
+
+	// first poll only primes
+	assert.NoError(t, n.Poll())
+	assert.Empty(t, events)
+
+	mu.Lock()
+	subsessionIDs = append(subsessionIDs, 1002)
+	standings = `{"standings":[2]}`
+	mu.Unlock()
+
+	assert.NoError(t, n.Poll())
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, EventNewSubsession, events[0].Type)
+	assert.Equal(t, int64(1002), events[0].SubsessionID)
+	assert.Equal(t, Watch{Kind: WatchDriver, ID: 42}, events[0].Watch)
+	assert.Equal(t, EventStandingsChanged, events[1].Type)
+	assert.JSONEq(t, standings, string(events[1].Data))
+
+	assert.Equal(t, []string{"irdata.new_subsession", "irdata.standings_changed"}, subjects)
+
+	// nothing new
+	assert.NoError(t, n.Poll())
+	assert.Len(t, events, 2)
+}
+
+func TestNotifierStandingsFailure(t *testing.T) {
+	var mu sync.Mutex
+
+	subsessionIDs := []int{1001}
+	standingsDown := false
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/data/results/search_hosted":
+			rows := []map[string]int{}
+			for _, id := range subsessionIDs {
+				rows = append(rows, map[string]int{"subsession_id": id})
+			}
+			data, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{ChunkDataKey: rows}})
+			w.Write(data)
+		case "/data/league/season_standings":
+			if standingsDown {
+				http.Error(w, "maintenance", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `{"standings":[1]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	fake.SetRetryPolicy(BackoffRetryPolicy{MaxAttempts: 1})
+
+	n := fake.NewNotifier(24 * time.Hour)
+
+	var events []NotifierEvent
+
+	n.OnEvent(func(event NotifierEvent) {
+		events = append(events, event)
+	})
+	n.Watch(Watch{Kind: WatchLeague, ID: 7, SeasonID: 9})
+
+	assert.NoError(t, n.Poll())
+
+	mu.Lock()
+	subsessionIDs = append(subsessionIDs, 1002)
+	standingsDown = true
+	mu.Unlock()
+
+	// the new subsession isn't lost when the standings can't be fetched
+	assert.Error(t, n.Poll())
+	assert.Empty(t, events)
+
+	mu.Lock()
+	standingsDown = false
+	mu.Unlock()
+
+	assert.NoError(t, n.Poll())
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventNewSubsession, events[0].Type)
+		assert.Equal(t, int64(1002), events[0].SubsessionID)
+	}
+}
+
+func TestNotifierRedelivers(t *testing.T) {
+	var mu sync.Mutex
+
+	subsessionIDs := []int{1001}
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		rows := []map[string]int{}
+		for _, id := range subsessionIDs {
+			rows = append(rows, map[string]int{"subsession_id": id})
+		}
+		data, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{ChunkDataKey: rows}})
+		w.Write(data)
+	}))
+
+	hookDown := false
+
+	var delivered []int64
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if hookDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var event NotifierEvent
+
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+
+		delivered = append(delivered, event.SubsessionID)
+	}))
+	defer hook.Close()
+
+	n := fake.NewNotifier(24 * time.Hour)
+
+	n.AddWebhook(hook.URL, []byte("s3cr3t"))
+	n.Watch(Watch{Kind: WatchDriver, ID: 42})
+
+	assert.NoError(t, n.Poll())
+
+	// watching again doesn't start over
+	n.Watch(Watch{Kind: WatchDriver, ID: 42})
+
+	assert.NoError(t, n.Poll())
+	assert.Empty(t, delivered)
+
+	mu.Lock()
+	subsessionIDs = append(subsessionIDs, 1002)
+	hookDown = true
+	mu.Unlock()
+
+	assert.Error(t, n.Poll())
+
+	mu.Lock()
+	hookDown = false
+	mu.Unlock()
+
+	// the event that wasn't delivered is sent again, once
+	assert.NoError(t, n.Poll())
+	assert.NoError(t, n.Poll())
+
+	assert.Equal(t, []int64{1002}, delivered)
+}
+
+func TestNotifierRateLimits(t *testing.T) {
+	setupRetryTest(t)
+
+	requests := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("X-Ratelimit-Reset", "1717243200")
+
+		if requests == 1 {
+			w.Header().Set("X-Ratelimit-Remaining", "0")
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+
+		w.Header().Set("X-Ratelimit-Remaining", "239")
+		fmt.Fprint(w, `{"data":{"_chunk_data":[]}}`)
+	}))
+
+	n := fake.NewNotifier(24 * time.Hour)
+
+	var events []NotifierEvent
+	var subjects []string
+
+	failing := true
+
+	n.AddPublisher(PublisherFunc(func(ctx context.Context, subject string, payload []byte) error {
+		if failing {
+			return makeErrorf("bus is down")
+		}
+
+		var event NotifierEvent
+
+		assert.NoError(t, json.Unmarshal(payload, &event))
+
+		events = append(events, event)
+		subjects = append(subjects, subject)
+
+		return nil
+	}))
+
+	n.NotifyRateLimits()
+	n.NotifyRateLimits()
+
+	n.Watch(Watch{Kind: WatchDriver, ID: 42})
+
+	// the search was held back by a 429, the warning couldn't be published
+	assert.Error(t, n.Poll())
+	assert.Empty(t, events)
+
+	// so it's sent again, once
+	failing = false
+
+	assert.NoError(t, n.Poll())
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventRateLimited, events[0].Type)
+
+		var warning RateLimitWarning
+
+		assert.NoError(t, json.Unmarshal(events[0].Data, &warning))
+		assert.Equal(t, 0, warning.Remaining)
+		assert.True(t, time.Unix(1717243200, 0).Equal(warning.Reset))
+	}
+
+	assert.Equal(t, []string{"irdata.rate_limited"}, subjects)
+
+	assert.NoError(t, n.Poll())
+	assert.Len(t, events, 1)
+}
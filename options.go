@@ -0,0 +1,75 @@
+package irdata
+
+import (
+	"net/http"
+)
+
+// Option configures a client as it's opened, see OpenWithOptions.  Each
+// Option does what the setter it's named after does.
+type Option func(*Irdata) error
+
+// WithBaseURL is SetBaseURL as an Option
+func WithBaseURL(baseURL string) Option {
+	return func(i *Irdata) error {
+		return i.SetBaseURL(baseURL)
+	}
+}
+
+// WithLoginURL is SetLoginURL as an Option
+func WithLoginURL(loginURL string) Option {
+	return func(i *Irdata) error {
+		return i.SetLoginURL(loginURL)
+	}
+}
+
+// WithTransport is SetTransport as an Option
+func WithTransport(transport http.RoundTripper) Option {
+	return func(i *Irdata) error {
+		i.SetTransport(transport)
+		return nil
+	}
+}
+
+// WithHTTPClient is SetHTTPClient as an Option
+func WithHTTPClient(client *http.Client) Option {
+	return func(i *Irdata) error {
+		i.SetHTTPClient(client)
+		return nil
+	}
+}
+
+// WithUserAgent is SetUserAgent as an Option
+func WithUserAgent(userAgent string) Option {
+	return func(i *Irdata) error {
+		i.SetUserAgent(userAgent)
+		return nil
+	}
+}
+
+// WithRetryPolicy is SetRetryPolicy as an Option
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(i *Irdata) error {
+		i.SetRetryPolicy(policy)
+		return nil
+	}
+}
+
+// WithStrictSecurity is SetStrictSecurity(true) as an Option.  It fails
+// rather than close a cache enabled by an earlier option.
+func WithStrictSecurity() Option {
+	return func(i *Irdata) error {
+		if i.cask != nil {
+			return makeErrorf("the cache is not encrypted and can't be used in strict security mode")
+		}
+
+		i.SetStrictSecurity(true)
+		return nil
+	}
+}
+
+// WithCache is EnableCache as an Option
+func WithCache(cacheDir string) Option {
+	return func(i *Irdata) error {
+		return i.EnableCache(cacheDir)
+	}
+}
@@ -0,0 +1,42 @@
+package irdata
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenWithOptions(t *testing.T) {
+	transport := &countingTransport{}
+
+	client, err := OpenWithOptions(context.Background(),
+		WithBaseURL("http://localhost:1234"),
+		WithLoginURL("http://localhost:1234/auth"),
+		WithTransport(transport),
+		WithUserAgent("test/1.0"),
+		WithCache(filepath.Join(t.TempDir(), "cache")),
+	)
+
+	if assert.NoError(t, err) {
+		defer client.Close()
+
+		assert.Equal(t, "http://localhost:1234", client.baseURL.String())
+		assert.Equal(t, "http://localhost:1234/auth", client.loginURL)
+		assert.Equal(t, http.RoundTripper(transport), client.httpClient.Transport)
+		assert.Equal(t, "test/1.0", client.headers.Get("User-Agent"))
+		assert.NotNil(t, client.cask)
+	}
+
+	_, err = OpenWithOptions(context.Background(), WithBaseURL("/relative"))
+	assert.Error(t, err)
+
+	// options are applied in order
+	_, err = OpenWithOptions(context.Background(), WithStrictSecurity(), WithCache(t.TempDir()))
+	assert.Error(t, err)
+
+	_, err = OpenWithOptions(context.Background(), WithCache(t.TempDir()), WithStrictSecurity())
+	assert.Error(t, err)
+}
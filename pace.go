@@ -0,0 +1,102 @@
+package irdata
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pacerT is a token bucket: it holds up to burst tokens, refilled at rate
+// per second, and each request takes one
+type pacerT struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// SetTargetRequestRate paces /data requests (s3 downloads aren't rate
+// limited) to rps per second on average, allowing bursts of up to burst
+// requests, so bulk jobs stay under iRacing's rate limit rather than run into
+// it and wait for it to reset.  rps of zero or less turns pacing off (the
+// default).
+func (i *Irdata) SetTargetRequestRate(rps float64, burst int) {
+	if rps <= 0 {
+		i.pacer = nil
+		return
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	i.pacer = &pacerT{rate: rps, burst: float64(burst), tokens: float64(burst)}
+}
+
+// reserve takes a token at now and returns how long to wait until it may be
+// used
+func (p *pacerT) reserve(now time.Time) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.last.IsZero() {
+		p.tokens += now.Sub(p.last).Seconds() * p.rate
+
+		if p.tokens > p.burst {
+			p.tokens = p.burst
+		}
+	}
+
+	p.last = now
+
+	// tokens go negative as requests queue up for them
+	p.tokens--
+
+	if p.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-p.tokens / p.rate * float64(time.Second))
+}
+
+// unreserve gives back a token that wasn't used
+func (p *pacerT) unreserve() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tokens++
+}
+
+// wait waits for a token, or until ctx is done, and returns how long it
+// waited.  If it would have to wait longer than maxWait (when that's more than
+// zero) it doesn't and returns a *RateLimitExceededError.
+func (p *pacerT) wait(ctx context.Context, maxWait time.Duration) (time.Duration, error) {
+	if p == nil {
+		return 0, nil
+	}
+
+	delay := p.reserve(time.Now())
+	if delay == 0 {
+		return 0, nil
+	}
+
+	if maxWait > 0 && delay > maxWait {
+		p.unreserve()
+
+		return 0, &RateLimitExceededError{Wait: delay, MaxWait: maxWait}
+	}
+
+	log.WithFields(logrus.Fields{"delay": delay}).Debug("Pacing request")
+
+	if err := sleepCtx(ctx, delay); err != nil {
+		p.unreserve()
+
+		return 0, err
+	}
+
+	return delay, nil
+}
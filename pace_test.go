@@ -0,0 +1,73 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacerReserve(t *testing.T) {
+	p := &pacerT{rate: 2, burst: 2, tokens: 2}
+
+	now := time.Now()
+
+	// the burst goes right away
+	assert.Equal(t, time.Duration(0), p.reserve(now))
+	assert.Equal(t, time.Duration(0), p.reserve(now))
+
+	// then requests queue up a token each
+	assert.Equal(t, 500*time.Millisecond, p.reserve(now))
+	assert.Equal(t, time.Second, p.reserve(now))
+
+	// once the queue has drained and the bucket refilled there's a burst
+	// again, but no more
+	later := now.Add(10 * time.Second)
+
+	assert.Equal(t, time.Duration(0), p.reserve(later))
+	assert.Equal(t, time.Duration(0), p.reserve(later))
+	assert.Equal(t, 500*time.Millisecond, p.reserve(later))
+}
+
+func TestPacerWaitCancelled(t *testing.T) {
+	p := &pacerT{rate: 1, burst: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := p.wait(ctx, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the token wasn't used so it's given back
+	assert.Equal(t, float64(0), p.tokens)
+
+	var off *pacerT
+
+	waited, err := off.wait(ctx, 0)
+	assert.NoError(t, err)
+	assert.Zero(t, waited)
+}
+
+func TestSetTargetRequestRate(t *testing.T) {
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+
+	fake.SetTargetRequestRate(50, 1)
+
+	started := time.Now()
+
+	for n := 0; n < 4; n++ {
+		_, err := fake.Get("/data/member/info")
+		assert.NoError(t, err)
+	}
+
+	// the first goes right away, the other 3 wait 20ms each
+	assert.GreaterOrEqual(t, time.Since(started), 55*time.Millisecond)
+
+	fake.SetTargetRequestRate(0, 0)
+	assert.Nil(t, fake.pacer)
+}
@@ -0,0 +1,84 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// how many uris Prefetch fetches at once
+const _prefetchWorkers = 4
+
+// Prefetch warms the cache: each of uris that isn't cached (or has expired)
+// is fetched and cached for ttl, as GetWithCache would.  Requests are subject
+// to the usual pacing, rate limit, and concurrency settings and, unless ctx
+// (see PrefetchCtx) says otherwise, are PriorityLow (see WithPriority).  If
+// some uris can't be fetched the rest still are and a *PartialError lists
+// the failures.
+func (i *Irdata) Prefetch(uris []string, ttl time.Duration) error {
+	return i.PrefetchCtx(context.Background(), uris, ttl)
+}
+
+// PrefetchCtx is Prefetch with a context, once it's done the remaining uris
+// aren't fetched
+func (i *Irdata) PrefetchCtx(ctx context.Context, uris []string, ttl time.Duration) error {
+	if i.cask == nil {
+		return makeErrorf("cache must be enabled")
+	}
+
+	if _, ok := ctx.Value(priorityKey{}).(Priority); !ok {
+		ctx = WithPriority(ctx, PriorityLow)
+	}
+
+	work := make(chan string)
+
+	var mu sync.Mutex
+
+	var failures []error
+
+	var wg sync.WaitGroup
+
+	for n := 0; n < _prefetchWorkers && n < len(uris); n++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for uri := range work {
+				if _, err := i.GetWithCacheCtx(ctx, uri, ttl); err != nil {
+					log.WithFields(logrus.Fields{"uri": uri, "err": err}).Warn("Unable to prefetch")
+
+					mu.Lock()
+					failures = append(failures, fmt.Errorf("%s: %w", uri, err))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, uri := range uris {
+		select {
+		case work <- uri:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(work)
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(failures) > 0 {
+		return &PartialError{Errors: failures}
+	}
+
+	return nil
+}
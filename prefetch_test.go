@@ -0,0 +1,87 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefetch(t *testing.T) {
+	var mu sync.Mutex
+
+	requests := map[string]int{}
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		if r.URL.Path == "/data/track/get" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprintf(w, `{"path":%q}`, r.URL.Path)
+	}))
+
+	assert.Error(t, fake.Prefetch([]string{"/data/car/get"}, time.Hour))
+
+	assert.NoError(t, fake.EnableCache(filepath.Join(t.TempDir(), "cache")))
+	t.Cleanup(fake.Close)
+
+	uris := []string{"/data/car/get", "/data/carclass/get", "/data/series/get", "/data/season/list", "/data/constants/divisions"}
+
+	assert.NoError(t, fake.Prefetch(uris, time.Hour))
+
+	for _, uri := range uris {
+		assert.Equal(t, 1, requests[uri], uri)
+	}
+
+	// already cached
+	assert.NoError(t, fake.Prefetch(uris, time.Hour))
+
+	for _, uri := range uris {
+		assert.Equal(t, 1, requests[uri], uri)
+
+		data, err := fake.GetWithCache(uri, time.Hour)
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, fmt.Sprintf(`{"path":%q}`, uri), string(data))
+	}
+
+	// failures don't stop the rest
+	fake.SetRetryPolicy(BackoffRetryPolicy{MaxAttempts: 1})
+
+	err := fake.Prefetch([]string{"/data/track/get", "/data/lookup/countries"}, time.Hour)
+
+	var partialErr *PartialError
+
+	if assert.True(t, errors.As(err, &partialErr)) {
+		assert.Len(t, partialErr.Errors, 1)
+	}
+
+	assert.Equal(t, 1, requests["/data/lookup/countries"])
+}
+
+func TestPrefetchCancelled(t *testing.T) {
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+
+	assert.NoError(t, fake.EnableCache(filepath.Join(t.TempDir(), "cache")))
+	t.Cleanup(fake.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := fake.PrefetchCtx(ctx, []string{"/data/car/get", "/data/track/get"}, time.Hour)
+
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -0,0 +1,76 @@
+package irdata
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Priority tells irdata how urgent a request is, see WithPriority
+type Priority int
+
+const (
+	// PriorityLow is for work that can wait, e.g. refreshing a cache
+	PriorityLow Priority = iota - 1
+	// PriorityNormal is the priority of requests that don't say
+	PriorityNormal
+	// PriorityHigh is for interactive requests, someone is waiting
+	PriorityHigh
+)
+
+type priorityKey struct{}
+
+// WithPriority returns ctx carrying priority for the Gets made with it (see
+// GetCtx), see SetLowPriorityThreshold
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityFrom(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+
+	return PriorityNormal
+}
+
+// SetLowPriorityThreshold defers PriorityLow requests (see WithPriority)
+// until the rate limit resets whenever fewer than remaining requests are left
+// in it (see RateLimitStatus), keeping the rest of the budget for requests
+// that matter more.  The wait is subject to SetMaxRateLimitWait.  Zero (the
+// default) never defers them.
+func (i *Irdata) SetLowPriorityThreshold(remaining int) {
+	i.lowPriorityThreshold = remaining
+}
+
+// deferLowPriority waits for the rate limit to reset if ctx's request is low
+// priority and the rate limit is running low
+func (i *Irdata) deferLowPriority(ctx context.Context) error {
+	if i.lowPriorityThreshold <= 0 || priorityFrom(ctx) >= PriorityNormal {
+		return nil
+	}
+
+	status, ok := i.RateLimitStatus()
+
+	now := time.Now()
+
+	if !ok || status.Remaining >= i.lowPriorityThreshold || !now.Before(status.Reset) {
+		return nil
+	}
+
+	wait := status.Reset.Sub(now)
+
+	if i.maxRateLimitWait > 0 && wait > i.maxRateLimitWait {
+		return &RateLimitExceededError{Wait: wait, MaxWait: i.maxRateLimitWait, Reset: status.Reset}
+	}
+
+	i.rateLimited(wait)
+
+	log.WithFields(logrus.Fields{
+		"remaining": status.Remaining,
+		"wait":      wait,
+	}).Info("Rate limit running low, deferring low priority request")
+
+	return sleepCtx(ctx, wait)
+}
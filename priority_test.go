@@ -0,0 +1,61 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLowPriorityThreshold(t *testing.T) {
+	var reset time.Time
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Remaining", "2")
+		w.Header().Set("X-Ratelimit-Reset", fmt.Sprint(reset.Unix()))
+
+		fmt.Fprint(w, `{}`)
+	}))
+
+	reset = time.Now().Add(time.Hour)
+
+	fake.SetLowPriorityThreshold(5)
+	fake.SetMaxRateLimitWait(time.Minute)
+
+	low := WithPriority(context.Background(), PriorityLow)
+
+	// the rate limit isn't known yet
+	_, err := fake.GetCtx(low, "/data/member/info")
+	assert.NoError(t, err)
+
+	// only 2 left, normal and high priority requests go ahead
+	_, err = fake.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	_, err = fake.GetCtx(WithPriority(context.Background(), PriorityHigh), "/data/member/info")
+	assert.NoError(t, err)
+
+	// low priority ones wait for the reset
+	_, err = fake.GetCtx(low, "/data/member/info")
+
+	var rateLimitErr *RateLimitExceededError
+
+	assert.True(t, errors.As(err, &rateLimitErr))
+
+	ctx, cancel := context.WithTimeout(low, 20*time.Millisecond)
+	defer cancel()
+
+	fake.SetMaxRateLimitWait(0)
+
+	_, err = fake.GetCtx(ctx, "/data/member/info")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPriorityFrom(t *testing.T) {
+	assert.Equal(t, PriorityNormal, priorityFrom(context.Background()))
+	assert.Equal(t, PriorityLow, priorityFrom(WithPriority(context.Background(), PriorityLow)))
+}
@@ -0,0 +1,283 @@
+package irdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// chart_type for /data/member/chart_data
+	_chartTypeiRating = 1
+
+	// official races needed in a season for a license promotion
+	MPRRaces = 4
+
+	// the safety rating needed for a promotion at the end of the season and
+	// for an immediate (fast track) promotion
+	PromotionSR = 3.0
+	FastTrackSR = 4.0
+)
+
+// Trend is a least squares line through a series of values, one per race (or
+// chart point).  Projected is where the line ends up after the number of
+// races asked for.
+type Trend struct {
+	Samples   []float64
+	Slope     float64 // change per race
+	Intercept float64
+	Current   float64
+	Projected float64
+}
+
+// Progression projects a member's iRating and safety rating in a category
+type Progression struct {
+	CustID     int64
+	CategoryID int
+	Races      int // races projected ahead
+
+	IRating      Trend
+	SafetyRating Trend // in SR units, e.g. 3.49
+
+	// official races in the latest season and how many more are needed for
+	// the minimum participation requirement
+	SeasonID    int64
+	SeasonRaces int
+	RacesForMPR int
+
+	// races needed at the current SR trend to reach PromotionSR and
+	// FastTrackSR, 0 if already there and -1 if the trend isn't heading
+	// there
+	RacesToPromotionSR int
+	RacesToFastTrackSR int
+}
+
+type chartDataT struct {
+	Data []struct {
+		When  string  `json:"when"`
+		Value float64 `json:"value"`
+	} `json:"data"`
+}
+
+type recentRaceT struct {
+	SeasonID         int64     `json:"season_id"`
+	SeriesID         int64     `json:"series_id"`
+	SessionStartTime time.Time `json:"session_start_time"`
+	OldiRating       int       `json:"oldi_rating"`
+	NewiRating       int       `json:"newi_rating"`
+	OldSubLevel      int       `json:"old_sub_level"`
+	NewSubLevel      int       `json:"new_sub_level"`
+
+	// set from the season, see ProjectProgression
+	official bool
+}
+
+type seriesCategoryT struct {
+	SeriesID   int64 `json:"series_id"`
+	CategoryID int   `json:"category_id"`
+}
+
+type seasonOfficialT struct {
+	SeasonID int64 `json:"season_id"`
+	Official bool  `json:"official"`
+}
+
+// ProjectProgression combines the member's iRating chart and recent races to
+// project where their iRating and safety rating will be after races more
+// races, and how far they are from the participation and safety rating
+// requirements for their next license.  Only the recent races in series of
+// categoryID are used (safety rating is tracked per category) and only
+// official ones count toward the participation requirement.
+func (i *Irdata) ProjectProgression(custID int64, categoryID int, races int) (Progression, error) {
+	chartData, err := i.Get(fmt.Sprintf("/data/member/chart_data?cust_id=%d&category_id=%d&chart_type=%d", custID, categoryID, _chartTypeiRating))
+	if err != nil {
+		return Progression{}, err
+	}
+
+	var chart chartDataT
+
+	if err := json.Unmarshal(chartData, &chart); err != nil {
+		return Progression{}, makeErrorf("unable to decode chart data [%v]", err)
+	}
+
+	recentData, err := i.Get(fmt.Sprintf("/data/stats/member_recent_races?cust_id=%d", custID))
+	if err != nil {
+		return Progression{}, err
+	}
+
+	var recent struct {
+		Races []recentRaceT `json:"races"`
+	}
+
+	if err := json.Unmarshal(recentData, &recent); err != nil {
+		return Progression{}, makeErrorf("unable to decode recent races [%v]", err)
+	}
+
+	categoryRaces, err := i.categoryRaces(recent.Races, categoryID)
+	if err != nil {
+		return Progression{}, err
+	}
+
+	iRatings := make([]float64, len(chart.Data))
+
+	for n, point := range chart.Data {
+		iRatings[n] = point.Value
+	}
+
+	progression := projectProgression(iRatings, categoryRaces, races)
+
+	progression.CustID = custID
+	progression.CategoryID = categoryID
+
+	return progression, nil
+}
+
+// categoryRaces returns the races in series of categoryID, marked official if
+// their season is (races in seasons that aren't current are taken as
+// unofficial, they don't count toward this season's participation anyway)
+func (i *Irdata) categoryRaces(races []recentRaceT, categoryID int) ([]recentRaceT, error) {
+	seriesData, err := i.Get("/data/series/get")
+	if err != nil {
+		return nil, err
+	}
+
+	var series []seriesCategoryT
+
+	if err := json.Unmarshal(seriesData, &series); err != nil {
+		return nil, makeErrorf("unable to decode series [%v]", err)
+	}
+
+	seasonsData, err := i.Get("/data/series/seasons")
+	if err != nil {
+		return nil, err
+	}
+
+	var seasons []seasonOfficialT
+
+	if err := json.Unmarshal(seasonsData, &seasons); err != nil {
+		return nil, makeErrorf("unable to decode seasons [%v]", err)
+	}
+
+	categories := map[int64]int{}
+
+	for _, s := range series {
+		categories[s.SeriesID] = s.CategoryID
+	}
+
+	official := map[int64]bool{}
+
+	for _, season := range seasons {
+		official[season.SeasonID] = season.Official
+	}
+
+	var filtered []recentRaceT
+
+	for _, race := range races {
+		if categories[race.SeriesID] != categoryID {
+			continue
+		}
+
+		race.official = official[race.SeasonID]
+
+		filtered = append(filtered, race)
+	}
+
+	return filtered, nil
+}
+
+func projectProgression(iRatings []float64, races []recentRaceT, ahead int) Progression {
+	progression := Progression{Races: ahead}
+
+	sorted := append([]recentRaceT{}, races...)
+
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].SessionStartTime.Before(sorted[b].SessionStartTime) })
+
+	// fall back to the races if there's no chart
+	if len(iRatings) == 0 {
+		for _, race := range sorted {
+			iRatings = append(iRatings, float64(race.NewiRating))
+		}
+	}
+
+	progression.IRating = makeTrend(iRatings, ahead)
+
+	var srs []float64
+
+	for _, race := range sorted {
+		srs = append(srs, float64(race.NewSubLevel)/100)
+	}
+
+	progression.SafetyRating = makeTrend(srs, ahead)
+
+	if len(sorted) > 0 {
+		progression.SeasonID = sorted[len(sorted)-1].SeasonID
+
+		for _, race := range sorted {
+			if race.SeasonID == progression.SeasonID && race.official {
+				progression.SeasonRaces++
+			}
+		}
+	}
+
+	if progression.SeasonRaces < MPRRaces {
+		progression.RacesForMPR = MPRRaces - progression.SeasonRaces
+	}
+
+	progression.RacesToPromotionSR = racesToReach(progression.SafetyRating, PromotionSR)
+	progression.RacesToFastTrackSR = racesToReach(progression.SafetyRating, FastTrackSR)
+
+	return progression
+}
+
+// makeTrend fits a line through samples (x is the sample index)
+func makeTrend(samples []float64, ahead int) Trend {
+	trend := Trend{Samples: samples}
+
+	n := float64(len(samples))
+
+	if n == 0 {
+		return trend
+	}
+
+	trend.Current = samples[len(samples)-1]
+
+	if n == 1 {
+		trend.Intercept = samples[0]
+		trend.Projected = trend.Current
+		return trend
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+
+	for x, y := range samples {
+		sumX += float64(x)
+		sumY += y
+		sumXY += float64(x) * y
+		sumXX += float64(x) * float64(x)
+	}
+
+	trend.Slope = (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	trend.Intercept = (sumY - trend.Slope*sumX) / n
+	trend.Projected = trend.Current + trend.Slope*float64(ahead)
+
+	return trend
+}
+
+func racesToReach(trend Trend, target float64) int {
+	if len(trend.Samples) == 0 {
+		return -1
+	}
+
+	if trend.Current >= target {
+		return 0
+	}
+
+	if trend.Slope <= 0 {
+		return -1
+	}
+
+	// allow for floating point error so e.g. 0.2 / 0.2 isn't rounded up to 2
+	return int(math.Ceil((target-trend.Current)/trend.Slope - 1e-9))
+}
@@ -0,0 +1,84 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeTrend(t *testing.T) {
+	trend := makeTrend([]float64{1000, 1010, 1020, 1030}, 5)
+
+	assert.InDelta(t, 10, trend.Slope, 1e-9)
+	assert.InDelta(t, 1000, trend.Intercept, 1e-9)
+	assert.Equal(t, 1030.0, trend.Current)
+	assert.InDelta(t, 1080, trend.Projected, 1e-9)
+
+	assert.Equal(t, Trend{}, makeTrend(nil, 5))
+	assert.Equal(t, 7.0, makeTrend([]float64{7}, 5).Projected)
+}
+
+func TestRacesToReach(t *testing.T) {
+	assert.Equal(t, 0, racesToReach(Trend{Samples: []float64{3.2}, Current: 3.2}, 3.0))
+	assert.Equal(t, 3, racesToReach(Trend{Samples: []float64{2.8}, Current: 2.8, Slope: 0.08}, 3.0))
+	assert.Equal(t, -1, racesToReach(Trend{Samples: []float64{2.8}, Current: 2.8, Slope: -0.1}, 3.0))
+	assert.Equal(t, -1, racesToReach(Trend{}, 3.0))
+}
+
+func TestProjectProgression(t *testing.T) {
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/member/chart_data":
+			assert.Equal(t, "1", r.URL.Query().Get("chart_type"))
+			fmt.Fprint(w, `{"data":[{"when":"2024-01-01","value":1400},{"when":"2024-01-02","value":1450},{"when":"2024-01-03","value":1500}]}`)
+		case "/data/stats/member_recent_races":
+			// most recent first, as the API returns them, with oval races
+			// (series 20) mixed in
+			fmt.Fprint(w, `{"races":[
+				{"season_id":5,"series_id":20,"session_start_time":"2024-01-04T00:00:00Z","new_sub_level":120},
+				{"season_id":2,"series_id":10,"session_start_time":"2024-01-03T00:00:00Z","new_sub_level":280},
+				{"season_id":2,"series_id":10,"session_start_time":"2024-01-02T00:00:00Z","new_sub_level":260},
+				{"season_id":3,"series_id":11,"session_start_time":"2024-01-02T12:00:00Z","new_sub_level":270},
+				{"season_id":5,"series_id":20,"session_start_time":"2024-01-01T12:00:00Z","new_sub_level":110},
+				{"season_id":1,"series_id":10,"session_start_time":"2024-01-01T00:00:00Z","new_sub_level":240}
+			]}`)
+		case "/data/series/get":
+			fmt.Fprint(w, `[{"series_id":10,"category_id":2},{"series_id":11,"category_id":2},{"series_id":20,"category_id":1}]`)
+		case "/data/series/seasons":
+			// season 3 is an unofficial season of another road series
+			fmt.Fprint(w, `[{"season_id":2,"official":true},{"season_id":3,"official":false},{"season_id":5,"official":true}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	progression, err := fake.ProjectProgression(42, 2, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), progression.CustID)
+	assert.InDelta(t, 50, progression.IRating.Slope, 1e-9)
+	assert.InDelta(t, 1650, progression.IRating.Projected, 1e-9)
+
+	// only the road races
+	assert.Equal(t, []float64{2.4, 2.6, 2.7, 2.8}, progression.SafetyRating.Samples)
+	assert.InDelta(t, 0.13, progression.SafetyRating.Slope, 1e-9)
+
+	assert.Equal(t, int64(2), progression.SeasonID)
+	assert.Equal(t, 2, progression.SeasonRaces)
+	assert.Equal(t, 2, progression.RacesForMPR)
+	assert.Equal(t, 2, progression.RacesToPromotionSR)
+	assert.Equal(t, 10, progression.RacesToFastTrackSR)
+}
+
+func TestProjectProgressionWithoutChart(t *testing.T) {
+	progression := projectProgression(nil, []recentRaceT{
+		{SessionStartTime: time.Unix(1, 0), NewiRating: 1000, official: true},
+		{SessionStartTime: time.Unix(2, 0), NewiRating: 990, official: true},
+	}, 1)
+
+	assert.Equal(t, []float64{1000, 990}, progression.IRating.Samples)
+	assert.Equal(t, MPRRaces-2, progression.RacesForMPR)
+}
@@ -0,0 +1,45 @@
+package irdata
+
+import (
+	"context"
+)
+
+// PublishSubjectPrefix is prepended to the NotifierEventType to form the
+// subject (or topic) events are published to, e.g. irdata.new_subsession
+const PublishSubjectPrefix = "irdata."
+
+// Publisher sends events to a message bus.  irdata doesn't depend on any bus
+// client, wrap yours with PublisherFunc, e.g. for NATS:
+//
+//	irdata.PublisherFunc(func(ctx context.Context, subject string, payload []byte) error {
+//		return nc.Publish(subject, payload)
+//	})
+//
+// or for Kafka (segmentio/kafka-go):
+//
+//	irdata.PublisherFunc(func(ctx context.Context, subject string, payload []byte) error {
+//		return w.WriteMessages(ctx, kafka.Message{Topic: subject, Value: payload})
+//	})
+type Publisher interface {
+	Publish(ctx context.Context, subject string, payload []byte) error
+}
+
+// PublisherFunc adapts a function to a Publisher
+type PublisherFunc func(ctx context.Context, subject string, payload []byte) error
+
+func (f PublisherFunc) Publish(ctx context.Context, subject string, payload []byte) error {
+	return f(ctx, subject, payload)
+}
+
+// AddPublisher registers a Publisher that is sent every event as JSON along
+// with the webhooks
+func (n *Notifier) AddPublisher(publisher Publisher) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.publishers = append(n.publishers, publisher)
+}
+
+func publishSubject(eventType NotifierEventType) string {
+	return PublishSubjectPrefix + string(eventType)
+}
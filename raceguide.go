@@ -0,0 +1,164 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// RaceGuideEventType is the type of a RaceGuideEvent
+type RaceGuideEventType string
+
+const (
+	// a session showed up in the race guide
+	RaceGuideSessionAdded RaceGuideEventType = "session_added"
+	// the number of registered entries changed
+	RaceGuideEntryCountChanged RaceGuideEventType = "entry_count_changed"
+	// the session's start time passed
+	RaceGuideSessionStarted RaceGuideEventType = "session_started"
+)
+
+// RaceGuideSession is a session in the race guide
+type RaceGuideSession struct {
+	SeasonID    int64     `json:"season_id"`
+	SeriesID    int64     `json:"series_id"`
+	SessionID   int64     `json:"session_id"`
+	RaceWeekNum int       `json:"race_week_num"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	EntryCount  int       `json:"entry_count"`
+}
+
+// RaceGuideEvent is a change between two polls of the race guide.
+// PreviousEntryCount is only set for RaceGuideEntryCountChanged.
+type RaceGuideEvent struct {
+	Type               RaceGuideEventType
+	Session            RaceGuideSession
+	PreviousEntryCount int
+	At                 time.Time
+}
+
+// sessions are identified by their season and start time since upcoming
+// sessions don't always have a session id yet
+type raceGuideKeyT struct {
+	seasonID  int64
+	startTime int64
+}
+
+func raceGuideKey(session RaceGuideSession) raceGuideKeyT {
+	return raceGuideKeyT{session.SeasonID, session.StartTime.Unix()}
+}
+
+// RaceGuideStream polls the race guide every interval and sends the changes
+// between consecutive polls on the returned channel, which is closed once
+// ctx is done.  The first poll only records the sessions that already exist.
+// Failed polls are logged and retried at the next interval.
+func (i *Irdata) RaceGuideStream(ctx context.Context, interval time.Duration) <-chan RaceGuideEvent {
+	events := make(chan RaceGuideEvent)
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var previous map[raceGuideKeyT]RaceGuideSession
+
+		started := map[raceGuideKeyT]bool{}
+
+		for {
+			sessions, err := i.raceGuideSessions()
+			if err != nil {
+				log.WithField("err", err).Warn("Race guide poll failed")
+			} else {
+				var changes []RaceGuideEvent
+
+				changes, previous = diffRaceGuide(previous, sessions, started, time.Now().UTC())
+
+				for _, change := range changes {
+					select {
+					case events <- change:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events
+}
+
+func (i *Irdata) raceGuideSessions() ([]RaceGuideSession, error) {
+	data, err := i.Get("/data/season/race_guide")
+	if err != nil {
+		return nil, err
+	}
+
+	var raceGuide struct {
+		Sessions []RaceGuideSession `json:"sessions"`
+	}
+
+	if err := json.Unmarshal(data, &raceGuide); err != nil {
+		return nil, makeErrorf("unable to decode race guide [%v]", err)
+	}
+
+	return raceGuide.Sessions, nil
+}
+
+// diffRaceGuide returns the events between previous and sessions and the
+// snapshot to diff the next poll against.  started tracks the sessions
+// already reported as started.  If previous is nil nothing but the snapshot
+// is returned.
+func diffRaceGuide(previous map[raceGuideKeyT]RaceGuideSession, sessions []RaceGuideSession, started map[raceGuideKeyT]bool, now time.Time) ([]RaceGuideEvent, map[raceGuideKeyT]RaceGuideSession) {
+	current := make(map[raceGuideKeyT]RaceGuideSession, len(sessions))
+
+	var events []RaceGuideEvent
+
+	for _, session := range sessions {
+		key := raceGuideKey(session)
+
+		current[key] = session
+
+		hasStarted := !session.StartTime.After(now)
+
+		if previous == nil {
+			started[key] = hasStarted
+			continue
+		}
+
+		before, seen := previous[key]
+
+		if !seen {
+			events = append(events, RaceGuideEvent{Type: RaceGuideSessionAdded, Session: session, At: now})
+		} else if before.EntryCount != session.EntryCount {
+			events = append(events, RaceGuideEvent{
+				Type:               RaceGuideEntryCountChanged,
+				Session:            session,
+				PreviousEntryCount: before.EntryCount,
+				At:                 now,
+			})
+		}
+
+		if hasStarted && !started[key] {
+			started[key] = true
+
+			events = append(events, RaceGuideEvent{Type: RaceGuideSessionStarted, Session: session, At: now})
+		}
+	}
+
+	// forget sessions that have dropped out of the guide
+	for key := range started {
+		if _, ok := current[key]; !ok {
+			delete(started, key)
+		}
+	}
+
+	return events, current
+}
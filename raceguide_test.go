@@ -0,0 +1,72 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRaceGuide(t *testing.T) {
+	now := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	a := RaceGuideSession{SeasonID: 1, StartTime: now.Add(10 * time.Minute), EntryCount: 5}
+	b := RaceGuideSession{SeasonID: 2, StartTime: now.Add(-5 * time.Minute), EntryCount: 9}
+
+	started := map[raceGuideKeyT]bool{}
+
+	// first snapshot only primes
+	events, snapshot := diffRaceGuide(nil, []RaceGuideSession{a, b}, started, now)
+	assert.Empty(t, events)
+	assert.Len(t, snapshot, 2)
+
+	c := RaceGuideSession{SeasonID: 3, StartTime: now.Add(time.Hour)}
+	a.EntryCount = 7
+
+	events, snapshot = diffRaceGuide(snapshot, []RaceGuideSession{a, b, c}, started, now)
+
+	assert.Equal(t, []RaceGuideEvent{
+		{Type: RaceGuideEntryCountChanged, Session: a, PreviousEntryCount: 5, At: now},
+		{Type: RaceGuideSessionAdded, Session: c, At: now},
+	}, events)
+
+	later := now.Add(15 * time.Minute)
+
+	events, _ = diffRaceGuide(snapshot, []RaceGuideSession{a, c}, started, later)
+
+	assert.Equal(t, []RaceGuideEvent{
+		{Type: RaceGuideSessionStarted, Session: a, At: later},
+	}, events)
+
+	// b dropped out of the guide
+	assert.NotContains(t, started, raceGuideKey(b))
+}
+
+func TestRaceGuideStream(t *testing.T) {
+	polls := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+
+		fmt.Fprintf(w, `{"sessions":[{"season_id":1,"start_time":"2099-01-01T00:00:00Z","entry_count":%d}]}`, polls)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := fake.RaceGuideStream(ctx, time.Millisecond)
+
+	event := <-events
+
+	assert.Equal(t, RaceGuideEntryCountChanged, event.Type)
+	assert.Equal(t, 1, event.PreviousEntryCount)
+	assert.Equal(t, 2, event.Session.EntryCount)
+
+	cancel()
+
+	for range events {
+	}
+}
@@ -0,0 +1,138 @@
+package irdata
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is the /data API's rate limit as reported by the x-ratelimit-*
+// headers of a response
+type RateLimit struct {
+	Limit     int       // requests allowed per window, zero if not reported
+	Remaining int       // requests left in the current window
+	Reset     time.Time // when the window resets, zero if not reported
+	Updated   time.Time // when the response was received
+}
+
+type rateLimitStateT struct {
+	mu       sync.Mutex
+	status   RateLimit
+	seen     bool
+	callback func(remaining int, reset time.Time, waited time.Duration)
+
+	// irdata's own, e.g. see NotifyRateLimits
+	observers []func(remaining int, reset time.Time, waited time.Duration)
+}
+
+// RateLimitStatus returns the rate limit reported by the last /data response
+// that reported one, ok is false until one has
+func (i *Irdata) RateLimitStatus() (status RateLimit, ok bool) {
+	i.rateLimit.mu.Lock()
+	defer i.rateLimit.mu.Unlock()
+
+	return i.rateLimit.status, i.rateLimit.seen
+}
+
+// recordRateLimit keeps the rate limit reported by resp, if it reports one
+func (i *Irdata) recordRateLimit(resp *http.Response, now time.Time) {
+	status, ok := parseRateLimit(resp.Header, now)
+	if !ok {
+		return
+	}
+
+	i.rateLimit.mu.Lock()
+	i.rateLimit.status = status
+	i.rateLimit.seen = true
+	i.rateLimit.mu.Unlock()
+
+	i.shareRateLimit(status)
+}
+
+// parseRateLimit reads the x-ratelimit-* headers, ok is false when there's
+// no x-ratelimit-remaining
+func parseRateLimit(header http.Header, now time.Time) (status RateLimit, ok bool) {
+	remaining, err := strconv.Atoi(header.Get("X-Ratelimit-Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	status = RateLimit{Remaining: remaining, Updated: now}
+
+	if limit, err := strconv.Atoi(header.Get("X-Ratelimit-Limit")); err == nil {
+		status.Limit = limit
+	}
+
+	if reset, err := strconv.ParseInt(header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+		status.Reset = time.Unix(reset, 0)
+	}
+
+	return status, true
+}
+
+// SetRateLimitCallback sets a func that is called whenever a /data request
+// is held back by the rate limit: when SetTargetRequestRate's pacing made it
+// wait (waited is how long it waited) and when iRacing answered it with a 429
+// (waited is how long iRacing asked to wait, zero if it didn't say).
+// remaining and reset are the last reported, see RateLimitStatus.  The
+// callback runs on the requesting goroutine so it should be quick.  nil (the
+// default) turns it off.
+func (i *Irdata) SetRateLimitCallback(callback func(remaining int, reset time.Time, waited time.Duration)) {
+	i.rateLimit.mu.Lock()
+	defer i.rateLimit.mu.Unlock()
+
+	i.rateLimit.callback = callback
+}
+
+// observeRateLimits adds observer to the funcs called alongside the callback
+func (i *Irdata) observeRateLimits(observer func(remaining int, reset time.Time, waited time.Duration)) {
+	i.rateLimit.mu.Lock()
+	defer i.rateLimit.mu.Unlock()
+
+	i.rateLimit.observers = append(i.rateLimit.observers, observer)
+}
+
+// rateLimited tells the callback (if any) and the observers a request was
+// held back
+func (i *Irdata) rateLimited(waited time.Duration) {
+	i.rateLimit.mu.Lock()
+	callback := i.rateLimit.callback
+	observers := append([]func(int, time.Time, time.Duration){}, i.rateLimit.observers...)
+	status := i.rateLimit.status
+	i.rateLimit.mu.Unlock()
+
+	for _, observer := range observers {
+		observer(status.Remaining, status.Reset, waited)
+	}
+
+	if callback == nil {
+		return
+	}
+
+	callback(status.Remaining, status.Reset, waited)
+}
+
+// SetMaxRateLimitWait limits how long a /data request may be held back by the
+// rate limit, either by SetTargetRequestRate's pacing or by iRacing asking
+// (in a 429) to wait before retrying.  Rather than wait longer the request
+// fails with a *RateLimitExceededError.  Zero (the default) is no limit.
+// Waits are always cut short when the request's context is done.
+func (i *Irdata) SetMaxRateLimitWait(maxWait time.Duration) {
+	i.maxRateLimitWait = maxWait
+}
+
+// rateLimitError fills in when the rate limit resets if err is a
+// *RateLimitExceededError that doesn't know
+func (i *Irdata) rateLimitError(err error) error {
+	var rateLimitErr *RateLimitExceededError
+
+	if errors.As(err, &rateLimitErr) && rateLimitErr.Reset.IsZero() {
+		status, _ := i.RateLimitStatus()
+
+		rateLimitErr.Reset = status.Reset
+	}
+
+	return err
+}
@@ -0,0 +1,176 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitStatus(t *testing.T) {
+	var linkBase string
+
+	remaining := 10
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/s3/info" {
+			// s3 doesn't report a rate limit, or reports another one
+			w.Header().Set("X-Ratelimit-Remaining", "9999")
+			fmt.Fprint(w, `{}`)
+			return
+		}
+
+		remaining--
+
+		w.Header().Set("X-Ratelimit-Limit", "240")
+		w.Header().Set("X-Ratelimit-Remaining", fmt.Sprint(remaining))
+		w.Header().Set("X-Ratelimit-Reset", "1717243200")
+
+		fmt.Fprintf(w, `{"link":"%s/s3/info"}`, linkBase)
+	}))
+
+	linkBase = fake.baseURL.String()
+
+	_, ok := fake.RateLimitStatus()
+	assert.False(t, ok)
+
+	before := time.Now()
+
+	_, err := fake.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	_, err = fake.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	status, ok := fake.RateLimitStatus()
+
+	if assert.True(t, ok) {
+		assert.Equal(t, 240, status.Limit)
+		assert.Equal(t, 8, status.Remaining)
+		assert.Equal(t, time.Unix(1717243200, 0), status.Reset)
+		assert.False(t, status.Updated.Before(before))
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Now()
+
+	_, ok := parseRateLimit(http.Header{}, now)
+	assert.False(t, ok)
+
+	status, ok := parseRateLimit(http.Header{"X-Ratelimit-Remaining": {"5"}}, now)
+
+	assert.True(t, ok)
+	assert.Equal(t, RateLimit{Remaining: 5, Updated: now}, status)
+}
+
+func TestRateLimitCallback(t *testing.T) {
+	setupRetryTest(t)
+
+	requests := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("X-Ratelimit-Reset", "1717243200")
+
+		if requests == 1 {
+			w.Header().Set("X-Ratelimit-Remaining", "0")
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+
+		w.Header().Set("X-Ratelimit-Remaining", "239")
+		fmt.Fprint(w, `{}`)
+	}))
+
+	type callT struct {
+		remaining int
+		reset     time.Time
+		waited    time.Duration
+	}
+
+	var calls []callT
+
+	fake.SetRateLimitCallback(func(remaining int, reset time.Time, waited time.Duration) {
+		calls = append(calls, callT{remaining, reset, waited})
+	})
+
+	// a 429
+	_, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, callT{0, time.Unix(1717243200, 0), 0}, calls[0])
+	}
+
+	// pacing
+	fake.SetTargetRequestRate(100, 1)
+
+	fake.Get("/data/member/info")
+	fake.Get("/data/member/info")
+
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, 239, calls[1].remaining)
+		assert.Greater(t, calls[1].waited, time.Duration(0))
+	}
+}
+
+func TestMaxRateLimitWait(t *testing.T) {
+	requests := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("X-Ratelimit-Remaining", "0")
+		w.Header().Set("X-Ratelimit-Reset", "1717243200")
+		w.Header().Set("Retry-After", "600")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+
+	fake.SetMaxRateLimitWait(time.Minute)
+
+	started := time.Now()
+
+	_, err := fake.Get("/data/member/info")
+
+	var rateLimitErr *RateLimitExceededError
+
+	if assert.True(t, errors.As(err, &rateLimitErr)) {
+		assert.Equal(t, 10*time.Minute, rateLimitErr.Wait)
+		assert.Equal(t, time.Minute, rateLimitErr.MaxWait)
+		assert.Equal(t, time.Unix(1717243200, 0), rateLimitErr.Reset)
+	}
+
+	assert.Equal(t, 1, requests)
+	assert.Less(t, time.Since(started), time.Second)
+
+	// pacing is capped too
+	fake.SetTargetRequestRate(0.01, 1)
+	fake.pacer.reserve(time.Now())
+
+	_, err = fake.Get("/data/member/info")
+
+	assert.True(t, errors.As(err, &rateLimitErr))
+	assert.Equal(t, 1, requests)
+}
+
+func TestRateLimitWaitCancelled(t *testing.T) {
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "600")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := fake.GetCtx(ctx, "/data/member/info")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
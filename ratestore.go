@@ -0,0 +1,152 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimitStore holds the rate limit of an account shared by several
+// processes, so that each takes its requests out of the same budget rather
+// than finding out the limit has been reached from a 429.  See
+// SetRateLimitStore.
+//
+// FileRateLimitStore works for processes on one machine.  For processes
+// spread over several, implement Update with e.g. a Redis transaction.
+type RateLimitStore interface {
+	// Update atomically replaces the stored rate limit with what update
+	// returns.  ok is false when nothing has been stored yet.
+	Update(update func(status RateLimit, ok bool) RateLimit) error
+}
+
+// SetRateLimitStore shares the rate limit through store: every /data request
+// takes one from the stored remaining count first, waiting for the reset
+// (see SetMaxRateLimitWait) when none are left, and the rate limit iRacing
+// reports is saved to it.  A store that fails is logged and ignored rather
+// than failing requests.  nil (the default) turns sharing off.
+func (i *Irdata) SetRateLimitStore(store RateLimitStore) {
+	i.rateLimitStore = store
+}
+
+// takeSharedRateLimit takes a request out of the shared rate limit, waiting
+// for it to reset if there are none left
+func (i *Irdata) takeSharedRateLimit(ctx context.Context) error {
+	if i.rateLimitStore == nil {
+		return nil
+	}
+
+	for {
+		var wait time.Duration
+
+		var reset time.Time
+
+		now := time.Now()
+
+		err := i.rateLimitStore.Update(func(status RateLimit, ok bool) RateLimit {
+			// nothing known, or the window has reset since
+			if !ok || (!status.Reset.IsZero() && !now.Before(status.Reset)) {
+				return status
+			}
+
+			if status.Remaining <= 0 {
+				if !status.Reset.IsZero() {
+					wait = status.Reset.Sub(now)
+					reset = status.Reset
+				}
+
+				return status
+			}
+
+			status.Remaining--
+
+			return status
+		})
+
+		if err != nil {
+			log.WithFields(logrus.Fields{"err": err}).Warn("Unable to take from the shared rate limit")
+
+			return nil
+		}
+
+		if wait == 0 {
+			return nil
+		}
+
+		if i.maxRateLimitWait > 0 && wait > i.maxRateLimitWait {
+			return &RateLimitExceededError{Wait: wait, MaxWait: i.maxRateLimitWait, Reset: reset}
+		}
+
+		i.rateLimited(wait)
+
+		log.WithFields(logrus.Fields{"wait": wait}).Info("Shared rate limit used up, waiting for the reset")
+
+		if err := sleepCtx(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// shareRateLimit saves the rate limit iRacing reported to the store, unless
+// the store knows of more requests taken from the same window since
+func (i *Irdata) shareRateLimit(reported RateLimit) {
+	if i.rateLimitStore == nil {
+		return
+	}
+
+	err := i.rateLimitStore.Update(func(status RateLimit, ok bool) RateLimit {
+		if ok && status.Reset.Equal(reported.Reset) && status.Remaining < reported.Remaining {
+			return status
+		}
+
+		return reported
+	})
+
+	if err != nil {
+		log.WithFields(logrus.Fields{"err": err}).Warn("Unable to save the shared rate limit")
+	}
+}
+
+// FileRateLimitStore is a RateLimitStore kept in a file, locked while it's
+// updated, for processes on the same machine
+type FileRateLimitStore struct {
+	Path string
+}
+
+func (s FileRateLimitStore) Update(update func(status RateLimit, ok bool) RateLimit) error {
+	lock := flock.New(s.Path + ".lock")
+
+	if err := lock.Lock(); err != nil {
+		return makeErrorf("unable to lock %s [%v]", s.Path, err)
+	}
+
+	defer lock.Unlock()
+
+	var status RateLimit
+
+	ok := false
+
+	data, err := os.ReadFile(s.Path)
+
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &status); err != nil {
+			return makeErrorf("unable to decode %s [%v]", s.Path, err)
+		}
+
+		ok = true
+	case !errors.Is(err, os.ErrNotExist):
+		return makeErrorf("unable to read %s [%v]", s.Path, err)
+	}
+
+	data, err = json.Marshal(update(status, ok))
+	if err != nil {
+		return makeErrorf("unable to encode rate limit [%v]", err)
+	}
+
+	return writeFileAtomic(s.Path, data, 0600)
+}
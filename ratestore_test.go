@@ -0,0 +1,95 @@
+package irdata
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileRateLimitStore(t *testing.T) {
+	store := FileRateLimitStore{Path: filepath.Join(t.TempDir(), "ratelimit.json")}
+
+	reset := time.Unix(1717243200, 0)
+
+	err := store.Update(func(status RateLimit, ok bool) RateLimit {
+		assert.False(t, ok)
+
+		return RateLimit{Limit: 240, Remaining: 100, Reset: reset}
+	})
+
+	assert.NoError(t, err)
+
+	// updates from many goroutines (or processes) don't get lost
+	var wg sync.WaitGroup
+
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			assert.NoError(t, store.Update(func(status RateLimit, ok bool) RateLimit {
+				status.Remaining--
+				return status
+			}))
+		}()
+	}
+
+	wg.Wait()
+
+	store.Update(func(status RateLimit, ok bool) RateLimit {
+		assert.True(t, ok)
+		assert.Equal(t, 90, status.Remaining)
+		assert.True(t, reset.Equal(status.Reset))
+
+		return status
+	})
+}
+
+func TestSharedRateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	requests := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("X-Ratelimit-Remaining", "5")
+		w.Header().Set("X-Ratelimit-Reset", fmt.Sprint(reset.Unix()))
+
+		fmt.Fprint(w, `{}`)
+	}))
+
+	store := FileRateLimitStore{Path: filepath.Join(t.TempDir(), "ratelimit.json")}
+
+	fake.SetRateLimitStore(store)
+	fake.SetMaxRateLimitWait(time.Second)
+
+	_, err := fake.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	// another process used up the rest
+	store.Update(func(status RateLimit, ok bool) RateLimit {
+		assert.Equal(t, 5, status.Remaining)
+
+		status.Remaining = 0
+
+		return status
+	})
+
+	_, err = fake.Get("/data/member/info")
+
+	var rateLimitErr *RateLimitExceededError
+
+	if assert.True(t, errors.As(err, &rateLimitErr)) {
+		assert.True(t, reset.Equal(rateLimitErr.Reset))
+	}
+
+	assert.Equal(t, 1, requests)
+}
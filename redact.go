@@ -0,0 +1,61 @@
+package irdata
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// names of fields, query parameters, and json keys that hold secrets
+const sensitiveNames = `password|passwd|secret|token|authcode|cookie|authorization|signature|credential`
+
+// log fields whose name looks sensitive are redacted entirely
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)(` + sensitiveNames + `)`)
+
+// name=value, name: value, and "name":"value" pairs inside of strings (urls,
+// request bodies, error messages) have their value redacted
+var sensitiveValuePattern = regexp.MustCompile(`(?i)((?:` + sensitiveNames + `)[\w-]*["']?\s*[:=]\s*["']?)([^"'&\s,;}]+)`)
+
+// redactString scrubs anything that looks like a secret from s
+func redactString(s string) string {
+	return sensitiveValuePattern.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// redactValue scrubs a log field value, values that aren't strings (or
+// things that turn into strings) are left as is
+func redactValue(key string, value interface{}) interface{} {
+	if sensitiveKeyPattern.MatchString(key) {
+		return redacted
+	}
+
+	switch v := value.(type) {
+	case string:
+		return redactString(v)
+	case error:
+		return redactString(v.Error())
+	case fmt.Stringer:
+		return redactString(v.String())
+	default:
+		return value
+	}
+}
+
+// redactHook is a logrus hook that scrubs secrets from every log entry
+type redactHook struct{}
+
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (redactHook) Fire(entry *logrus.Entry) error {
+	for k, v := range entry.Data {
+		entry.Data[k] = redactValue(k, v)
+	}
+
+	entry.Message = redactString(entry.Message)
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package irdata
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactString(t *testing.T) {
+	for input, expected := range map[string]string{
+		`{"email": "louis@example.com", "password": "nKb060s95vcF0Rpj="}`:            `{"email": "louis@example.com", "password": "[REDACTED]"}`,
+		`https://s3/x.json?X-Amz-Credential=AKIA%2F1&X-Amz-Signature=abc123&foo=bar`: `https://s3/x.json?X-Amz-Credential=[REDACTED]&X-Amz-Signature=[REDACTED]&foo=bar`,
+		`refresh_token=abc access_token: def`:                                        `refresh_token=[REDACTED] access_token: [REDACTED]`,
+		`nothing to see here`:                                                        `nothing to see here`,
+	} {
+		assert.Equal(t, expected, redactString(input))
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	assert.Equal(t, redacted, redactValue("EncodedPassword", "hunter2"))
+	assert.Equal(t, redacted, redactValue("Set-Cookie", []string{"irsso=abc"}))
+	assert.Equal(t, 42, redactValue("len(data)", 42))
+
+	u, _ := url.Parse("https://example.com/chunk?X-Amz-Security-Token=abc")
+
+	assert.Equal(t, "https://example.com/chunk?X-Amz-Security-Token=[REDACTED]", redactValue("url", u))
+	assert.Equal(t, "bad password=[REDACTED]", redactValue("err", errors.New("bad password=hunter2")))
+}
+
+func TestRedactHook(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.AddHook(redactHook{})
+
+	logger.WithFields(logrus.Fields{
+		"url":      "https://example.com/?X-Amz-Signature=abc123",
+		"password": "hunter2",
+	}).Error("token=shhh")
+
+	assert.NotContains(t, buf.String(), "abc123")
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.NotContains(t, buf.String(), "shhh")
+}
+
+func TestRedactionIsScopedToIrdata(t *testing.T) {
+	var buf bytes.Buffer
+
+	standard := logrus.StandardLogger()
+
+	out := standard.Out
+	standard.SetOutput(&buf)
+
+	t.Cleanup(func() { standard.SetOutput(out) })
+
+	// the application's logging is left alone
+	assert.Empty(t, standard.Hooks)
+
+	standard.WithField("password", "hunter2").Error("application")
+
+	assert.Contains(t, buf.String(), "hunter2")
+
+	// ours is scrubbed, and written where the application's logs go
+	buf.Reset()
+
+	log.WithField("password", "hunter2").Error("irdata")
+
+	assert.Contains(t, buf.String(), "irdata")
+	assert.NotContains(t, buf.String(), "hunter2")
+}
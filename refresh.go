@@ -0,0 +1,226 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// refresherT keeps track of the cache entries GetWithCache has been asked
+// for and refreshes the ones still in use before they expire
+type refresherT struct {
+	lead time.Duration // how long before they expire entries are refreshed
+	idle time.Duration // how long after their last use they're dropped
+
+	// how long to wait before trying again after a refresh fails
+	backoff BackoffFunc
+
+	mu      sync.Mutex
+	entries map[string]*refreshEntryT // by cache key
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type refreshEntryT struct {
+	uri      string
+	ttl      time.Duration // asked for, the entry may be cached for less
+	expires  time.Time
+	lastUsed time.Time
+
+	// refreshes that have failed in a row and when to try again
+	failures int
+	retryAt  time.Time
+}
+
+// how long the refresher waits before trying again after refreshes fail
+const (
+	_refreshRetryBase = 10 * time.Second
+	_refreshRetryMax  = 10 * time.Minute
+)
+
+// StartCacheRefresh refreshes cached results in the background, lead before
+// they expire, as long as they've been asked for (with GetWithCache or a
+// cache policy) within the last idle.  Hot data, e.g. behind a dashboard,
+// then never goes stale and is never waited for.  The refreshes are
+// PriorityLow requests (see WithPriority) subject to the usual rate limit
+// settings.  A refresh that fails isn't tried again for a while, backing off
+// further each time it fails.  The refresher runs until StopCacheRefresh or
+// Close.
+func (i *Irdata) StartCacheRefresh(lead time.Duration, idle time.Duration) {
+	i.StopCacheRefresh()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &refresherT{
+		lead:    lead,
+		idle:    idle,
+		backoff: ExponentialBackoff(_refreshRetryBase, _refreshRetryMax),
+		entries: map[string]*refreshEntryT{},
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	i.refresherMu.Lock()
+	i.refresher = r
+	i.refresherMu.Unlock()
+
+	go i.runRefresher(ctx, r)
+}
+
+// StopCacheRefresh stops the refresher started with StartCacheRefresh and
+// waits for it to finish
+func (i *Irdata) StopCacheRefresh() {
+	i.refresherMu.Lock()
+	r := i.refresher
+	i.refresher = nil
+	i.refresherMu.Unlock()
+
+	if r == nil {
+		return
+	}
+
+	r.cancel()
+
+	<-r.done
+}
+
+// trackCacheUse tells the refresher (if there is one) uri, asked for with
+// ttl, was used at now
+func (i *Irdata) trackCacheUse(uri string, ttl time.Duration, expires time.Time, now time.Time) {
+	i.refresherMu.Lock()
+	r := i.refresher
+	i.refresherMu.Unlock()
+
+	// entries written before they recorded when they expire can't be tracked
+	if r == nil || expires.IsZero() {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := i.cacheKey(uri)
+
+	if entry, ok := r.entries[key]; ok {
+		entry.ttl = ttl
+		entry.expires = expires
+		entry.lastUsed = now
+
+		return
+	}
+
+	r.entries[key] = &refreshEntryT{uri: uri, ttl: ttl, expires: expires, lastUsed: now}
+}
+
+func (i *Irdata) runRefresher(ctx context.Context, r *refresherT) {
+	defer close(r.done)
+
+	interval := r.lead / 2
+	if interval <= 0 || interval > time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, key := range r.due(now) {
+				i.refresh(ctx, r, key)
+			}
+		}
+	}
+}
+
+// due returns the keys of the entries to refresh at now, forgetting those
+// no longer used and skipping those backing off after failing
+func (r *refresherT) due(now time.Time) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var keys []string
+
+	for key, entry := range r.entries {
+		if now.Sub(entry.lastUsed) > r.idle {
+			delete(r.entries, key)
+			continue
+		}
+
+		if now.Before(entry.retryAt) {
+			continue
+		}
+
+		if entry.expires.Sub(now) <= r.lead {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (i *Irdata) refresh(ctx context.Context, r *refresherT, key string) {
+	r.mu.Lock()
+
+	entry, ok := r.entries[key]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+
+	uri, ttl := entry.uri, entry.ttl
+
+	r.mu.Unlock()
+
+	log.WithFields(logrus.Fields{"uri": uri}).Debug("Refreshing cached data")
+
+	data, err := i.getUncachedCtx(WithPriority(ctx, PriorityLow), uri)
+
+	notFound := errors.Is(err, errNotFound)
+	if notFound {
+		err = nil
+	}
+
+	ttl = i.negativeCacheTTLFor(data, notFound, ttl)
+
+	if err == nil {
+		err = i.setCachedData(key, data, ttl)
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the entry's use is tracked from when it was asked for, not refreshed
+	current, ok := r.entries[key]
+	if !ok {
+		return
+	}
+
+	if err != nil {
+		current.failures++
+		current.retryAt = time.Now().Add(r.backoff(current.failures))
+
+		log.WithFields(logrus.Fields{
+			"uri":      uri,
+			"err":      err,
+			"failures": current.failures,
+			"retryAt":  current.retryAt,
+		}).Warn("Unable to refresh cached data")
+
+		return
+	}
+
+	current.failures = 0
+	current.retryAt = time.Time{}
+	current.expires = time.Now().Add(ttl)
+}
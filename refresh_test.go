@@ -0,0 +1,151 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheRefresh(t *testing.T) {
+	var version int32
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"version":%d}`, atomic.AddInt32(&version, 1))
+	}))
+
+	assert.NoError(t, fake.EnableCache(filepath.Join(t.TempDir(), "cache")))
+	t.Cleanup(fake.Close)
+
+	ttl := 200 * time.Millisecond
+
+	fake.StartCacheRefresh(100*time.Millisecond, time.Hour)
+
+	data, err := fake.GetWithCache("/data/member/info", ttl)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"version":1}`, string(data))
+
+	// refreshed before it expired
+	assert.Eventually(t, func() bool {
+		data, _ := fake.getCachedData("/data/member/info")
+		return string(data) != `{"version":1}` && data != nil
+	}, time.Second, 10*time.Millisecond)
+
+	data, err = fake.GetWithCache("/data/member/info", ttl)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, `{"version":1}`, string(data))
+
+	fake.StopCacheRefresh()
+
+	// stopped
+	stoppedAt := atomic.LoadInt32(&version)
+
+	time.Sleep(3 * ttl)
+
+	assert.Equal(t, stoppedAt, atomic.LoadInt32(&version))
+}
+
+func TestRefresherDue(t *testing.T) {
+	now := time.Now()
+
+	r := &refresherT{
+		lead: time.Minute,
+		idle: time.Hour,
+		entries: map[string]*refreshEntryT{
+			"/soon":   {ttl: time.Hour, expires: now.Add(30 * time.Second), lastUsed: now},
+			"/later":  {ttl: time.Hour, expires: now.Add(30 * time.Minute), lastUsed: now},
+			"/unused": {ttl: time.Hour, expires: now.Add(30 * time.Second), lastUsed: now.Add(-2 * time.Hour)},
+		},
+	}
+
+	assert.Equal(t, []string{"/soon"}, r.due(now))
+	assert.Len(t, r.entries, 2)
+}
+
+func TestRefresherDueBacksOff(t *testing.T) {
+	now := time.Now()
+
+	r := &refresherT{
+		lead: time.Minute,
+		idle: time.Hour,
+		entries: map[string]*refreshEntryT{
+			"/failed": {ttl: time.Hour, expires: now, lastUsed: now, failures: 1, retryAt: now.Add(time.Second)},
+		},
+	}
+
+	assert.Empty(t, r.due(now))
+	assert.Equal(t, []string{"/failed"}, r.due(now.Add(time.Second)))
+}
+
+func TestCacheRefreshFailing(t *testing.T) {
+	setupRetryTest(t)
+
+	var requests int32
+
+	failing := int32(0)
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprint(w, `[]`)
+	}))
+
+	assert.NoError(t, fake.EnableCache(filepath.Join(t.TempDir(), "cache")))
+	t.Cleanup(fake.Close)
+
+	fake.SetNegativeCacheTTL(100 * time.Millisecond)
+
+	fake.StartCacheRefresh(50*time.Millisecond, time.Hour)
+
+	// equivalent uris are tracked as the one cache entry
+	_, err := fake.GetWithCache("/data/member/info?b=2&a=1", time.Hour)
+	assert.NoError(t, err)
+
+	_, err = fake.GetWithCache("/data/member/info?a=1&b=2", time.Hour)
+	assert.NoError(t, err)
+
+	fake.refresherMu.Lock()
+	r := fake.refresher
+	fake.refresherMu.Unlock()
+
+	r.mu.Lock()
+	assert.Len(t, r.entries, 1)
+	r.mu.Unlock()
+
+	// the empty result is refreshed with the negative cache ttl, not the
+	// hour asked for
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&requests) >= 3
+	}, time.Second, 10*time.Millisecond)
+
+	atomic.StoreInt32(&failing, 1)
+
+	// after the first failure the refresher waits before trying again
+	assert.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		for _, entry := range r.entries {
+			return entry.failures > 0
+		}
+
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	failedAt := atomic.LoadInt32(&requests)
+
+	time.Sleep(300 * time.Millisecond)
+
+	assert.Equal(t, failedAt, atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,412 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const _maxRetries = 5
+
+// maximum number of bytes we'll read from an abandoned body so that the
+// connection can be reused
+const _maxDrainBytes = 1024 * 64 // 64K
+
+// BackoffFunc returns how long to wait after the given (1 based) attempt
+// before making the next one
+type BackoffFunc func(attempt int) time.Duration
+
+// LinearBackoff waits step longer after each attempt
+func LinearBackoff(step time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		return time.Duration(attempt) * step
+	}
+}
+
+// ExponentialBackoff waits base after the first attempt and doubles the wait
+// after each one after that, up to max
+func ExponentialBackoff(base time.Duration, max time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		backoff := base
+
+		for n := 1; n < attempt && backoff < max; n++ {
+			backoff *= 2
+		}
+
+		if backoff > max {
+			return max
+		}
+
+		return backoff
+	}
+}
+
+// WithJitter randomizes the waits of backoff by up to fraction (e.g. 0.2 for
+// +/-20%) so that many clients retrying at once spread out
+func WithJitter(backoff BackoffFunc, fraction float64) BackoffFunc {
+	return func(attempt int) time.Duration {
+		wait := backoff(attempt)
+
+		return wait + time.Duration(float64(wait)*fraction*(2*rand.Float64()-1))
+	}
+}
+
+// retryBackoff returns how long to wait before the next attempt
+var retryBackoff = LinearBackoff(time.Duration(5) * time.Second)
+
+// RetryAttempt describes a failed attempt for a RetryPolicy
+type RetryAttempt struct {
+	Attempt    int           // 1 based
+	StatusCode int           // zero when the request itself failed
+	Err        error         // why the request failed, if it did
+	Waited     time.Duration // total time already spent waiting to retry
+	RetryAfter time.Duration // how long the server asked us to wait, if it did
+}
+
+// RetryPolicy decides whether a failed attempt (a network error or an
+// error status) is retried and how long to wait first
+type RetryPolicy interface {
+	NextRetry(attempt RetryAttempt) (wait time.Duration, retry bool)
+}
+
+// BackoffRetryPolicy is a RetryPolicy that retries network errors and the
+// statuses chosen by RetryStatus, up to MaxAttempts attempts, waiting
+// Backoff between them (or as long as the server asked with Retry-After) and
+// giving up early rather than wait more than Budget in total.  Zero fields
+// take the defaults: 5 attempts, a linear 5s backoff, retrying 429 and 5xx
+// statuses, and no budget.
+type BackoffRetryPolicy struct {
+	MaxAttempts int
+	Backoff     BackoffFunc
+	RetryStatus func(statusCode int) bool
+	Budget      time.Duration
+}
+
+func (p BackoffRetryPolicy) NextRetry(attempt RetryAttempt) (time.Duration, bool) {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = _maxRetries
+	}
+
+	if attempt.Attempt >= maxAttempts {
+		return 0, false
+	}
+
+	if attempt.Err == nil {
+		retryStatus := p.RetryStatus
+		if retryStatus == nil {
+			retryStatus = retryServerErrors
+		}
+
+		if !retryStatus(attempt.StatusCode) {
+			return 0, false
+		}
+	}
+
+	backoff := p.Backoff
+	if backoff == nil {
+		backoff = retryBackoff
+	}
+
+	wait := backoff(attempt.Attempt)
+
+	if attempt.RetryAfter > 0 {
+		wait = attempt.RetryAfter
+	}
+
+	if p.Budget > 0 && attempt.Waited+wait > p.Budget {
+		return 0, false
+	}
+
+	return wait, true
+}
+
+func retryServerErrors(statusCode int) bool {
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
+
+// retryAfter returns how long resp asks us to wait before trying again: the
+// Retry-After header (in seconds or as a date) or failing that, for rate
+// limited responses, until the x-ratelimit-reset time
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+
+		if at, err := http.ParseTime(value); err == nil && at.After(now) {
+			return at.Sub(now)
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+			if at := time.Unix(reset, 0); at.After(now) {
+				return at.Sub(now)
+			}
+		}
+	}
+
+	return 0
+}
+
+// retryingDo calls doRequest until it succeeds or policy gives up.  Network
+// errors and statuses of 400 and up are failures that policy may retry,
+// other responses are returned right away.
+//
+// Every response that is not returned to the caller is drained and closed so
+// its connection goes back to the pool.  When policy gives up the last
+// response (if any) is returned along with the last error.
+//
+// No attempts are made once ctx is done and waits between attempts are cut
+// short by it, in which case ctx's error is returned.
+func retryingDo(ctx context.Context, description string, policy RetryPolicy, doRequest func() (*http.Response, error)) (resp *http.Response, err error) {
+	var waited time.Duration
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		resp, err = doRequest()
+
+		if err == nil && resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		// retrying won't help until the breaker closes or the rate limit
+		// resets
+		var rateLimitErr *RateLimitExceededError
+
+		if errors.Is(err, ErrCircuitOpen) || errors.As(err, &rateLimitErr) {
+			return nil, err
+		}
+
+		failed := RetryAttempt{Attempt: attempt, Err: err, Waited: waited}
+
+		if err == nil {
+			failed.StatusCode = resp.StatusCode
+			failed.RetryAfter = retryAfter(resp, time.Now())
+		}
+
+		wait, retry := policy.NextRetry(failed)
+		if !retry {
+			return resp, err
+		}
+
+		fields := logrus.Fields{
+			"description": description,
+			"attempt":     attempt,
+		}
+
+		if err != nil {
+			fields["err"] = err
+		} else {
+			fields["resp.StatusCode"] = resp.StatusCode
+
+			drainAndClose(resp)
+		}
+
+		fields["backoff"] = wait
+
+		log.WithFields(fields).Warn("*** Retrying")
+
+		if err := sleepCtx(ctx, wait); err != nil {
+			return nil, err
+		}
+
+		waited += wait
+	}
+}
+
+// sleepCtx sleeps for d or until ctx is done, whichever comes first
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// drainAndClose reads what's left of a response body (up to a limit) and
+// closes it
+func drainAndClose(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, _maxDrainBytes))
+	resp.Body.Close()
+}
+
+// SetRetryPolicy sets the policy for retrying /data requests and, unless
+// SetDownloadRetryPolicy is used, the s3 links, data urls, and chunks they
+// lead to.  nil restores the default, a BackoffRetryPolicy with its defaults.
+// Logins have their own policy, see SetAuthRetryPolicy.
+func (i *Irdata) SetRetryPolicy(policy RetryPolicy) {
+	i.retryPolicyMu.Lock()
+	defer i.retryPolicyMu.Unlock()
+
+	i.retryPolicy = policy
+}
+
+func (i *Irdata) getRetryPolicy() RetryPolicy {
+	i.retryPolicyMu.Lock()
+	defer i.retryPolicyMu.Unlock()
+
+	if i.retryPolicy == nil {
+		return BackoffRetryPolicy{}
+	}
+
+	return i.retryPolicy
+}
+
+// retryingGet gets url from the /data API
+func (i *Irdata) retryingGet(ctx context.Context, url string) (*http.Response, error) {
+	return i.retryingFetch(ctx, _apiHost, url, i.getRetryPolicy())
+}
+
+// retryingDownload gets an s3 link, data url, or chunk
+func (i *Irdata) retryingDownload(ctx context.Context, url string) (*http.Response, error) {
+	if i.hedgeDelay > 0 {
+		return i.hedgedFetch(ctx, _downloadHost, url, i.getDownloadRetryPolicy(), i.hedgeDelay)
+	}
+
+	return i.retryingFetch(ctx, _downloadHost, url, i.getDownloadRetryPolicy())
+}
+
+func (i *Irdata) retryingFetch(ctx context.Context, kind hostKindT, url string, policy RetryPolicy) (*http.Response, error) {
+	ctx, span := i.startSpan(ctx, TraceFetch, map[string]any{"url": redactString(url), "host": kind.String()})
+
+	resp, err := i.retryingGetAttempts(ctx, kind, url, policy)
+
+	if resp != nil {
+		span.SetAttribute("http.status_code", resp.StatusCode)
+	}
+
+	span.End(err)
+
+	return resp, err
+}
+
+func (i *Irdata) retryingGetAttempts(ctx context.Context, kind hostKindT, url string, policy RetryPolicy) (*http.Response, error) {
+	return retryingDo(ctx, url, policy, func() (*http.Response, error) {
+		log.WithFields(logrus.Fields{"url": url}).Info("httpClient.Get")
+
+		req, err := i.newRequest(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		acceptGzip(req)
+
+		if kind == _apiHost {
+			if err := i.deferLowPriority(ctx); err != nil {
+				return nil, err
+			}
+
+			if err := i.waitForBudget(ctx, url); err != nil {
+				return nil, err
+			}
+
+			if err := i.adaptivePace(ctx); err != nil {
+				return nil, err
+			}
+
+			waited, err := i.pacer.wait(ctx, i.maxRateLimitWait)
+			if err != nil {
+				return nil, i.rateLimitError(err)
+			}
+
+			if waited > 0 {
+				i.rateLimited(waited)
+			}
+
+			if err := i.takeSharedRateLimit(ctx); err != nil {
+				return nil, i.rateLimitError(err)
+			}
+		}
+
+		if err := i.breaker.allow(time.Now()); err != nil {
+			return nil, err
+		}
+
+		release, err := i.slots(kind).acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		started := time.Now()
+
+		resp, err := i.do(req)
+
+		i.observeResponse(resp, started)
+
+		if kind == _apiHost && resp != nil {
+			i.recordRateLimit(resp, time.Now())
+		}
+
+		// a cancelled request says nothing about iRacing
+		if ctx.Err() == nil {
+			i.breaker.record(err != nil || resp.StatusCode >= 500, time.Now())
+		}
+
+		if err != nil {
+			release()
+
+			return nil, err
+		}
+
+		resp.Body = slotBodyT{ReadCloser: resp.Body, release: release}
+
+		if kind == _apiHost && resp.StatusCode == http.StatusTooManyRequests {
+			wait := retryAfter(resp, time.Now())
+
+			i.rateLimited(wait)
+
+			if i.maxRateLimitWait > 0 && wait > i.maxRateLimitWait {
+				drainAndClose(resp)
+
+				return nil, i.rateLimitError(&RateLimitExceededError{Wait: wait, MaxWait: i.maxRateLimitWait})
+			}
+		}
+
+		if err := gunzipResponse(resp); err != nil {
+			return nil, err
+		}
+
+		return resp, nil
+	})
+}
+
+// SetAuthRetryPolicy sets how many attempts logging in makes (when the login
+// endpoint fails with a 5xx or a network error) and how long it waits
+// between them, separately from the retries of /data requests.  Defaults are
+// 5 attempts with a linear 5s backoff; maxAttempts below 1 or a nil backoff
+// restore them.
+func (i *Irdata) SetAuthRetryPolicy(maxAttempts int, backoff BackoffFunc) {
+	i.authMu.Lock()
+	defer i.authMu.Unlock()
+
+	i.authMaxAttempts = maxAttempts
+	i.authBackoff = backoff
+}
+
+// authRetryingDo is retryingDo with the auth retry policy
+func (i *Irdata) authRetryingDo(ctx context.Context, description string, doRequest func() (*http.Response, error)) (*http.Response, error) {
+	i.authMu.Lock()
+	policy := BackoffRetryPolicy{MaxAttempts: i.authMaxAttempts, Backoff: i.authBackoff}
+	i.authMu.Unlock()
+
+	return retryingDo(ctx, description, policy, doRequest)
+}
@@ -0,0 +1,238 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRetryTest(t *testing.T) {
+	saved := retryBackoff
+	retryBackoff = func(int) time.Duration { return time.Millisecond }
+	t.Cleanup(func() { retryBackoff = saved })
+}
+
+func TestRetryingGetRecovers(t *testing.T) {
+	setupRetryTest(t)
+
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "try again", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	resp, err := i.retryingGet(context.Background(), ts.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	drainAndClose(resp)
+}
+
+func TestRetryingGetGivesUp(t *testing.T) {
+	setupRetryTest(t)
+
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	resp, err := i.retryingGet(context.Background(), ts.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, int32(_maxRetries), atomic.LoadInt32(&calls))
+
+	drainAndClose(resp)
+}
+
+func TestRetryingGetConnectionError(t *testing.T) {
+	setupRetryTest(t)
+
+	ts := httptest.NewServer(http.NotFoundHandler())
+	url := ts.URL
+	ts.Close()
+
+	_, err := i.retryingGet(context.Background(), url)
+
+	assert.Error(t, err)
+}
+
+func TestBackoffFuncs(t *testing.T) {
+	assert.Equal(t, 10*time.Second, LinearBackoff(5*time.Second)(2))
+
+	exponential := ExponentialBackoff(time.Second, 5*time.Second)
+
+	assert.Equal(t, time.Second, exponential(1))
+	assert.Equal(t, 4*time.Second, exponential(3))
+	assert.Equal(t, 5*time.Second, exponential(10))
+
+	jittered := WithJitter(LinearBackoff(time.Second), 0.5)
+
+	for n := 0; n < 20; n++ {
+		wait := jittered(1)
+
+		assert.GreaterOrEqual(t, wait, 500*time.Millisecond)
+		assert.LessOrEqual(t, wait, 1500*time.Millisecond)
+	}
+}
+
+func TestAuthRetryPolicy(t *testing.T) {
+	var logins int32
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&logins, 1)
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+
+	fake.isAuthed = false
+
+	var waits []int
+
+	fake.SetAuthRetryPolicy(2, func(attempt int) time.Duration {
+		waits = append(waits, attempt)
+		return time.Millisecond
+	})
+
+	assert.Error(t, fake.AuthWithProvideCreds(testCredsProvider{}))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&logins))
+	assert.Equal(t, []int{1}, waits)
+}
+
+func TestGetCtxCancelsRetries(t *testing.T) {
+	saved := retryBackoff
+	retryBackoff = func(int) time.Duration { return time.Hour }
+	t.Cleanup(func() { retryBackoff = saved })
+
+	var calls int32
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := fake.GetCtx(ctx, "/data/member/info")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Minute)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// nothing is requested with a done context
+	_, err = fake.GetCtx(ctx, "/data/member/info")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestBackoffRetryPolicy(t *testing.T) {
+	policy := BackoffRetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     LinearBackoff(time.Second),
+	}
+
+	wait, retry := policy.NextRetry(RetryAttempt{Attempt: 1, StatusCode: http.StatusBadGateway})
+	assert.True(t, retry)
+	assert.Equal(t, time.Second, wait)
+
+	// other 4xx aren't retried by default
+	_, retry = policy.NextRetry(RetryAttempt{Attempt: 1, StatusCode: http.StatusForbidden})
+	assert.False(t, retry)
+
+	// the server's Retry-After wins over the backoff
+	wait, retry = policy.NextRetry(RetryAttempt{Attempt: 1, StatusCode: http.StatusTooManyRequests, RetryAfter: 7 * time.Second})
+	assert.True(t, retry)
+	assert.Equal(t, 7*time.Second, wait)
+
+	_, retry = policy.NextRetry(RetryAttempt{Attempt: 3, Err: errors.New("reset")})
+	assert.False(t, retry)
+
+	policy.RetryStatus = func(statusCode int) bool { return statusCode == http.StatusTooManyRequests }
+
+	_, retry = policy.NextRetry(RetryAttempt{Attempt: 1, StatusCode: http.StatusTooManyRequests})
+	assert.True(t, retry)
+
+	_, retry = policy.NextRetry(RetryAttempt{Attempt: 1, StatusCode: http.StatusBadGateway})
+	assert.False(t, retry)
+
+	// the budget stops retries before the attempts run out
+	policy.Budget = 2 * time.Second
+
+	_, retry = policy.NextRetry(RetryAttempt{Attempt: 2, Err: errors.New("reset"), Waited: time.Second})
+	assert.False(t, retry)
+}
+
+func TestSetRetryPolicy(t *testing.T) {
+	var calls int32
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+
+		w.Write([]byte(`{}`))
+	}))
+
+	var attempts []RetryAttempt
+
+	fake.SetRetryPolicy(retryPolicyFunc(func(attempt RetryAttempt) (time.Duration, bool) {
+		attempts = append(attempts, attempt)
+		return time.Millisecond, true
+	}))
+
+	_, err := fake.Get("/data/member/info")
+
+	assert.NoError(t, err)
+	assert.Len(t, attempts, 2)
+	assert.Equal(t, http.StatusTooManyRequests, attempts[1].StatusCode)
+	assert.Equal(t, time.Millisecond, attempts[1].Waited)
+}
+
+type retryPolicyFunc func(RetryAttempt) (time.Duration, bool)
+
+func (f retryPolicyFunc) NextRetry(attempt RetryAttempt) (time.Duration, bool) {
+	return f(attempt)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	resp := func(statusCode int, header ...string) *http.Response {
+		r := &http.Response{StatusCode: statusCode, Header: http.Header{}}
+
+		for n := 0; n < len(header); n += 2 {
+			r.Header.Set(header[n], header[n+1])
+		}
+
+		return r
+	}
+
+	assert.Equal(t, 30*time.Second, retryAfter(resp(http.StatusServiceUnavailable, "Retry-After", "30"), now))
+	assert.Equal(t, time.Minute, retryAfter(resp(http.StatusServiceUnavailable, "Retry-After", now.Add(time.Minute).Format(http.TimeFormat)), now))
+	assert.Equal(t, 10*time.Second, retryAfter(resp(http.StatusTooManyRequests, "X-Ratelimit-Reset", strconv.FormatInt(now.Add(10*time.Second).Unix(), 10)), now))
+
+	// the reset only says when to retry a rate limited request
+	assert.Equal(t, time.Duration(0), retryAfter(resp(http.StatusBadGateway, "X-Ratelimit-Reset", strconv.FormatInt(now.Add(10*time.Second).Unix(), 10)), now))
+	assert.Equal(t, time.Duration(0), retryAfter(resp(http.StatusServiceUnavailable, "Retry-After", "soon"), now))
+}
@@ -0,0 +1,72 @@
+package irdata
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+const _rotatingSuffix = ".rotating"
+
+// RotateKey re-encrypts files (e.g. creds files) that were encrypted with the
+// key in oldKeyFilename with the key in newKeyFilename, so the key can be
+// rotated without re-entering credentials.
+//
+// All of the files are decrypted before any are written and each is replaced
+// by renaming a fully written temporary file, so a failure leaves every file
+// readable with one of the keys.
+func (i *Irdata) RotateKey(oldKeyFilename string, newKeyFilename string, filenames ...string) error {
+	return i.RotateKeyWithKeys(i.keyFromFile(oldKeyFilename), i.keyFromFile(newKeyFilename), filenames...)
+}
+
+// RotateKeyWithKeys is RotateKey with the keys supplied by key providers
+func (i *Irdata) RotateKeyWithKeys(oldKeyProvider KeyProvider, newKeyProvider KeyProvider, filenames ...string) error {
+	opts := i.fileOpts()
+
+	plaintexts := make([][]byte, len(filenames))
+
+	defer func() {
+		for n := range plaintexts {
+			shred(&plaintexts[n])
+		}
+	}()
+
+	for n, filename := range filenames {
+		plaintext, err := decryptFromFile(oldKeyProvider, filename, opts)
+		if err != nil {
+			return err
+		}
+
+		plaintexts[n] = plaintext
+	}
+
+	var written []string
+
+	cleanup := func() {
+		for _, tmpFilename := range written {
+			os.Remove(tmpFilename)
+		}
+	}
+
+	for n, filename := range filenames {
+		tmpFilename := filename + _rotatingSuffix
+
+		written = append(written, tmpFilename)
+
+		if err := encryptToFile(newKeyProvider, tmpFilename, plaintexts[n], opts); err != nil {
+			cleanup()
+			return err
+		}
+	}
+
+	for n, filename := range filenames {
+		if err := os.Rename(written[n], filename); err != nil {
+			cleanup()
+			return makeErrorf("unable to replace %s [%v]", filename, err)
+		}
+
+		log.WithFields(logrus.Fields{"filename": filename}).Info("Rotated key")
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package irdata
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// staticKey is a KeyProvider for a fixed key
+type staticKey []byte
+
+func (k staticKey) GetKey() ([]byte, error) {
+	return append([]byte{}, k...), nil
+}
+
+func TestRotateKey(t *testing.T) {
+	dir := t.TempDir()
+
+	a, b := filepath.Join(dir, "a.creds"), filepath.Join(dir, "b.creds")
+
+	authData := authDataT{Username: "user", EncodedPassword: "encoded"}
+
+	assert.NoError(t, writeCreds(testKeyProvider{}, a, authData, fileOptsT{}))
+	assert.NoError(t, writeCreds(testKeyProvider{}, b, authData, fileOptsT{}))
+
+	newKey := staticKey(bytes.Repeat([]byte{0x24}, 32))
+
+	// the wrong old key leaves everything alone
+	err := i.RotateKeyWithKeys(newKey, newKey, a, b)
+	assert.True(t, errors.Is(err, ErrWrongKey))
+
+	_, err = readCreds(testKeyProvider{}, a, fileOptsT{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, i.RotateKeyWithKeys(testKeyProvider{}, newKey, a, b))
+
+	for _, filename := range []string{a, b} {
+		_, err = readCreds(testKeyProvider{}, filename, fileOptsT{})
+		assert.True(t, errors.Is(err, ErrWrongKey))
+
+		rotated, err := readCreds(newKey, filename, fileOptsT{})
+		assert.NoError(t, err)
+		assert.Equal(t, authData.EncodedPassword, rotated.EncodedPassword)
+
+		_, err = os.Stat(filename + _rotatingSuffix)
+		assert.True(t, os.IsNotExist(err))
+	}
+}
@@ -0,0 +1,125 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const _icsTimeFormat = "20060102T150405Z"
+
+type seriesT struct {
+	SeriesID   int64  `json:"series_id"`
+	SeriesName string `json:"series_name"`
+}
+
+// ScheduleICS returns an iCalendar (.ics) feed of the upcoming race sessions
+// of the series in seriesIDs (all series if empty) from the race guide.
+// Times are written in UTC, tz (if not nil) is set as the calendar's
+// display timezone.
+func (i *Irdata) ScheduleICS(seriesIDs []int64, tz *time.Location) ([]byte, error) {
+	sessions, err := i.raceGuideSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	seriesData, err := i.Get("/data/series/get")
+	if err != nil {
+		return nil, err
+	}
+
+	var series []seriesT
+
+	if err := json.Unmarshal(seriesData, &series); err != nil {
+		return nil, makeErrorf("unable to decode series [%v]", err)
+	}
+
+	seriesNames := map[int64]string{}
+
+	for _, s := range series {
+		seriesNames[s.SeriesID] = s.SeriesName
+	}
+
+	return scheduleICS(sessions, seriesNames, seriesIDs, tz, time.Now().UTC()), nil
+}
+
+func scheduleICS(sessions []RaceGuideSession, seriesNames map[int64]string, seriesIDs []int64, tz *time.Location, now time.Time) []byte {
+	wanted := map[int64]bool{}
+
+	for _, seriesID := range seriesIDs {
+		wanted[seriesID] = true
+	}
+
+	var buf bytes.Buffer
+
+	writeICSLine(&buf, "BEGIN:VCALENDAR")
+	writeICSLine(&buf, "VERSION:2.0")
+	writeICSLine(&buf, "PRODID:-//popmonkey//irdata//EN")
+	writeICSLine(&buf, "CALSCALE:GREGORIAN")
+	writeICSLine(&buf, "X-WR-CALNAME:iRacing schedule")
+
+	if tz != nil {
+		writeICSLine(&buf, "X-WR-TIMEZONE:"+tz.String())
+	}
+
+	for _, session := range sessions {
+		if len(wanted) > 0 && !wanted[session.SeriesID] {
+			continue
+		}
+
+		name, ok := seriesNames[session.SeriesID]
+		if !ok {
+			name = fmt.Sprintf("Series %d", session.SeriesID)
+		}
+
+		uid := fmt.Sprintf("%d-%d-%d@irdata", session.SeasonID, session.SessionID, session.StartTime.Unix())
+
+		end := session.EndTime
+		if end.IsZero() || end.Before(session.StartTime) {
+			end = session.StartTime
+		}
+
+		writeICSLine(&buf, "BEGIN:VEVENT")
+		writeICSLine(&buf, "UID:"+uid)
+		writeICSLine(&buf, "DTSTAMP:"+now.UTC().Format(_icsTimeFormat))
+		writeICSLine(&buf, "DTSTART:"+session.StartTime.UTC().Format(_icsTimeFormat))
+		writeICSLine(&buf, "DTEND:"+end.UTC().Format(_icsTimeFormat))
+		writeICSLine(&buf, "SUMMARY:"+escapeICSText(name))
+		writeICSLine(&buf, "DESCRIPTION:"+escapeICSText(fmt.Sprintf("Week %d, %d registered", session.RaceWeekNum+1, session.EntryCount)))
+		writeICSLine(&buf, "END:VEVENT")
+	}
+
+	writeICSLine(&buf, "END:VCALENDAR")
+
+	return buf.Bytes()
+}
+
+// writeICSLine writes line folded at 75 octets (RFC 5545 3.1)
+func writeICSLine(buf *bytes.Buffer, line string) {
+	const max = 75
+
+	for len(line) > max {
+		cut := max
+
+		// don't split a multibyte character
+		for cut > 0 && line[cut]&0xc0 == 0x80 {
+			cut--
+		}
+
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+
+		line = line[cut:]
+	}
+
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func escapeICSText(text string) string {
+	return icsTextEscaper.Replace(text)
+}
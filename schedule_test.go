@@ -0,0 +1,66 @@
+package irdata
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleICS(t *testing.T) {
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/season/race_guide":
+			fmt.Fprint(w, `{"sessions":[
+				{"season_id":1,"series_id":10,"session_id":100,"race_week_num":2,"start_time":"2024-06-01T18:00:00Z","end_time":"2024-06-01T18:45:00Z","entry_count":12},
+				{"season_id":2,"series_id":20,"session_id":200,"race_week_num":0,"start_time":"2024-06-01T19:00:00Z","end_time":"2024-06-01T19:30:00Z","entry_count":3}
+			]}`)
+		case "/data/series/get":
+			fmt.Fprint(w, `[{"series_id":10,"series_name":"GT3, Fixed; Open"}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	tz, err := time.LoadLocation("UTC")
+	assert.NoError(t, err)
+
+	data, err := fake.ScheduleICS([]int64{10}, tz)
+	assert.NoError(t, err)
+
+	ics := string(data)
+
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+	assert.Contains(t, ics, "X-WR-TIMEZONE:UTC\r\n")
+	assert.Contains(t, ics, "DTSTART:20240601T180000Z\r\n")
+	assert.Contains(t, ics, "DTEND:20240601T184500Z\r\n")
+	assert.Contains(t, ics, `SUMMARY:GT3\, Fixed\; Open`)
+	assert.Contains(t, ics, "DESCRIPTION:Week 3\\, 12 registered\r\n")
+	assert.Equal(t, 1, strings.Count(ics, "BEGIN:VEVENT"))
+
+	data, err = fake.ScheduleICS(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "BEGIN:VEVENT"))
+	assert.Contains(t, string(data), "SUMMARY:Series 20\r\n")
+}
+
+func TestWriteICSLine(t *testing.T) {
+	var buf bytes.Buffer
+
+	line := "SUMMARY:" + strings.Repeat("é", 60)
+
+	writeICSLine(&buf, line)
+
+	folded := strings.TrimSuffix(buf.String(), "\r\n")
+
+	for _, part := range strings.Split(folded, "\r\n") {
+		assert.LessOrEqual(t, len(part), 76)
+	}
+
+	assert.Equal(t, line, strings.ReplaceAll(folded, "\r\n ", ""))
+}
@@ -0,0 +1,194 @@
+// Package schemas infers JSON Schema documents from irdata results and holds
+// the schemas generated from them.
+//
+// The schemas describe irdata's output, i.e. with s3 links followed and
+// chunked results merged into _chunk_data, not the raw API responses.  Only
+// the chunk envelope ("chunked") ships so far, the per-endpoint schemas have
+// to be generated against the live API with examples/irschema.
+package schemas
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+const draft = "https://json-schema.org/draft/2020-12/schema"
+
+const fileSuffix = ".schema.json"
+
+//go:embed *.schema.json
+var files embed.FS
+
+// Filename returns the name of the schema file for an API uri, e.g.
+// /data/member/info?cust_ids=1 is data_member_info.schema.json
+func Filename(uri string) string {
+	path, _, _ := strings.Cut(uri, "?")
+
+	return strings.ReplaceAll(strings.Trim(path, "/"), "/", "_") + fileSuffix
+}
+
+// Names returns the names of the schemas included in this package
+func Names() []string {
+	entries, _ := fs.ReadDir(files, ".")
+
+	names := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), fileSuffix))
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Load returns the schema for uri (or a name returned by Names)
+func Load(uri string) ([]byte, error) {
+	filename := Filename(uri)
+
+	data, err := files.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("schemas: no schema for %s", uri)
+	}
+
+	return data, nil
+}
+
+// node accumulates what's been seen at one place in the samples
+type node struct {
+	types      map[string]bool
+	objects    int
+	properties map[string]*node
+	counts     map[string]int
+	items      *node
+}
+
+func newNode() *node {
+	return &node{
+		types:      map[string]bool{},
+		properties: map[string]*node{},
+		counts:     map[string]int{},
+	}
+}
+
+func (n *node) add(v interface{}) {
+	switch value := v.(type) {
+	case nil:
+		n.types["null"] = true
+	case bool:
+		n.types["boolean"] = true
+	case float64:
+		if value == float64(int64(value)) {
+			n.types["integer"] = true
+		} else {
+			n.types["number"] = true
+		}
+	case string:
+		n.types["string"] = true
+	case []interface{}:
+		n.types["array"] = true
+
+		if n.items == nil {
+			n.items = newNode()
+		}
+
+		for _, item := range value {
+			n.items.add(item)
+		}
+	case map[string]interface{}:
+		n.types["object"] = true
+		n.objects++
+
+		for k, inner := range value {
+			if n.properties[k] == nil {
+				n.properties[k] = newNode()
+			}
+
+			n.properties[k].add(inner)
+			n.counts[k]++
+		}
+	}
+}
+
+func (n *node) schema() map[string]interface{} {
+	s := map[string]interface{}{}
+
+	if n.types["integer"] && n.types["number"] {
+		delete(n.types, "integer")
+	}
+
+	types := make([]string, 0, len(n.types))
+
+	for t := range n.types {
+		types = append(types, t)
+	}
+
+	sort.Strings(types)
+
+	switch len(types) {
+	case 0:
+		// nothing seen (e.g. the items of empty arrays), anything goes
+	case 1:
+		s["type"] = types[0]
+	default:
+		s["type"] = types
+	}
+
+	if n.types["object"] {
+		properties := map[string]interface{}{}
+		required := []string{}
+
+		for k, inner := range n.properties {
+			properties[k] = inner.schema()
+
+			if n.counts[k] == n.objects {
+				required = append(required, k)
+			}
+		}
+
+		sort.Strings(required)
+
+		s["properties"] = properties
+
+		if len(required) > 0 {
+			s["required"] = required
+		}
+	}
+
+	if n.types["array"] && n.items != nil {
+		s["items"] = n.items.schema()
+	}
+
+	return s
+}
+
+// Infer returns a JSON Schema document that all of the samples validate
+// against.  Properties missing from any sample aren't required and values
+// seen with different types get a list of types.
+func Infer(title string, samples ...[]byte) ([]byte, error) {
+	root := newNode()
+
+	for n, sample := range samples {
+		var v interface{}
+
+		if err := json.Unmarshal(sample, &v); err != nil {
+			return nil, fmt.Errorf("schemas: unable to decode sample %d [%w]", n, err)
+		}
+
+		root.add(v)
+	}
+
+	s := root.schema()
+
+	s["$schema"] = draft
+
+	if title != "" {
+		s["title"] = title
+	}
+
+	return json.MarshalIndent(s, "", "  ")
+}
@@ -0,0 +1,57 @@
+package schemas
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfer(t *testing.T) {
+	data, err := Infer("test",
+		[]byte(`{"a":1,"b":"x","c":[{"d":true}],"e":null}`),
+		[]byte(`{"a":1.5,"c":[],"e":"y"}`),
+	)
+
+	assert.NoError(t, err)
+
+	var s map[string]interface{}
+
+	assert.NoError(t, json.Unmarshal(data, &s))
+
+	assert.Equal(t, draft, s["$schema"])
+	assert.Equal(t, "test", s["title"])
+	assert.Equal(t, "object", s["type"])
+	assert.Equal(t, []interface{}{"a", "c", "e"}, s["required"])
+
+	properties := s["properties"].(map[string]interface{})
+
+	assert.Equal(t, map[string]interface{}{"type": "number"}, properties["a"])
+	assert.Equal(t, map[string]interface{}{"type": "string"}, properties["b"])
+	assert.Equal(t, map[string]interface{}{"type": []interface{}{"null", "string"}}, properties["e"])
+
+	items := properties["c"].(map[string]interface{})["items"].(map[string]interface{})
+
+	assert.Equal(t, []interface{}{"d"}, items["required"])
+}
+
+func TestInferInvalid(t *testing.T) {
+	_, err := Infer("", []byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestFilename(t *testing.T) {
+	assert.Equal(t, "data_member_info.schema.json", Filename("/data/member/info?cust_ids=1"))
+	assert.Equal(t, "chunked.schema.json", Filename("chunked"))
+}
+
+func TestLoad(t *testing.T) {
+	assert.Contains(t, Names(), "chunked")
+
+	data, err := Load("chunked")
+	assert.NoError(t, err)
+	assert.True(t, json.Valid(data))
+
+	_, err = Load("/data/nope")
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package irdata
+
+// secret holds sensitive bytes (passwords, request bodies containing them).
+//
+// In secure memory mode the bytes live in their own buffer outside the Go
+// heap which is locked into memory (so it isn't swapped) and, where the
+// platform supports it, excluded from core dumps.  Call Destroy as soon as
+// the secret is no longer needed to shred and release it.
+type secret struct {
+	buf    []byte
+	locked bool
+}
+
+// SetSecureMemory turns the secure memory mode on or off.  In this mode the
+// encoded password kept to renew the session, and the login requests built
+// from it, are kept in locked buffers and the password returned by a
+// CredsProvider is shredded once it has been encoded.
+//
+// Go strings can't be shredded so the short lived copies of the encoded
+// password made while encoding it, reading or writing a creds file, or
+// exporting the auth state (see ExportAuthState) stay on the heap until
+// they're garbage collected.
+func (i *Irdata) SetSecureMemory(enabled bool) {
+	i.secureMemory = enabled
+}
+
+// newSecret copies b into a new secret
+func (i *Irdata) newSecret(b []byte) *secret {
+	s := i.allocSecret(len(b))
+
+	copy(s.buf, b)
+
+	return s
+}
+
+// allocSecret returns a new secret of size zeroed bytes
+func (i *Irdata) allocSecret(size int) *secret {
+	// there's nothing to protect in an empty secret (and mmap won't map
+	// zero bytes)
+	if i.secureMemory && size > 0 {
+		buf, err := lockedAlloc(size)
+		if err == nil {
+			return &secret{buf: buf, locked: true}
+		}
+
+		log.WithField("err", err).Warn("Unable to allocate locked memory, using the heap")
+	}
+
+	return &secret{buf: make([]byte, size)}
+}
+
+// Bytes returns the secret's contents which are only valid until Destroy
+func (s *secret) Bytes() []byte {
+	return s.buf
+}
+
+// Destroy shreds the secret and releases its memory
+func (s *secret) Destroy() {
+	if s.buf == nil {
+		return
+	}
+
+	shred(&s.buf)
+
+	if s.locked {
+		if err := lockedFree(s.buf); err != nil {
+			log.WithField("err", err).Warn("Unable to release locked memory")
+		}
+	}
+
+	s.buf = nil
+}
@@ -0,0 +1,12 @@
+package irdata
+
+import (
+	"syscall"
+)
+
+// MADV_DONTDUMP isn't exported by the syscall package
+const _madvDontDump = 0x10
+
+func excludeFromDumps(buf []byte) {
+	syscall.Madvise(buf, _madvDontDump)
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package irdata
+
+import (
+	"errors"
+)
+
+func lockedAlloc(size int) ([]byte, error) {
+	return nil, errors.New("locked memory is not supported on this platform")
+}
+
+func lockedFree(buf []byte) error {
+	return nil
+}
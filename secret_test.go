@@ -0,0 +1,107 @@
+package irdata
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret(t *testing.T) {
+	s := i.newSecret(testPassword)
+
+	assert.Equal(t, testPassword, s.Bytes())
+
+	s.Destroy()
+
+	assert.Nil(t, s.Bytes())
+
+	// destroying twice is harmless
+	s.Destroy()
+}
+
+func TestSecureMemorySecret(t *testing.T) {
+	i.SetSecureMemory(true)
+	t.Cleanup(func() { i.SetSecureMemory(false) })
+
+	for _, b := range [][]byte{testPassword, {}} {
+		s := i.newSecret(b)
+
+		assert.Equal(t, b, s.Bytes())
+
+		buf := s.Bytes()
+
+		s.Destroy()
+
+		if !s.locked {
+			// shredded in place
+			for _, c := range buf {
+				assert.Equal(t, byte(0x69), c)
+			}
+		}
+	}
+
+	// nothing to lock (or leak) in an empty secret
+	assert.False(t, i.newSecret([]byte{}).locked)
+}
+
+type copiedCredsProvider struct{}
+
+// the password is shredded in secure memory mode, don't shred testPassword
+func (copiedCredsProvider) GetCreds() ([]byte, []byte, error) {
+	return append([]byte{}, testUsername...), append([]byte{}, testPassword...), nil
+}
+
+func TestSecureMemoryAuthData(t *testing.T) {
+	setupRetryTest(t)
+
+	var bodies []string
+
+	loggedIn := false
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			loggedIn = true
+			fmt.Fprint(w, `{}`)
+		default:
+			if !loggedIn {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+
+	fake.isAuthed = false
+	fake.SetSecureMemory(true)
+
+	assert.NoError(t, fake.AuthWithProvideCreds(copiedCredsProvider{}))
+
+	encoded, _ := encodePassword(testUsername, testPassword)
+
+	// the encoded password is only kept in the secret
+	assert.Empty(t, fake.authData.EncodedPassword)
+	assert.Equal(t, []byte(encoded), fake.authPassword.Bytes())
+
+	// renewing the session sends the same login
+	loggedIn = false
+
+	_, err := fake.Get("/data/member/info")
+	assert.NoError(t, err)
+
+	if assert.Len(t, bodies, 2) {
+		assert.JSONEq(t, fmt.Sprintf(`{"email":%q,"password":%q}`, testUsername, encoded), bodies[0])
+		assert.Equal(t, bodies[0], bodies[1])
+	}
+
+	password := fake.authPassword
+
+	assert.NoError(t, fake.Logout())
+	assert.Nil(t, password.Bytes())
+}
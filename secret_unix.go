@@ -0,0 +1,31 @@
+//go:build linux || darwin
+
+package irdata
+
+import (
+	"syscall"
+)
+
+// lockedAlloc maps a private anonymous region of size (more than zero) bytes
+// and locks it into memory
+func lockedAlloc(size int) ([]byte, error) {
+	buf, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Mlock(buf); err != nil {
+		syscall.Munmap(buf)
+		return nil, err
+	}
+
+	excludeFromDumps(buf)
+
+	return buf, nil
+}
+
+func lockedFree(buf []byte) error {
+	syscall.Munlock(buf)
+
+	return syscall.Munmap(buf)
+}
@@ -0,0 +1,188 @@
+package irdata
+
+import (
+	"crypto/rand"
+)
+
+// Shamir's secret sharing over GF(2^8), each byte of the key is shared
+// independently.  A share is its x coordinate (1-255) followed by one y value
+// per key byte.
+
+// SplitKey splits key into n shares any threshold of which can be combined
+// with CombineKeyShares (or KeyFromShares) to reconstruct it.  Fewer than
+// threshold shares reveal nothing about the key.
+func SplitKey(key []byte, n int, threshold int) ([][]byte, error) {
+	if len(key) == 0 {
+		return nil, makeErrorf("key must not be empty")
+	}
+
+	if threshold < 2 || threshold > n || n > 255 {
+		return nil, makeErrorf("need 2 <= threshold (%d) <= shares (%d) <= 255", threshold, n)
+	}
+
+	shares := make([][]byte, n)
+
+	for s := range shares {
+		shares[s] = make([]byte, len(key)+1)
+		shares[s][0] = byte(s + 1)
+	}
+
+	coefficients := make([]byte, threshold)
+
+	defer shred(&coefficients)
+
+	for b, secretByte := range key {
+		// random polynomial of degree threshold-1 with the secret as its constant
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, makeErrorf("unable to generate coefficients [%v]", err)
+		}
+
+		coefficients[0] = secretByte
+
+		for _, share := range shares {
+			share[b+1] = gfEval(coefficients, share[0])
+		}
+	}
+
+	return shares, nil
+}
+
+// CombineKeyShares reconstructs a key from shares made by SplitKey.  At least
+// as many shares as the threshold used to split the key must be given,
+// otherwise the result is garbage (it will fail to open any files).
+func CombineKeyShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, makeErrorf("need at least 2 shares")
+	}
+
+	size := len(shares[0])
+
+	if size < 2 {
+		return nil, makeErrorf("invalid share")
+	}
+
+	seen := map[byte]bool{}
+
+	for _, share := range shares {
+		if len(share) != size {
+			return nil, makeErrorf("shares must all be the same length")
+		}
+
+		if share[0] == 0 || seen[share[0]] {
+			return nil, makeErrorf("invalid or duplicate share %d", share[0])
+		}
+
+		seen[share[0]] = true
+	}
+
+	key := make([]byte, size-1)
+
+	for b := range key {
+		// lagrange interpolation at x = 0
+		var secretByte byte
+
+		for j, shareJ := range shares {
+			basis := byte(1)
+
+			for m, shareM := range shares {
+				if m == j {
+					continue
+				}
+
+				// x_m / (x_m - x_j), subtraction is xor in GF(2^8)
+				basis = gfMul(basis, gfDiv(shareM[0], shareM[0]^shareJ[0]))
+			}
+
+			secretByte ^= gfMul(shareJ[b+1], basis)
+		}
+
+		key[b] = secretByte
+	}
+
+	return key, nil
+}
+
+// KeyFromShares is a KeyProvider that reconstructs the key from shares
+// held in memory
+type KeyFromShares [][]byte
+
+func (k KeyFromShares) GetKey() ([]byte, error) {
+	return CombineKeyShares(k)
+}
+
+// KeyFromShareFiles is a KeyProvider that reconstructs the key from shares
+// stored base64 encoded in files (e.g. one per admin), each of which must
+// have its perms set to 0400 like a key file.
+type KeyFromShareFiles []string
+
+func (k KeyFromShareFiles) GetKey() ([]byte, error) {
+	shares := make([][]byte, len(k))
+
+	defer func() {
+		for s := range shares {
+			shred(&shares[s])
+		}
+	}()
+
+	for s, shareFilename := range k {
+		share, err := getKey(shareFilename)
+		if err != nil {
+			return nil, err
+		}
+
+		shares[s] = share
+	}
+
+	return CombineKeyShares(shares)
+}
+
+// GF(2^8) arithmetic using the AES polynomial x^8 + x^4 + x^3 + x + 1
+
+var gfExp [510]byte
+var gfLog [256]byte
+
+func init() {
+	x := byte(1)
+
+	for p := 0; p < 255; p++ {
+		gfExp[p] = x
+		gfExp[p+255] = x
+		gfLog[x] = byte(p)
+
+		// multiply by the generator 3
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+}
+
+func gfMul(a byte, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a byte, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+
+	// b is never 0 as share x coordinates are distinct
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// gfEval evaluates the polynomial with coefficients at x
+func gfEval(coefficients []byte, x byte) byte {
+	var y byte
+
+	for c := len(coefficients) - 1; c >= 0; c-- {
+		y = gfMul(y, x) ^ coefficients[c]
+	}
+
+	return y
+}
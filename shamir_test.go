@@ -0,0 +1,85 @@
+package irdata
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGF(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			assert.Equal(t, byte(a), gfDiv(gfMul(byte(a), byte(b)), byte(b)))
+		}
+	}
+
+	// known AES field product
+	assert.Equal(t, byte(0xc1), gfMul(0x57, 0x83))
+}
+
+func TestSplitCombineKey(t *testing.T) {
+	shares, err := SplitKey(testKey, 5, 3)
+
+	assert.NoError(t, err)
+	assert.Len(t, shares, 5)
+
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var picked [][]byte
+
+		for _, s := range subset {
+			picked = append(picked, shares[s])
+		}
+
+		key, err := CombineKeyShares(picked)
+
+		assert.NoError(t, err)
+		assert.Equal(t, testKey, key)
+	}
+
+	// below the threshold
+	key, err := CombineKeyShares(shares[:2])
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, testKey, key)
+
+	// duplicates
+	_, err = CombineKeyShares([][]byte{shares[0], shares[0]})
+
+	assert.Error(t, err)
+
+	_, err = SplitKey(testKey, 2, 3)
+
+	assert.Error(t, err)
+}
+
+func TestKeyFromShareFiles(t *testing.T) {
+	setupAuthTest()
+	t.Cleanup(cleanupAuthTest)
+
+	shares, err := SplitKey(testKey, 3, 2)
+
+	assert.NoError(t, err)
+
+	var shareFiles KeyFromShareFiles
+
+	for s, share := range shares[1:] {
+		fn := filepath.Join(testAuthDir, "share"+string(rune('a'+s)))
+
+		assert.NoError(t, os.WriteFile(fn, []byte(base64.StdEncoding.EncodeToString(share)), 0400))
+
+		shareFiles = append(shareFiles, fn)
+	}
+
+	key, err := shareFiles.GetKey()
+
+	assert.NoError(t, err)
+	assert.Equal(t, testKey, key)
+
+	key, err = KeyFromShares(shares[:2]).GetKey()
+
+	assert.NoError(t, err)
+	assert.Equal(t, testKey, key)
+}
@@ -0,0 +1,84 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+)
+
+// getFlightT is a Get in progress, Gets of the same uri that start while
+// it's in flight wait for its result instead of making their own requests
+type getFlightT struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// sharedGet calls get for uri unless a call for the same uri is already in
+// flight, in which case it waits for that call's result.  uris are compared
+// by cacheKey, so the same query in another order (or differing only by
+// ignored params) is the same uri.  shared reports whether the result came
+// from another call.
+//
+// Low priority calls may be deferred (see SetLowPriorityThreshold) so they
+// have flights of their own that only other low priority calls wait on,
+// while they can wait on any flight.
+func (i *Irdata) sharedGet(ctx context.Context, uri string, get func(context.Context) ([]byte, error)) (data []byte, shared bool, err error) {
+	low := priorityFrom(ctx) < PriorityNormal
+
+	uriKey := i.cacheKey(uri)
+
+	key := uriKey
+
+	if low {
+		key = "low " + uriKey
+	}
+
+	for {
+		i.getFlightsMu.Lock()
+
+		flight, ok := i.getFlights[uriKey]
+		if !ok && low {
+			flight, ok = i.getFlights[key]
+		}
+
+		if !ok {
+			break
+		}
+
+		i.getFlightsMu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		case <-flight.done:
+		}
+
+		// the call we waited on was cancelled but we weren't, make our own
+		if errors.Is(flight.err, context.Canceled) || errors.Is(flight.err, context.DeadlineExceeded) {
+			continue
+		}
+
+		// each caller gets its own copy to do with as it pleases
+		return append([]byte(nil), flight.data...), true, flight.err
+	}
+
+	flight := &getFlightT{done: make(chan struct{})}
+
+	if i.getFlights == nil {
+		i.getFlights = map[string]*getFlightT{}
+	}
+
+	i.getFlights[key] = flight
+
+	i.getFlightsMu.Unlock()
+
+	flight.data, flight.err = get(ctx)
+
+	i.getFlightsMu.Lock()
+	delete(i.getFlights, key)
+	i.getFlightsMu.Unlock()
+
+	close(flight.done)
+
+	return append([]byte(nil), flight.data...), false, flight.err
+}
@@ -0,0 +1,155 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedGet(t *testing.T) {
+	var requests int32
+
+	release := make(chan struct{})
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+
+	var wg sync.WaitGroup
+
+	results := make([][]byte, 5)
+
+	for n := range results {
+		wg.Add(1)
+
+		go func(n int) {
+			defer wg.Done()
+
+			data, err := fake.Get("/data/member/info")
+			assert.NoError(t, err)
+
+			results[n] = data
+		}(n)
+	}
+
+	// let every Get join the one in flight
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	for _, data := range results {
+		assert.JSONEq(t, `{"ok":true}`, string(data))
+	}
+
+	// callers get their own copies
+	results[0][0] = 'x'
+	assert.Equal(t, byte('{'), results[1][0])
+}
+
+func TestSharedGetCacheKey(t *testing.T) {
+	fake := mustOpen()
+
+	fake.SetCacheIgnoredParams("nonce")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go fake.sharedGet(context.Background(), "/uri?a=1&b=2&nonce=1", func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-release
+		return []byte("first"), nil
+	})
+
+	<-started
+
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+
+	// the same query in another order, with a different ignored param
+	data, shared, err := fake.sharedGet(context.Background(), "/uri?nonce=2&b=2&a=1", func(ctx context.Context) ([]byte, error) {
+		return []byte("second"), nil
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, shared)
+	assert.Equal(t, []byte("first"), data)
+}
+
+func TestSharedGetCancelled(t *testing.T) {
+	fake := mustOpen()
+
+	started := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+
+	go fake.sharedGet(ctx, "/uri", func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	<-started
+
+	// the leader is cancelled, a follower that isn't makes its own call
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	data, shared, err := fake.sharedGet(context.Background(), "/uri", func(ctx context.Context) ([]byte, error) {
+		calls++
+		return []byte("mine"), nil
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, shared)
+	assert.Equal(t, "mine", string(data))
+	assert.Equal(t, 1, calls)
+}
+
+func TestSharedGetDeferredLowPriority(t *testing.T) {
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Remaining", "2")
+		w.Header().Set("X-Ratelimit-Reset", fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+
+	fake.SetLowPriorityThreshold(5)
+
+	_, err := fake.Get("/data/constants/divisions")
+	assert.NoError(t, err)
+
+	// a low priority Get is held back until the reset
+	low, cancelLow := context.WithCancel(WithPriority(context.Background(), PriorityLow))
+
+	lowDone := make(chan error)
+
+	go func() {
+		_, err := fake.GetCtx(low, "/data/member/info")
+		lowDone <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	// a high priority Get of the same uri doesn't wait on it
+	high, cancelHigh := context.WithTimeout(WithPriority(context.Background(), PriorityHigh), 2*time.Second)
+	defer cancelHigh()
+
+	data, err := fake.GetCtx(high, "/data/member/info")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+
+	cancelLow()
+
+	assert.ErrorIs(t, <-lowDone, context.Canceled)
+}
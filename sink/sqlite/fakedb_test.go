@@ -0,0 +1,285 @@
+package sqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// fakeDriver is a database/sql driver that understands just the statements
+// the sink sends, backed by tables held in memory.  Each dsn is a database.
+type fakeDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*fakeDB
+}
+
+type fakeDB struct {
+	mu     sync.Mutex
+	tables map[string]*fakeTable
+}
+
+type fakeTable struct {
+	columns []string
+	key     []string
+	rows    []map[string]driver.Value
+}
+
+var fakeSQLite = &fakeDriver{dbs: map[string]*fakeDB{}}
+
+func init() {
+	sql.Register("fakesqlite", fakeSQLite)
+}
+
+// fakeRows returns the rows of table in the database named dsn
+func fakeRows(dsn string, table string) []map[string]driver.Value {
+	db := fakeSQLite.db(dsn)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, ok := db.tables[table]
+	if !ok {
+		return nil
+	}
+
+	return t.rows
+}
+
+func (d *fakeDriver) db(dsn string) *fakeDB {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	db, ok := d.dbs[dsn]
+	if !ok {
+		db = &fakeDB{tables: map[string]*fakeTable{}}
+		d.dbs[dsn] = db
+	}
+
+	return db
+}
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{db: d.db(dsn)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+var (
+	identifierPattern  = regexp.MustCompile(`"((?:[^"]|"")*)"`)
+	createTablePattern = regexp.MustCompile(`^CREATE TABLE IF NOT EXISTS ("(?:[^"]|"")*") \((.*)\)$`)
+	createIndexPattern = regexp.MustCompile(`^CREATE UNIQUE INDEX IF NOT EXISTS "(?:[^"]|"")*" ON ("(?:[^"]|"")*") \((.*)\)$`)
+	alterTablePattern  = regexp.MustCompile(`^ALTER TABLE ("(?:[^"]|"")*") ADD COLUMN ("(?:[^"]|"")*")`)
+	insertPattern      = regexp.MustCompile(`^INSERT INTO ("(?:[^"]|"")*") \((.*?)\) VALUES \(.*?\) ON CONFLICT \((.*?)\) DO (NOTHING|UPDATE SET (.*))$`)
+	pragmaPattern      = regexp.MustCompile(`^SELECT name FROM pragma_table_info\('((?:[^']|'')*)'\)$`)
+)
+
+// identifiers returns the quoted identifiers in s
+func identifiers(s string) []string {
+	var names []string
+
+	for _, match := range identifierPattern.FindAllStringSubmatch(s, -1) {
+		names = append(names, strings.ReplaceAll(match[1], `""`, `"`))
+	}
+
+	return names
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if m := createTablePattern.FindStringSubmatch(s.query); m != nil {
+		table := identifiers(m[1])[0]
+
+		if _, ok := s.db.tables[table]; !ok {
+			var columns []string
+
+			for _, column := range strings.Split(m[2], ", ") {
+				columns = append(columns, identifiers(column)[0])
+			}
+
+			s.db.tables[table] = &fakeTable{columns: columns}
+		}
+
+		return driver.RowsAffected(0), nil
+	}
+
+	if m := createIndexPattern.FindStringSubmatch(s.query); m != nil {
+		t, err := s.table(identifiers(m[1])[0])
+		if err != nil {
+			return nil, err
+		}
+
+		t.key = identifiers(m[2])
+
+		return driver.RowsAffected(0), nil
+	}
+
+	if m := alterTablePattern.FindStringSubmatch(s.query); m != nil {
+		t, err := s.table(identifiers(m[1])[0])
+		if err != nil {
+			return nil, err
+		}
+
+		column := identifiers(m[2])[0]
+
+		if t.has(column) {
+			return nil, fmt.Errorf("duplicate column name: %s", column)
+		}
+
+		t.columns = append(t.columns, column)
+
+		return driver.RowsAffected(0), nil
+	}
+
+	if m := insertPattern.FindStringSubmatch(s.query); m != nil {
+		return s.upsert(identifiers(m[1])[0], identifiers(m[2]), identifiers(m[3]), identifiers(m[5]), args)
+	}
+
+	return nil, fmt.Errorf("fake sqlite doesn't understand %s", s.query)
+}
+
+func (s *fakeStmt) upsert(table string, columns []string, conflict []string, updates []string, args []driver.Value) (driver.Result, error) {
+	t, err := s.table(table)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Join(conflict, ",") != strings.Join(t.key, ",") {
+		return nil, fmt.Errorf("ON CONFLICT clause does not match any UNIQUE constraint")
+	}
+
+	row := map[string]driver.Value{}
+
+	for _, column := range t.columns {
+		row[column] = nil
+	}
+
+	for _, k := range t.key {
+		row[k] = int64(0)
+	}
+
+	for n, column := range columns {
+		if !t.has(column) {
+			return nil, fmt.Errorf("table %s has no column named %s", table, column)
+		}
+
+		row[column] = args[n]
+	}
+
+	for _, existing := range t.rows {
+		if !sameKey(t.key, existing, row) {
+			continue
+		}
+
+		// every other column is updated, excluded.x pairs come in twos
+		for n := 0; n < len(updates); n += 2 {
+			existing[updates[n]] = row[updates[n]]
+		}
+
+		return driver.RowsAffected(1), nil
+	}
+
+	t.rows = append(t.rows, row)
+
+	return driver.RowsAffected(1), nil
+}
+
+func sameKey(key []string, a map[string]driver.Value, b map[string]driver.Value) bool {
+	for _, k := range key {
+		if fmt.Sprint(a[k]) != fmt.Sprint(b[k]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	m := pragmaPattern.FindStringSubmatch(s.query)
+	if m == nil {
+		return nil, fmt.Errorf("fake sqlite doesn't understand %s", s.query)
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	rows := &fakeNameRows{}
+
+	if t, ok := s.db.tables[strings.ReplaceAll(m[1], `''`, `'`)]; ok {
+		rows.names = append(rows.names, t.columns...)
+	}
+
+	return rows, nil
+}
+
+// table returns the named table, the caller must hold db.mu
+func (s *fakeStmt) table(name string) (*fakeTable, error) {
+	t, ok := s.db.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("no such table: %s", name)
+	}
+
+	return t, nil
+}
+
+func (t *fakeTable) has(column string) bool {
+	for _, c := range t.columns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+type fakeNameRows struct {
+	names []string
+	next  int
+}
+
+func (r *fakeNameRows) Columns() []string { return []string{"name"} }
+func (r *fakeNameRows) Close() error      { return nil }
+
+func (r *fakeNameRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.names) {
+		return io.EOF
+	}
+
+	dest[0] = r.names[r.next]
+	r.next++
+
+	return nil
+}
+
+// openFake opens a fresh fake database named name
+func openFake(name string) (*sql.DB, error) {
+	return sql.Open("fakesqlite", name)
+}
@@ -0,0 +1,396 @@
+// Package sqlite stores irdata results in SQLite tables.
+//
+// The package doesn't import a SQLite driver, open the database with the
+// driver of your choice (e.g. github.com/mattn/go-sqlite3 or modernc.org/sqlite)
+// and hand the *sql.DB to New.
+//
+// Results are flattened (nested objects become prefix_name columns) and
+// upserted into the subsessions, results, laps, and standings tables.  Tables
+// gain columns as new fields show up so the schema follows the API.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/popmonkey/irdata"
+)
+
+// table names and the columns that identify a row in each
+var tableKeys = map[string][]string{
+	"subsessions": {"subsession_id"},
+	"results":     {"subsession_id", "simsession_number", "cust_id", "team_id"},
+	"laps":        {"subsession_id", "simsession_number", "cust_id", "lap_number"},
+	"standings":   {"league_id", "season_id", "cust_id", "team_id"},
+}
+
+// Sink upserts results into a SQLite database
+type Sink struct {
+	db *sql.DB
+
+	mu      sync.Mutex
+	columns map[string]map[string]bool
+}
+
+func New(db *sql.DB) *Sink {
+	return &Sink{
+		db:      db,
+		columns: map[string]map[string]bool{},
+	}
+}
+
+// EnsureSchema creates any of the tables that don't exist yet
+func (s *Sink) EnsureSchema(ctx context.Context) error {
+	for table, key := range tableKeys {
+		for _, stmt := range createTableSQL(table, key) {
+			if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("sqlite: unable to create %s [%w]", table, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpsertSubsession stores a /data/results/get result in the subsessions
+// table and each of its session results in the results table
+func (s *Sink) UpsertSubsession(ctx context.Context, data []byte) error {
+	var subsession map[string]interface{}
+
+	if err := json.Unmarshal(data, &subsession); err != nil {
+		return fmt.Errorf("sqlite: unable to decode subsession [%w]", err)
+	}
+
+	subsessionID := subsession["subsession_id"]
+
+	sessionResults, _ := subsession["session_results"].([]interface{})
+
+	delete(subsession, "session_results")
+
+	if err := s.UpsertRows(ctx, "subsessions", []map[string]interface{}{flatten(subsession)}); err != nil {
+		return err
+	}
+
+	var rows []map[string]interface{}
+
+	for _, sessionI := range sessionResults {
+		session, ok := sessionI.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		results, _ := session["results"].([]interface{})
+
+		for _, resultI := range results {
+			result, ok := resultI.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			row := flatten(result)
+
+			row["subsession_id"] = subsessionID
+			row["simsession_number"] = session["simsession_number"]
+
+			rows = append(rows, row)
+		}
+	}
+
+	return s.UpsertRows(ctx, "results", rows)
+}
+
+// UpsertLaps stores the laps of a /data/results/lap_data result
+func (s *Sink) UpsertLaps(ctx context.Context, subsessionID int64, simsessionNumber int, data []byte) error {
+	var raw map[string]interface{}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("sqlite: unable to decode laps [%w]", err)
+	}
+
+	laps, _ := raw[irdata.ChunkDataKey].([]interface{})
+
+	var rows []map[string]interface{}
+
+	for _, lapI := range laps {
+		lap, ok := lapI.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		row := flatten(lap)
+
+		row["subsession_id"] = subsessionID
+		row["simsession_number"] = simsessionNumber
+
+		rows = append(rows, row)
+	}
+
+	return s.UpsertRows(ctx, "laps", rows)
+}
+
+// UpsertStandings stores the driver standings of a
+// /data/league/season_standings result
+func (s *Sink) UpsertStandings(ctx context.Context, data []byte) error {
+	var raw map[string]interface{}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("sqlite: unable to decode standings [%w]", err)
+	}
+
+	standings, _ := raw["standings"].(map[string]interface{})
+	driverStandings, _ := standings["driver_standings"].([]interface{})
+
+	var rows []map[string]interface{}
+
+	for _, standingI := range driverStandings {
+		standing, ok := standingI.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		row := flatten(standing)
+
+		row["league_id"] = raw["league_id"]
+		row["season_id"] = raw["season_id"]
+
+		if custID, ok := row["driver_cust_id"]; ok {
+			row["cust_id"] = custID
+		}
+
+		rows = append(rows, row)
+	}
+
+	return s.UpsertRows(ctx, "standings", rows)
+}
+
+// UpsertRows upserts already flattened rows into table, adding any columns
+// the table doesn't have yet.  Missing key columns are stored as 0, rows
+// isn't modified.
+func (s *Sink) UpsertRows(ctx context.Context, table string, rows []map[string]interface{}) error {
+	key, ok := tableKeys[table]
+	if !ok {
+		return fmt.Errorf("sqlite: unknown table %s", table)
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if err := s.ensureColumns(ctx, table, rows); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: unable to begin [%w]", err)
+	}
+
+	defer tx.Rollback()
+
+	for _, original := range rows {
+		row := make(map[string]interface{}, len(original)+len(key))
+
+		for column, value := range original {
+			row[column] = value
+		}
+
+		for _, k := range key {
+			if row[k] == nil {
+				row[k] = 0
+			}
+		}
+
+		columns := sortedColumns(row)
+
+		args := make([]interface{}, len(columns))
+
+		for n, column := range columns {
+			args[n] = sqlValue(row[column])
+		}
+
+		if _, err := tx.ExecContext(ctx, upsertSQL(table, key, columns), args...); err != nil {
+			return fmt.Errorf("sqlite: unable to upsert into %s [%w]", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite: unable to commit [%w]", err)
+	}
+
+	return nil
+}
+
+// ensureColumns adds the columns in rows that table doesn't have yet
+func (s *Sink) ensureColumns(ctx context.Context, table string, rows []map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	known, ok := s.columns[table]
+	if !ok {
+		var err error
+
+		known, err = s.tableColumns(ctx, table)
+		if err != nil {
+			return err
+		}
+
+		s.columns[table] = known
+	}
+
+	for _, row := range rows {
+		for _, column := range sortedColumns(row) {
+			if known[column] {
+				continue
+			}
+
+			stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", quote(table), quote(column), columnType(row[column]))
+
+			if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("sqlite: unable to add %s.%s [%w]", table, column, err)
+			}
+
+			known[column] = true
+		}
+	}
+
+	return nil
+}
+
+func (s *Sink) tableColumns(ctx context.Context, table string) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT name FROM pragma_table_info(%s)", quoteString(table)))
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: unable to read columns of %s [%w]", table, err)
+	}
+
+	defer rows.Close()
+
+	columns := map[string]bool{}
+
+	for rows.Next() {
+		var name string
+
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("sqlite: unable to read columns of %s [%w]", table, err)
+		}
+
+		columns[name] = true
+	}
+
+	return columns, rows.Err()
+}
+
+func createTableSQL(table string, key []string) []string {
+	columns := make([]string, len(key))
+
+	for n, k := range key {
+		columns[n] = quote(k) + " INTEGER NOT NULL DEFAULT 0"
+	}
+
+	quotedKey := make([]string, len(key))
+
+	for n, k := range key {
+		quotedKey[n] = quote(k)
+	}
+
+	return []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quote(table), strings.Join(columns, ", ")),
+		fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s)", quote(table+"_key"), quote(table), strings.Join(quotedKey, ", ")),
+	}
+}
+
+func upsertSQL(table string, key []string, columns []string) string {
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+
+	isKey := map[string]bool{}
+
+	for _, k := range key {
+		isKey[k] = true
+	}
+
+	var updates []string
+
+	for n, column := range columns {
+		quoted[n] = quote(column)
+		placeholders[n] = "?"
+
+		if !isKey[column] {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", quote(column), quote(column)))
+		}
+	}
+
+	quotedKey := make([]string, len(key))
+
+	for n, k := range key {
+		quotedKey[n] = quote(k)
+	}
+
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO ",
+		quote(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", "), strings.Join(quotedKey, ", "))
+
+	if len(updates) == 0 {
+		return stmt + "NOTHING"
+	}
+
+	return stmt + "UPDATE SET " + strings.Join(updates, ", ")
+}
+
+// flatten turns nested objects into prefix_name columns, arrays are stored
+// as JSON
+func flatten(o map[string]interface{}) map[string]interface{} {
+	return irdata.FlattenRow(o, irdata.FlattenOptions{})
+}
+
+// sqlValue converts a decoded JSON value to something database/sql accepts
+func sqlValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case float64:
+		if value == float64(int64(value)) {
+			return int64(value)
+		}
+		return value
+	case []interface{}, map[string]interface{}:
+		data, _ := json.Marshal(value)
+		return string(data)
+	default:
+		return v
+	}
+}
+
+func columnType(v interface{}) string {
+	switch sqlValue(v).(type) {
+	case int, int64, bool:
+		return "INTEGER"
+	case float64:
+		return "REAL"
+	case nil:
+		return ""
+	default:
+		return "TEXT"
+	}
+}
+
+func sortedColumns(row map[string]interface{}) []string {
+	columns := make([]string, 0, len(row))
+
+	for column := range row {
+		columns = append(columns, column)
+	}
+
+	sort.Strings(columns)
+
+	return columns
+}
+
+func quote(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+func quoteString(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+}
@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlatten(t *testing.T) {
+	row := flatten(map[string]interface{}{
+		"cust_id": 1.0,
+		"car": map[string]interface{}{
+			"car_id": 2.0,
+			"class":  map[string]interface{}{"name": "GT3"},
+		},
+		"helmet": []interface{}{1.0, 2.0},
+	})
+
+	assert.Equal(t, map[string]interface{}{
+		"cust_id":        1.0,
+		"car_car_id":     2.0,
+		"car_class_name": "GT3",
+		"helmet":         "[1,2]",
+	}, row)
+}
+
+func TestSqlValue(t *testing.T) {
+	assert.Equal(t, int64(3), sqlValue(3.0))
+	assert.Equal(t, 3.5, sqlValue(3.5))
+	assert.Equal(t, "[1,2]", sqlValue([]interface{}{1.0, 2.0}))
+	assert.Equal(t, "x", sqlValue("x"))
+
+	assert.Equal(t, "INTEGER", columnType(3.0))
+	assert.Equal(t, "REAL", columnType(3.5))
+	assert.Equal(t, "TEXT", columnType("x"))
+	assert.Equal(t, "INTEGER", columnType(true))
+}
+
+func TestCreateTableSQL(t *testing.T) {
+	assert.Equal(t, []string{
+		`CREATE TABLE IF NOT EXISTS "laps" ("subsession_id" INTEGER NOT NULL DEFAULT 0, "lap_number" INTEGER NOT NULL DEFAULT 0)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS "laps_key" ON "laps" ("subsession_id", "lap_number")`,
+	}, createTableSQL("laps", []string{"subsession_id", "lap_number"}))
+}
+
+func TestUpsertSQL(t *testing.T) {
+	assert.Equal(t,
+		`INSERT INTO "subsessions" ("subsession_id", "track_name") VALUES (?, ?) ON CONFLICT ("subsession_id") DO UPDATE SET "track_name" = excluded."track_name"`,
+		upsertSQL("subsessions", []string{"subsession_id"}, []string{"subsession_id", "track_name"}))
+
+	assert.Equal(t,
+		`INSERT INTO "subsessions" ("subsession_id") VALUES (?) ON CONFLICT ("subsession_id") DO NOTHING`,
+		upsertSQL("subsessions", []string{"subsession_id"}, []string{"subsession_id"}))
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `"a""b"`, quote(`a"b`))
+	assert.Equal(t, `'a''b'`, quoteString(`a'b`))
+}
+
+func TestUpsertRows(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := openFake(t.Name())
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	s := New(db)
+
+	assert.NoError(t, s.EnsureSchema(ctx))
+
+	rows := []map[string]interface{}{
+		{"subsession_id": 1.0, "simsession_number": 0.0, "cust_id": 42.0, "finish_position": 3.0},
+	}
+
+	assert.NoError(t, s.UpsertRows(ctx, "results", rows))
+
+	// the caller's rows are left alone, team_id is only defaulted in the
+	// database
+	assert.Equal(t, []map[string]interface{}{
+		{"subsession_id": 1.0, "simsession_number": 0.0, "cust_id": 42.0, "finish_position": 3.0},
+	}, rows)
+
+	assert.Equal(t, []map[string]driver.Value{
+		{"subsession_id": int64(1), "simsession_number": int64(0), "cust_id": int64(42), "team_id": int64(0), "finish_position": int64(3)},
+	}, fakeRows(t.Name(), "results"))
+
+	// the same row again is updated, with a column the table didn't have
+	assert.NoError(t, s.UpsertRows(ctx, "results", []map[string]interface{}{
+		{"subsession_id": 1.0, "simsession_number": 0.0, "cust_id": 42.0, "finish_position": 1.0, "car_name": "GT3"},
+	}))
+
+	assert.Equal(t, []map[string]driver.Value{
+		{"subsession_id": int64(1), "simsession_number": int64(0), "cust_id": int64(42), "team_id": int64(0), "finish_position": int64(1), "car_name": "GT3"},
+	}, fakeRows(t.Name(), "results"))
+
+	// another driver is a new row
+	assert.NoError(t, s.UpsertRows(ctx, "results", []map[string]interface{}{
+		{"subsession_id": 1.0, "simsession_number": 0.0, "cust_id": 43.0, "finish_position": 2.0},
+	}))
+
+	assert.Len(t, fakeRows(t.Name(), "results"), 2)
+
+	// a new sink on the same database picks up the columns already added
+	assert.NoError(t, New(db).UpsertRows(ctx, "results", []map[string]interface{}{
+		{"subsession_id": 1.0, "simsession_number": 0.0, "cust_id": 43.0, "car_name": "LMP2"},
+	}))
+
+	assert.Equal(t, "LMP2", fakeRows(t.Name(), "results")[1]["car_name"])
+
+	assert.Error(t, s.UpsertRows(ctx, "nope", rows))
+}
+
+func TestUpsertSubsession(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := openFake(t.Name())
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	s := New(db)
+
+	assert.NoError(t, s.EnsureSchema(ctx))
+
+	assert.NoError(t, s.UpsertSubsession(ctx, []byte(`{
+		"subsession_id": 7,
+		"track": {"track_name": "Spa"},
+		"session_results": [
+			{"simsession_number": 0, "results": [{"cust_id": 42, "finish_position": 0}]}
+		]
+	}`)))
+
+	assert.Equal(t, []map[string]driver.Value{
+		{"subsession_id": int64(7), "track_track_name": "Spa"},
+	}, fakeRows(t.Name(), "subsessions"))
+
+	assert.Equal(t, []map[string]driver.Value{
+		{"subsession_id": int64(7), "simsession_number": int64(0), "cust_id": int64(42), "team_id": int64(0), "finish_position": int64(0)},
+	}, fakeRows(t.Name(), "results"))
+}
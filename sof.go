@@ -0,0 +1,130 @@
+package irdata
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// SOF is the strength of field of a subsession, overall and per car class
+type SOF struct {
+	Overall int
+	ByClass map[int64]int // keyed by car_class_id
+}
+
+// StrengthOfField computes the strength of field of iRatings using the
+// standard formula:
+//
+//	1600 / ln(2) * ln(n / sum(e^(-iRating * ln(2) / 1600)))
+//
+// iRatings that aren't positive (unrated entries) are skipped.
+func StrengthOfField(iRatings []int) int {
+	n := 0
+	sum := 0.0
+
+	for _, iRating := range iRatings {
+		if iRating <= 0 {
+			continue
+		}
+
+		n++
+		sum += math.Exp(-float64(iRating) * math.Ln2 / 1600)
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	return int(math.Round(1600 / math.Ln2 * math.Log(float64(n)/sum)))
+}
+
+// resultT is the part of a result row the analysis helpers use
+type resultT struct {
+	CustID        int64     `json:"cust_id"`
+	DisplayName   string    `json:"display_name"`
+	CarClassID    int64     `json:"car_class_id"`
+	OldiRating    int       `json:"oldi_rating"`
+	Incidents     int       `json:"incidents"`
+	LapsComplete  int       `json:"laps_complete"`
+	ReasonOut     string    `json:"reason_out"`
+	DriverResults []resultT `json:"driver_results"`
+}
+
+// SubsessionSOF computes the strength of field of a /data/results/get result
+// from the iRatings entries had going into the main event (simsession 0).
+// In team events a team counts with the average iRating of its drivers.
+func SubsessionSOF(data []byte) (SOF, error) {
+	results, err := mainEventResults(data)
+	if err != nil {
+		return SOF{}, err
+	}
+
+	return resultsSOF(results), nil
+}
+
+func resultsSOF(results []resultT) SOF {
+	sof := SOF{ByClass: map[int64]int{}}
+
+	var all []int
+
+	byClass := map[int64][]int{}
+
+	for _, result := range results {
+		iRating := entryiRating(result)
+
+		all = append(all, iRating)
+		byClass[result.CarClassID] = append(byClass[result.CarClassID], iRating)
+	}
+
+	sof.Overall = StrengthOfField(all)
+
+	for carClassID, iRatings := range byClass {
+		sof.ByClass[carClassID] = StrengthOfField(iRatings)
+	}
+
+	return sof
+}
+
+// mainEventResults returns the results of simsession 0 of a
+// /data/results/get result
+func mainEventResults(data []byte) ([]resultT, error) {
+	var subsession struct {
+		SessionResults []struct {
+			SimsessionNumber int       `json:"simsession_number"`
+			Results          []resultT `json:"results"`
+		} `json:"session_results"`
+	}
+
+	if err := json.Unmarshal(data, &subsession); err != nil {
+		return nil, makeErrorf("unable to decode subsession [%v]", err)
+	}
+
+	for _, session := range subsession.SessionResults {
+		if session.SimsessionNumber == 0 {
+			return session.Results, nil
+		}
+	}
+
+	return nil, makeErrorf("subsession has no main event results")
+}
+
+func entryiRating(result resultT) int {
+	if len(result.DriverResults) == 0 {
+		return result.OldiRating
+	}
+
+	n := 0
+	sum := 0
+
+	for _, driver := range result.DriverResults {
+		if driver.OldiRating > 0 {
+			n++
+			sum += driver.OldiRating
+		}
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	return sum / n
+}
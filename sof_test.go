@@ -0,0 +1,41 @@
+package irdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrengthOfField(t *testing.T) {
+	assert.Equal(t, 0, StrengthOfField(nil))
+	assert.Equal(t, 2000, StrengthOfField([]int{2000, 2000, 2000}))
+	assert.Equal(t, 2000, StrengthOfField([]int{2000, -1, 0}))
+
+	// lower ratings pull the SOF down more than higher ones push it up
+	sof := StrengthOfField([]int{1000, 3000})
+	assert.Less(t, sof, 2000)
+	assert.Greater(t, sof, 1000)
+}
+
+func TestSubsessionSOF(t *testing.T) {
+	data := []byte(`{"session_results":[
+		{"simsession_number":-1,"results":[{"car_class_id":1,"oldi_rating":9999}]},
+		{"simsession_number":0,"results":[
+			{"car_class_id":1,"oldi_rating":3000},
+			{"car_class_id":1,"oldi_rating":3000},
+			{"car_class_id":2,"oldi_rating":-1,"driver_results":[{"oldi_rating":1000},{"oldi_rating":2000},{"oldi_rating":-1}]}
+		]}
+	]}`)
+
+	sof, err := SubsessionSOF(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, StrengthOfField([]int{3000, 3000, 1500}), sof.Overall)
+	assert.Equal(t, map[int64]int{1: 3000, 2: 1500}, sof.ByClass)
+
+	_, err = SubsessionSOF([]byte(`{"session_results":[]}`))
+	assert.Error(t, err)
+
+	_, err = SubsessionSOF([]byte(`{`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+package irdata
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Split is one of the subsessions an official session was split into
+type Split struct {
+	Number       int // 1 is the top split
+	SubsessionID int64
+	SOF          SOF
+	Drivers      []int64
+	// the range of iRatings in the split
+	MiniRating int
+	MaxiRating int
+}
+
+// SplitAnalysis is the splits of an official session
+type SplitAnalysis struct {
+	SeriesID    int64
+	StartTime   time.Time
+	Splits      []Split
+	DriverSplit map[int64]int // cust_id to split number
+}
+
+// AnalyzeSplits finds the splits of the session of seriesID that started at
+// startTime and works out the SOF, drivers, and iRating range of each.
+// Splits are numbered by SOF, highest first.
+func (i *Irdata) AnalyzeSplits(seriesID int64, startTime time.Time) (SplitAnalysis, error) {
+	analysis := SplitAnalysis{SeriesID: seriesID, StartTime: startTime}
+
+	query := url.Values{}
+
+	query.Set("series_id", fmt.Sprint(seriesID))
+	query.Set("start_range_begin", startTime.UTC().Format("2006-01-02T15:04Z"))
+	query.Set("start_range_end", startTime.Add(time.Minute).UTC().Format("2006-01-02T15:04Z"))
+	query.Set("official_only", "true")
+
+	data, err := i.Get("/data/results/search_series?" + query.Encode())
+	if err != nil {
+		return analysis, err
+	}
+
+	rows, err := chunkRows(data)
+	if err != nil {
+		return analysis, err
+	}
+
+	subsessions := map[int64][]resultT{}
+
+	for _, row := range rows {
+		subsessionIDf, ok := row["subsession_id"].(float64)
+		if !ok {
+			continue
+		}
+
+		subsessionID := int64(subsessionIDf)
+
+		if _, ok := subsessions[subsessionID]; ok {
+			continue
+		}
+
+		data, err := i.Get(fmt.Sprintf("/data/results/get?subsession_id=%d", subsessionID))
+		if err != nil {
+			return analysis, err
+		}
+
+		results, err := mainEventResults(data)
+		if err != nil {
+			return analysis, err
+		}
+
+		subsessions[subsessionID] = results
+	}
+
+	analysis.Splits, analysis.DriverSplit = makeSplits(subsessions)
+
+	return analysis, nil
+}
+
+func makeSplits(subsessions map[int64][]resultT) ([]Split, map[int64]int) {
+	splits := make([]Split, 0, len(subsessions))
+
+	for subsessionID, results := range subsessions {
+		split := Split{
+			SubsessionID: subsessionID,
+			SOF:          resultsSOF(results),
+		}
+
+		for _, result := range results {
+			drivers := result.DriverResults
+			if len(drivers) == 0 {
+				drivers = []resultT{result}
+			}
+
+			for _, driver := range drivers {
+				split.Drivers = append(split.Drivers, driver.CustID)
+
+				if driver.OldiRating <= 0 {
+					continue
+				}
+
+				if split.MiniRating == 0 || driver.OldiRating < split.MiniRating {
+					split.MiniRating = driver.OldiRating
+				}
+
+				if driver.OldiRating > split.MaxiRating {
+					split.MaxiRating = driver.OldiRating
+				}
+			}
+		}
+
+		splits = append(splits, split)
+	}
+
+	sort.Slice(splits, func(a, b int) bool {
+		if splits[a].SOF.Overall != splits[b].SOF.Overall {
+			return splits[a].SOF.Overall > splits[b].SOF.Overall
+		}
+
+		return splits[a].SubsessionID < splits[b].SubsessionID
+	})
+
+	driverSplit := map[int64]int{}
+
+	for n := range splits {
+		splits[n].Number = n + 1
+
+		for _, custID := range splits[n].Drivers {
+			driverSplit[custID] = n + 1
+		}
+	}
+
+	return splits, driverSplit
+}
@@ -0,0 +1,52 @@
+package irdata
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzeSplits(t *testing.T) {
+	startTime := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/results/search_series":
+			assert.Equal(t, "7", r.URL.Query().Get("series_id"))
+			assert.Equal(t, "2024-06-01T18:00Z", r.URL.Query().Get("start_range_begin"))
+
+			fmt.Fprint(w, `{"data":{"_chunk_data":[{"subsession_id":1},{"subsession_id":2},{"subsession_id":1}]}}`)
+		case "/data/results/get":
+			switch r.URL.Query().Get("subsession_id") {
+			case "1":
+				fmt.Fprint(w, `{"session_results":[{"simsession_number":0,"results":[
+					{"cust_id":10,"oldi_rating":1500},{"cust_id":11,"oldi_rating":1700}]}]}`)
+			case "2":
+				fmt.Fprint(w, `{"session_results":[{"simsession_number":0,"results":[
+					{"cust_id":20,"oldi_rating":3000},{"cust_id":21,"oldi_rating":2500},{"cust_id":22,"oldi_rating":-1}]}]}`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	analysis, err := fake.AnalyzeSplits(7, startTime)
+
+	assert.NoError(t, err)
+	assert.Len(t, analysis.Splits, 2)
+
+	top := analysis.Splits[0]
+	assert.Equal(t, 1, top.Number)
+	assert.Equal(t, int64(2), top.SubsessionID)
+	assert.Equal(t, 2500, top.MiniRating)
+	assert.Equal(t, 3000, top.MaxiRating)
+	assert.Equal(t, StrengthOfField([]int{3000, 2500}), top.SOF.Overall)
+
+	assert.Equal(t, int64(1), analysis.Splits[1].SubsessionID)
+	assert.Equal(t, 1500, analysis.Splits[1].MiniRating)
+
+	assert.Equal(t, map[int64]int{10: 2, 11: 2, 20: 1, 21: 1, 22: 1}, analysis.DriverSplit)
+}
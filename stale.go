@@ -0,0 +1,36 @@
+package irdata
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+// SetStaleCacheFallback keeps entries in the cache for up to maxStale past
+// their ttl so that, when refreshing one fails because iRacing is down (a
+// 5xx or a network error) or rate limiting, GetWithCache returns the expired
+// data along with a *StaleDataError instead of failing.  Only entries cached
+// after it's set are kept.  Zero (the default) turns it off.
+func (i *Irdata) SetStaleCacheFallback(maxStale time.Duration) {
+	i.cacheMaxStale = maxStale
+}
+
+// isUpstreamFailure is true for errors that mean iRacing couldn't be reached
+// or wouldn't answer, rather than something wrong with the request
+func isUpstreamFailure(err error) bool {
+	var statusErr *StatusError
+	var rateLimitErr *RateLimitExceededError
+	var urlErr *url.Error
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case errors.As(err, &statusErr), errors.As(err, &rateLimitErr), errors.Is(err, ErrCircuitOpen):
+		return true
+	case errors.As(err, &urlErr):
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+package irdata
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleCacheFallback(t *testing.T) {
+	down := false
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+
+	fake.SetRetryPolicy(BackoffRetryPolicy{MaxAttempts: 1})
+
+	assert.NoError(t, fake.EnableCache(filepath.Join(t.TempDir(), "cache")))
+	t.Cleanup(fake.Close)
+
+	ttl := 10 * time.Millisecond
+
+	// without the fallback
+	_, err := fake.GetWithCache("/data/member/info", ttl)
+	assert.NoError(t, err)
+
+	time.Sleep(2 * ttl)
+
+	down = true
+
+	_, err = fake.GetWithCache("/data/member/info", ttl)
+
+	var statusErr *StatusError
+
+	if assert.True(t, errors.As(err, &statusErr)) {
+		assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	}
+
+	// with it
+	fake.SetStaleCacheFallback(time.Hour)
+
+	down = false
+
+	_, err = fake.GetWithCache("/data/member/info", ttl)
+	assert.NoError(t, err)
+
+	time.Sleep(2 * ttl)
+
+	down = true
+
+	data, err := fake.GetWithCache("/data/member/info", ttl)
+
+	var staleErr *StaleDataError
+
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+
+	if assert.True(t, errors.As(err, &staleErr)) {
+		assert.True(t, errors.As(err, &statusErr))
+		assert.False(t, staleErr.Expired.IsZero())
+	}
+
+	// fresh data once iRacing is back
+	down = false
+
+	data, err = fake.GetWithCache("/data/member/info", ttl)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(data))
+}
+
+func TestCacheEntryEncoding(t *testing.T) {
+	expires := time.Unix(1717243200, 42)
+
+	entry, compression := decodeCacheEntry(encodeCacheEntry(cacheEntryT{data: []byte(`{}`), expires: expires}, "gzip"))
+
+	assert.Equal(t, `{}`, string(entry.data))
+	assert.Equal(t, "gzip", compression)
+	assert.True(t, expires.Equal(entry.expires))
+
+	// written before entries had a header
+	entry, compression = decodeCacheEntry([]byte(`{"old":true}`))
+
+	assert.Equal(t, "", compression)
+
+	assert.Equal(t, `{"old":true}`, string(entry.data))
+	assert.True(t, entry.expires.IsZero())
+	assert.False(t, entry.stale(time.Now()))
+}
+
+func TestIsUpstreamFailure(t *testing.T) {
+	assert.True(t, isUpstreamFailure(&StatusError{StatusCode: 502}))
+	assert.True(t, isUpstreamFailure(ErrCircuitOpen))
+	assert.True(t, isUpstreamFailure(&RateLimitExceededError{}))
+	assert.False(t, isUpstreamFailure(ErrUnauthorized))
+	assert.False(t, isUpstreamFailure(errors.New("bad json")))
+}
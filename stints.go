@@ -0,0 +1,161 @@
+package irdata
+
+import (
+	"sort"
+	"time"
+)
+
+// Stint is a run of laps between pit stops
+type Stint struct {
+	Number      int // from 1
+	StartLap    int
+	EndLap      int
+	Laps        int
+	AveragePace time.Duration // of the valid laps, excluding in and out laps
+	BestLap     time.Duration
+}
+
+// PitStop is a lap on which a car pitted.  TimeLost is the time the in and
+// out laps took over two typical (median) laps, an estimate of the cost of
+// the stop.
+type PitStop struct {
+	Lap      int
+	TimeLost time.Duration
+}
+
+// StintAnalysis is the stints and pit stops of a car
+type StintAnalysis struct {
+	Stints   []Stint
+	PitStops []PitStop
+	// the longest stint that ended in a pit stop, an estimate of how many
+	// laps a full tank lasts (zero if the car never pitted)
+	FuelWindowLaps int
+}
+
+// AnalyzeStints splits the laps of a single car (see LapsOf) into stints at
+// each pit stop.  Lap 0 (the start) isn't part of any stint.
+func AnalyzeStints(laps []Lap) StintAnalysis {
+	var analysis StintAnalysis
+
+	sorted := append([]Lap{}, laps...)
+
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].LapNumber < sorted[b].LapNumber })
+
+	var racing []Lap
+
+	for _, lap := range sorted {
+		if lap.LapNumber > 0 {
+			racing = append(racing, lap)
+		}
+	}
+
+	if len(racing) == 0 {
+		return analysis
+	}
+
+	median := medianLapTime(racing)
+
+	var current []Lap
+
+	endStint := func() {
+		if len(current) == 0 {
+			return
+		}
+
+		analysis.Stints = append(analysis.Stints, makeStint(len(analysis.Stints)+1, current))
+		current = nil
+	}
+
+	for n, lap := range racing {
+		current = append(current, lap)
+
+		if !lap.HasEvent(LapEventPitted) {
+			continue
+		}
+
+		stop := PitStop{Lap: lap.LapNumber}
+
+		if median > 0 && lap.LapTime > 0 && n+1 < len(racing) && racing[n+1].LapTime > 0 {
+			stop.TimeLost = lap.LapTime + racing[n+1].LapTime - 2*median
+		}
+
+		analysis.PitStops = append(analysis.PitStops, stop)
+
+		if len(current) > analysis.FuelWindowLaps {
+			analysis.FuelWindowLaps = len(current)
+		}
+
+		endStint()
+	}
+
+	endStint()
+
+	return analysis
+}
+
+func makeStint(number int, laps []Lap) Stint {
+	stint := Stint{
+		Number:   number,
+		StartLap: laps[0].LapNumber,
+		EndLap:   laps[len(laps)-1].LapNumber,
+		Laps:     len(laps),
+	}
+
+	var total time.Duration
+
+	timed := 0
+
+	for n, lap := range laps {
+		// the out lap (unless it's the first stint) and the in lap are slow
+		if (n == 0 && number > 1) || lap.HasEvent(LapEventPitted) || !lap.Valid() {
+			continue
+		}
+
+		total += lap.LapTime
+		timed++
+
+		if stint.BestLap == 0 || lap.LapTime < stint.BestLap {
+			stint.BestLap = lap.LapTime
+		}
+	}
+
+	if timed > 0 {
+		stint.AveragePace = total / time.Duration(timed)
+	}
+
+	return stint
+}
+
+// medianLapTime returns the median of the valid lap times of laps, leaving
+// out in and out laps
+func medianLapTime(laps []Lap) time.Duration {
+	var times []time.Duration
+
+	for n, lap := range laps {
+		outLap := n > 0 && laps[n-1].HasEvent(LapEventPitted)
+
+		if lap.Valid() && !lap.HasEvent(LapEventPitted) && !outLap {
+			times = append(times, lap.LapTime)
+		}
+	}
+
+	return medianDuration(times)
+}
+
+func medianDuration(times []time.Duration) time.Duration {
+	if len(times) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration{}, times...)
+
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+
+	middle := len(sorted) / 2
+
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
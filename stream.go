@@ -0,0 +1,104 @@
+package irdata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetStream is Get for results too big to hold in memory: rather than read
+// the data the uri's s3 link (or data url) points at, it returns its body for
+// the caller to read, e.g. straight to a file, and close.  If the API returns
+// the data itself, without a link, that is returned instead.
+//
+// Unlike Get, chunks aren't fetched: a chunked response is streamed as is,
+// chunk_info and all.  SetMaxResponseBytes doesn't apply to the stream.
+func (i *Irdata) GetStream(uri string) (io.ReadCloser, error) {
+	return i.GetStreamCtx(context.Background(), uri)
+}
+
+// GetStreamCtx is GetStream with a context.  The stream is read with ctx too,
+// so it must not be cancelled until the caller is done reading.
+func (i *Irdata) GetStreamCtx(ctx context.Context, uri string) (io.ReadCloser, error) {
+	ctx, span := i.startSpan(ctx, TraceGet, map[string]any{"uri": uri, "stream": true})
+
+	stream, err := i.getStream(ctx, uri)
+
+	span.End(err)
+
+	return stream, err
+}
+
+func (i *Irdata) getStream(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if err := i.ensureAuthed(ctx); err != nil {
+		return nil, err
+	}
+
+	uriRef, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	url := i.baseURL.ResolveReference(uriRef)
+
+	for attempt := 1; ; attempt++ {
+		stream, err := i.streamLinked(ctx, url.String())
+
+		if !errors.Is(err, ErrLinkExpired) || attempt >= _maxLinkRefetches {
+			return stream, err
+		}
+
+		log.WithFields(logrus.Fields{"url": url, "attempt": attempt}).Warn("Link expired, fetching a new one")
+	}
+}
+
+// streamLinked is getLinked that returns the linked body instead of reading it
+func (i *Irdata) streamLinked(ctx context.Context, url string) (io.ReadCloser, error) {
+	log.WithFields(logrus.Fields{"url": url}).Debug("Streaming")
+
+	resp, err := i.authedGet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &StatusError{URL: redactString(url), StatusCode: resp.StatusCode}
+	}
+
+	data, err := readBody(ctx, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	link := linkIn(data)
+	if link == "" {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	linkResp, err := i.retryingDownload(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	if linkResp.StatusCode == http.StatusForbidden {
+		drainAndClose(linkResp)
+
+		return nil, ErrLinkExpired
+	}
+
+	// an error body isn't the data, don't let it end up in the caller's file
+	if linkResp.StatusCode != http.StatusOK {
+		drainAndClose(linkResp)
+
+		return nil, makeErrorf("unexpected status %d following link", linkResp.StatusCode)
+	}
+
+	return linkResp.Body, nil
+}
@@ -0,0 +1,61 @@
+package irdata
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStream(t *testing.T) {
+	var linkBase string
+
+	links := 0
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/member/info":
+			links++
+			fmt.Fprintf(w, `{"link":"%s/s3/%d"}`, linkBase, links)
+		case "/data/constants/divisions":
+			fmt.Fprint(w, `[{"id":1}]`)
+		case "/s3/1":
+			w.WriteHeader(http.StatusForbidden)
+		case "/s3/2":
+			fmt.Fprint(w, `{"ok":true}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	linkBase = fake.baseURL.String()
+
+	// follows the link, fetching a new one when it has expired
+	stream, err := fake.GetStream("/data/member/info")
+	if assert.NoError(t, err) {
+		data, err := io.ReadAll(stream)
+		stream.Close()
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"ok":true}`, string(data))
+		assert.Equal(t, 2, links)
+	}
+
+	// no link, the data is in the response
+	stream, err = fake.GetStream("/data/constants/divisions")
+	if assert.NoError(t, err) {
+		data, _ := io.ReadAll(stream)
+		stream.Close()
+
+		assert.JSONEq(t, `[{"id":1}]`, string(data))
+	}
+
+	// error bodies aren't streamed
+	_, err = fake.GetStream("/data/member/info")
+	assert.Error(t, err)
+
+	_, err = mustOpen().GetStream("/data/member/info")
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+package irdata
+
+import (
+	"os"
+)
+
+// creds files are written with these perms in strict security mode
+const _strictFileMode = 0600
+
+// SetStrictSecurity turns strict security mode on or off.  This is meant for
+// compliance sensitive deployments and fails closed:
+//
+//   - key files (and key share files) must be regular files (not symlinks)
+//     with 0400 perms that are owned by the current user, whatever the key
+//     file permission policy
+//   - creds files must not be accessible by group or others and are written
+//     with 0600 perms
+//   - on Windows, which has no unix perms, both must instead have ACLs that
+//     don't let Everyone, Users, Authenticated Users, or Guests read them
+//   - the cache, which is stored unencrypted, can't be enabled (and is
+//     closed if it already was)
+//   - secure memory mode is turned on (see SetSecureMemory)
+//
+// irdata only ever authenticates with the credentials it's given, so there is
+// no fallback authentication to disable.
+func (i *Irdata) SetStrictSecurity(enabled bool) {
+	i.strictSecurity = enabled
+
+	if !enabled {
+		return
+	}
+
+	i.secureMemory = true
+
+	if i.cask != nil {
+		log.Warn("Closing the cache, it's not encrypted and can't be used in strict security mode")
+
+		i.StopCacheRefresh()
+		i.cacheClose()
+		i.cask = nil
+	}
+}
+
+// checkStrictFile verifies filename is a regular file, owned by us, and not
+// accessible by others
+func checkStrictFile(filename string) error {
+	stat, err := os.Lstat(filename)
+	if err != nil {
+		return makeErrorf("unable to stat %s [%v]", filename, err)
+	}
+
+	if !stat.Mode().IsRegular() {
+		return makeErrorf("%s must be a regular file in strict security mode", filename)
+	}
+
+	return checkStrictAccess(filename, stat)
+}
+
+// checkStrictKeyFile verifies filename is a regular file owned by us with
+// 0400 perms (or, on Windows, an ACL that doesn't let broad groups read it)
+// whatever the key file permission policy
+func checkStrictKeyFile(filename string) error {
+	if err := checkStrictFile(filename); err != nil {
+		return err
+	}
+
+	return checkKeyFilePerms(filename)
+}
+
+// strictKeyFilesT checks the files its provider reads keys from before it
+// reads them
+type strictKeyFilesT struct {
+	filenames []string
+	provider  KeyProvider
+}
+
+func (k strictKeyFilesT) GetKey() ([]byte, error) {
+	for _, filename := range k.filenames {
+		if err := checkStrictKeyFile(filename); err != nil {
+			return nil, err
+		}
+	}
+
+	return k.provider.GetKey()
+}
+
+// strictKeys makes keyProvider check the files it reads keys (or key shares)
+// from in strict security mode.  Keys from elsewhere are left to their
+// providers.
+func strictKeys(keyProvider KeyProvider, strict bool) KeyProvider {
+	if !strict {
+		return keyProvider
+	}
+
+	switch p := keyProvider.(type) {
+	case KeyFromFile:
+		return strictKeyFilesT{filenames: []string{string(p)}, provider: p}
+	case keyFileT:
+		return strictKeyFilesT{filenames: []string{p.filename}, provider: p}
+	case KeyFromShareFiles:
+		return strictKeyFilesT{filenames: p, provider: p}
+	}
+
+	return keyProvider
+}
+
+// checkStrictPerms checks filename's unix perms don't let group or others in
+func checkStrictPerms(filename string, stat os.FileInfo) error {
+	if stat.Mode().Perm()&0077 != 0 {
+		return makeErrorf("%s must not be accessible by group or others in strict security mode", filename)
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+package irdata
+
+import (
+	"os"
+)
+
+// file ownership isn't exposed by os.FileInfo here
+func checkStrictAccess(filename string, stat os.FileInfo) error {
+	return checkStrictPerms(filename, stat)
+}
@@ -0,0 +1,33 @@
+package irdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrictSecurityCache(t *testing.T) {
+	strict := mustOpen()
+
+	strict.SetStrictSecurity(true)
+
+	assert.True(t, strict.secureMemory)
+	assert.Error(t, strict.EnableCache(testCacheDir))
+}
+
+func TestStrictSecurityAfterCache(t *testing.T) {
+	strict := mustOpen()
+
+	assert.NoError(t, strict.EnableCache(t.TempDir()))
+
+	strict.SetStrictSecurity(true)
+
+	assert.Nil(t, strict.cask)
+
+	_, err := strict.GetWithCache("/data/member/info", time.Hour)
+	assert.Error(t, err)
+
+	// closing again is fine
+	strict.Close()
+}
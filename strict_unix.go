@@ -0,0 +1,25 @@
+//go:build !windows && !plan9
+
+package irdata
+
+import (
+	"os"
+	"syscall"
+)
+
+func checkStrictAccess(filename string, stat os.FileInfo) error {
+	if err := checkStrictPerms(filename, stat); err != nil {
+		return err
+	}
+
+	sys, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return makeErrorf("unable to determine the owner of %s", filename)
+	}
+
+	if int(sys.Uid) != os.Getuid() {
+		return makeErrorf("%s must be owned by the current user in strict security mode", filename)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+//go:build !windows && !plan9
+
+package irdata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckStrictFile(t *testing.T) {
+	setupAuthTest()
+	t.Cleanup(cleanupAuthTest)
+
+	assert.NoError(t, checkStrictFile(testKeyFilename))
+
+	loose := filepath.Join(testAuthDir, "loose")
+
+	assert.NoError(t, os.WriteFile(loose, []byte("x"), 0644))
+	assert.NoError(t, os.Chmod(loose, 0644))
+	assert.Error(t, checkStrictFile(loose))
+
+	link := filepath.Join(testAuthDir, "link")
+
+	assert.NoError(t, os.Symlink(loose, link))
+	assert.Error(t, checkStrictFile(link))
+}
+
+func TestStrictWriteCreds(t *testing.T) {
+	setupAuthTest()
+	t.Cleanup(cleanupAuthTest)
+
+	credsFn := filepath.Join(testAuthDir, "strict.creds")
+
+	// a pre-existing world readable file gets locked down
+	assert.NoError(t, os.WriteFile(credsFn, []byte("x"), 0644))
+
+	opts := fileOptsT{strict: true}
+
+	assert.NoError(t, writeCreds(KeyFromFile(testKeyFilename), credsFn, authDataT{Username: string(testUsername)}, opts))
+
+	stat, err := os.Stat(credsFn)
+
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(_strictFileMode), stat.Mode().Perm())
+
+	authData, err := readCreds(KeyFromFile(testKeyFilename), credsFn, opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, string(testUsername), authData.Username)
+}
+
+func TestStrictKeyFile(t *testing.T) {
+	setupAuthTest()
+	t.Cleanup(cleanupAuthTest)
+
+	credsFn := filepath.Join(testAuthDir, "strict.creds")
+
+	assert.NoError(t, writeCreds(KeyFromFile(testKeyFilename), credsFn, authDataT{Username: string(testUsername)}, fileOptsT{}))
+
+	// 0600 isn't enough for a key file, even when the policy says to ignore
+	// its perms
+	loose := filepath.Join(testAuthDir, "loose.key")
+
+	key, err := os.ReadFile(testKeyFilename)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(loose, key, 0600))
+
+	client := mustOpen()
+
+	client.SetKeyFilePermissionPolicy(KeyFilePermsIgnore)
+	client.SetStrictSecurity(true)
+
+	assert.Error(t, client.AuthWithCredsFromFile(loose, credsFn))
+	assert.Error(t, client.AuthWithCredsFromFileWithKey(KeyFromFile(loose), credsFn))
+	assert.Error(t, client.RotateKey(loose, testKeyFilename, credsFn))
+	assert.Error(t, client.RotateKeyWithKeys(KeyFromFile(testKeyFilename), KeyFromFile(loose), credsFn))
+
+	// left alone by the failed rotations
+	_, err = readCreds(KeyFromFile(testKeyFilename), credsFn, fileOptsT{strict: true})
+	assert.NoError(t, err)
+
+	_, err = strictKeys(KeyFromShareFiles{loose}, true).GetKey()
+	assert.Error(t, err)
+
+	// not strict, the policy applies
+	client.SetStrictSecurity(false)
+
+	_, err = readCreds(client.keyFromFile(loose), credsFn, client.fileOpts())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,69 @@
+package irdata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/windows"
+)
+
+// setACL replaces filename's ACL with one granting the current user full
+// access and, if everyone, Everyone read access
+func setACL(t *testing.T, filename string, everyone bool) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	assert.NoError(t, err)
+
+	entries := []windows.EXPLICIT_ACCESS{{
+		AccessPermissions: windows.GENERIC_ALL,
+		AccessMode:        windows.GRANT_ACCESS,
+		Trustee: windows.TRUSTEE{
+			TrusteeForm:  windows.TRUSTEE_IS_SID,
+			TrusteeType:  windows.TRUSTEE_IS_USER,
+			TrusteeValue: windows.TrusteeValueFromSID(user.User.Sid),
+		},
+	}}
+
+	if everyone {
+		sid, err := windows.CreateWellKnownSid(windows.WinWorldSid)
+		assert.NoError(t, err)
+
+		entries = append(entries, windows.EXPLICIT_ACCESS{
+			AccessPermissions: windows.GENERIC_READ,
+			AccessMode:        windows.GRANT_ACCESS,
+			Trustee: windows.TRUSTEE{
+				TrusteeForm:  windows.TRUSTEE_IS_SID,
+				TrusteeType:  windows.TRUSTEE_IS_WELL_KNOWN_GROUP,
+				TrusteeValue: windows.TrusteeValueFromSID(sid),
+			},
+		})
+	}
+
+	acl, err := windows.ACLFromEntries(entries, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, windows.SetNamedSecurityInfo(filename, windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION, nil, nil, acl, nil))
+}
+
+func TestCheckStrictFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// Go reports 0666 for this but only we can read it
+	private := filepath.Join(dir, "private")
+
+	assert.NoError(t, os.WriteFile(private, []byte("x"), _strictFileMode))
+
+	setACL(t, private, false)
+
+	assert.NoError(t, checkStrictFile(private))
+
+	shared := filepath.Join(dir, "shared")
+
+	assert.NoError(t, os.WriteFile(shared, []byte("x"), _strictFileMode))
+
+	setACL(t, shared, true)
+
+	assert.Error(t, checkStrictFile(shared))
+}
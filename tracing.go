@@ -0,0 +1,48 @@
+package irdata
+
+import (
+	"context"
+)
+
+// Operations reported to a Tracer
+const (
+	TraceGet          = "irdata.Get"          // attributes: uri, shared or stream
+	TraceGetWithCache = "irdata.GetWithCache" // attributes: uri, cache.hit
+	TraceAuth         = "irdata.auth"         // attributes: renewing
+	TraceFetch        = "irdata.fetch"        // attributes: url (redacted), host, http.status_code
+	TraceChunks       = "irdata.chunks"       // attributes: chunk_count, chunk_failures
+)
+
+// Tracer is told about the operations irdata performs so they can show up
+// in traces (e.g. as OpenTelemetry spans) without irdata depending on a
+// tracing library.  Start begins an operation as a child of whatever span
+// ctx carries and returns the context for its own children.
+type Tracer interface {
+	Start(ctx context.Context, operation string, attributes map[string]any) (context.Context, Span)
+}
+
+// Span is an operation started by a Tracer
+type Span interface {
+	SetAttribute(key string, value any)
+	// End finishes the operation, err is why it failed (nil if it didn't)
+	End(err error)
+}
+
+// SetTracer sets the Tracer operations are reported to, nil (the default)
+// turns tracing off
+func (i *Irdata) SetTracer(tracer Tracer) {
+	i.tracer = tracer
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, any) {}
+func (noopSpan) End(error)                {}
+
+func (i *Irdata) startSpan(ctx context.Context, operation string, attributes map[string]any) (context.Context, Span) {
+	if i.tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	return i.tracer.Start(ctx, operation, attributes)
+}
@@ -0,0 +1,94 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testSpan struct {
+	operation  string
+	parent     string
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttribute(key string, value any) {
+	s.attributes[key] = value
+}
+
+func (s *testSpan) End(err error) {
+	s.err = err
+	s.ended = true
+}
+
+type testSpanKey struct{}
+
+// testTracer records spans and which span each was started under
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, operation string, attributes map[string]any) (context.Context, Span) {
+	span := &testSpan{operation: operation, attributes: attributes}
+
+	if parent, ok := ctx.Value(testSpanKey{}).(*testSpan); ok {
+		span.parent = parent.operation
+	}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func TestTracer(t *testing.T) {
+	var chunkBase string
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/results/search_series":
+			fmt.Fprintf(w, `{"chunk_info":{"base_download_url":"%s/chunks/","chunk_file_names":["a","b"]}}`, chunkBase)
+		default:
+			fmt.Fprint(w, `[{}]`)
+		}
+	}))
+
+	chunkBase = fake.baseURL.String()
+
+	tracer := &testTracer{}
+
+	fake.SetTracer(tracer)
+
+	_, err := fake.Get("/data/results/search_series?X-Amz-Signature=secret")
+	assert.NoError(t, err)
+
+	var operations []string
+
+	for _, span := range tracer.spans {
+		operations = append(operations, span.parent+">"+span.operation)
+
+		assert.True(t, span.ended)
+		assert.NoError(t, span.err)
+	}
+
+	assert.Equal(t, []string{
+		">" + TraceGet,
+		TraceGet + ">" + TraceFetch,
+		TraceGet + ">" + TraceChunks,
+		TraceChunks + ">" + TraceFetch,
+		TraceChunks + ">" + TraceFetch,
+	}, operations)
+
+	assert.Equal(t, 2, tracer.spans[2].attributes["chunk_count"])
+	assert.Equal(t, 0, tracer.spans[2].attributes["chunk_failures"])
+	assert.Equal(t, http.StatusOK, tracer.spans[1].attributes["http.status_code"])
+	assert.NotContains(t, tracer.spans[1].attributes["url"], "secret")
+}
@@ -0,0 +1,220 @@
+package irdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// A cassette holds recorded HTTP interactions.  Secrets (cookies, auth
+// headers, passwords, tokens, and S3 signatures) are redacted before they are
+// recorded, and request bodies (only logins have them) and the body of the
+// login response aren't recorded at all since they identify the account, so
+// cassettes are safe to commit alongside tests.
+type cassetteT struct {
+	Interactions []interactionT `json:"interactions"`
+}
+
+type interactionT struct {
+	Request  recordedRequestT  `json:"request"`
+	Response recordedResponseT `json:"response"`
+}
+
+type recordedRequestT struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type recordedResponseT struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// headers that are never recorded
+var unrecordedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// StartRecording records every HTTP interaction made by irdata until
+// StopRecording is called, at which point they are written to
+// cassetteFilename.
+func (i *Irdata) StartRecording(cassetteFilename string) {
+	i.recorder = &recorderT{
+		transport: i.httpClient.Transport,
+		filename:  cassetteFilename,
+		loginURL:  i.loginURL,
+	}
+
+	i.httpClient.Transport = i.recorder
+}
+
+// StopRecording stops recording and writes the cassette
+func (i *Irdata) StopRecording() error {
+	if i.recorder == nil {
+		return makeErrorf("not recording")
+	}
+
+	recorder := i.recorder
+
+	i.httpClient.Transport = recorder.transport
+	i.recorder = nil
+
+	return recorder.save()
+}
+
+// Replay serves irdata's HTTP requests from the interactions recorded in
+// cassetteFilename instead of the network.  Requests are matched by method
+// and (redacted) URL in the order they were recorded.  A request with no
+// matching interaction fails.
+func (i *Irdata) Replay(cassetteFilename string) error {
+	data, err := os.ReadFile(cassetteFilename)
+	if err != nil {
+		return makeErrorf("unable to read cassette %s [%v]", cassetteFilename, err)
+	}
+
+	var cassette cassetteT
+
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return makeErrorf("unable to parse cassette %s [%v]", cassetteFilename, err)
+	}
+
+	i.httpClient.Transport = &playerT{
+		interactions: cassette.Interactions,
+		used:         make([]bool, len(cassette.Interactions)),
+	}
+
+	return nil
+}
+
+type recorderT struct {
+	transport http.RoundTripper
+	filename  string
+	loginURL  string // its response bodies aren't recorded
+
+	mu       sync.Mutex
+	cassette cassetteT
+}
+
+func (r *recorderT) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// cassettes hold text, record (and hand back) the decompressed body
+	if err := gunzipResponse(resp); err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := resp.Header.Clone()
+
+	for _, h := range unrecordedHeaders {
+		header.Del(h)
+	}
+
+	// the recorded body may be a different length once redacted
+	header.Del("Content-Length")
+
+	recordedBody := redactString(string(respBody))
+
+	if req.URL.String() == r.loginURL {
+		recordedBody = ""
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interactionT{
+		Request: recordedRequestT{
+			Method: req.Method,
+			URL:    redactString(req.URL.String()),
+		},
+		Response: recordedResponseT{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       recordedBody,
+		},
+	})
+
+	return resp, nil
+}
+
+func (r *recorderT) save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return makeErrorf("unable to encode cassette [%v]", err)
+	}
+
+	if err := os.WriteFile(r.filename, data, 0644); err != nil {
+		return makeErrorf("unable to write cassette %s [%v]", r.filename, err)
+	}
+
+	log.WithFields(logrus.Fields{
+		"filename":     r.filename,
+		"interactions": len(r.cassette.Interactions),
+	}).Debug("Saved cassette")
+
+	return nil
+}
+
+type playerT struct {
+	mu           sync.Mutex
+	interactions []interactionT
+	used         []bool
+}
+
+func (p *playerT) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	url := redactString(req.URL.String())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for n, interaction := range p.interactions {
+		if p.used[n] || interaction.Request.Method != req.Method || interaction.Request.URL != url {
+			continue
+		}
+
+		p.used[n] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Response.Body))),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, makeErrorf("no recorded interaction for %s %s", req.Method, url)
+}
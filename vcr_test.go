@@ -0,0 +1,104 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordReplay(t *testing.T) {
+	setupRetryTest(t)
+	setupAuthTest()
+	t.Cleanup(cleanupAuthTest)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "irsso_membersv2", Value: "sekrit"})
+		w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+
+	cassetteFn := filepath.Join(testAuthDir, "test.cassette")
+
+	vcr := mustOpen()
+
+	vcr.StartRecording(cassetteFn)
+
+	for _, path := range []string{"/a?X-Amz-Signature=sig1", "/b"} {
+		resp, err := vcr.retryingGet(context.Background(), ts.URL+path)
+
+		assert.NoError(t, err)
+
+		drainAndClose(resp)
+	}
+
+	assert.NoError(t, vcr.StopRecording())
+
+	ts.Close()
+
+	cassette, err := os.ReadFile(cassetteFn)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, string(cassette), "sekrit")
+	assert.NotContains(t, string(cassette), "sig1")
+
+	assert.NoError(t, vcr.Replay(cassetteFn))
+
+	// the signature doesn't need to match, it's redacted on both sides
+	resp, err := vcr.retryingGet(context.Background(), ts.URL+"/a?X-Amz-Signature=sig2")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"path":"/a"}`, string(body))
+
+	resp.Body.Close()
+
+	// each interaction is only played once
+	_, err = vcr.retryingGet(context.Background(), ts.URL+"/a?X-Amz-Signature=sig2")
+
+	assert.Error(t, err)
+}
+
+func TestRecordLogin(t *testing.T) {
+	setupRetryTest(t)
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/auth" {
+			fmt.Fprint(w, `{"authcode":"abc","custId":1234,"email":"user@example.com"}`)
+			return
+		}
+
+		fmt.Fprint(w, `{}`)
+	}))
+
+	fake.isAuthed = false
+
+	cassetteFn := filepath.Join(t.TempDir(), "login.cassette")
+
+	fake.StartRecording(cassetteFn)
+
+	password := fake.passwordSecret(MaskPassword("hunter2", "user@example.com"))
+
+	assert.NoError(t, fake.login(context.Background(), "user@example.com", password))
+	assert.NoError(t, fake.StopRecording())
+
+	cassette, err := os.ReadFile(cassetteFn)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, string(cassette), "user@example.com")
+	assert.NotContains(t, string(cassette), MaskPassword("hunter2", "user@example.com"))
+	assert.Contains(t, string(cassette), "/auth")
+
+	// the login can still be replayed
+	assert.NoError(t, fake.Replay(cassetteFn))
+	assert.NoError(t, fake.login(context.Background(), "user@example.com", password))
+}
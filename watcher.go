@@ -0,0 +1,77 @@
+package irdata
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const _watcherEventsBuffer = 64
+
+// Watcher delivers the new subsessions of drivers and teams to callbacks
+// (see OnEvent) and on a channel (see Events).  It's a Notifier so webhooks
+// and publishers can be added too.
+type Watcher struct {
+	*Notifier
+
+	events chan NotifierEvent
+}
+
+// NewWatcher returns a Watcher that uses i to poll, see NewNotifier
+func (i *Irdata) NewWatcher(lookback time.Duration) *Watcher {
+	w := &Watcher{
+		Notifier: i.NewNotifier(lookback),
+		events:   make(chan NotifierEvent, _watcherEventsBuffer),
+	}
+
+	w.mu.Lock()
+	w.deliverers = append(w.deliverers, w.deliver)
+	w.mu.Unlock()
+
+	return w
+}
+
+// WatchDriver watches for new subsessions of the driver with custID
+func (w *Watcher) WatchDriver(custID int64) {
+	w.Watch(Watch{Kind: WatchDriver, ID: custID})
+}
+
+// WatchTeam watches for new subsessions of the team with teamID
+func (w *Watcher) WatchTeam(teamID int64) {
+	w.Watch(Watch{Kind: WatchTeam, ID: teamID})
+}
+
+// Events returns the channel events are delivered on.  If events aren't
+// read fast enough the channel's buffer fills up and further events fail to
+// be delivered, they're sent again (to the callbacks, webhooks, and
+// publishers too) by the next poll.
+func (w *Watcher) Events() <-chan NotifierEvent {
+	return w.events
+}
+
+// Run polls every interval until ctx is done.  If no cache TTL has been set
+// results are cached for interval when the client's cache is enabled.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) error {
+	w.mu.Lock()
+	if w.cacheTTL == 0 {
+		w.cacheTTL = interval
+	}
+	w.mu.Unlock()
+
+	return w.Notifier.Run(ctx, interval)
+}
+
+func (w *Watcher) deliver(event NotifierEvent) error {
+	select {
+	case w.events <- event:
+		return nil
+	default:
+		log.WithFields(logrus.Fields{
+			"type":         event.Type,
+			"subsessionID": event.SubsessionID,
+		}).Warn("Watcher events channel is full, will retry event")
+
+		return makeErrorf("watcher events channel is full, %s event not delivered", event.Type)
+	}
+}
@@ -0,0 +1,112 @@
+package irdata
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher(t *testing.T) {
+	var mu sync.Mutex
+
+	subsessionIDs := map[string][]int{
+		"cust_id": {1},
+		"team_id": {2},
+	}
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		rows := []map[string]int{}
+
+		for param, ids := range subsessionIDs {
+			if r.URL.Query().Get(param) != "" {
+				for _, id := range ids {
+					rows = append(rows, map[string]int{"subsession_id": id})
+				}
+			}
+		}
+
+		data, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{ChunkDataKey: rows}})
+		w.Write(data)
+	}))
+
+	watcher := fake.NewWatcher(24 * time.Hour)
+
+	var called []int64
+
+	watcher.OnEvent(func(event NotifierEvent) {
+		called = append(called, event.SubsessionID)
+	})
+
+	watcher.WatchDriver(42)
+	watcher.WatchTeam(7)
+
+	assert.NoError(t, watcher.Poll())
+	assert.Empty(t, called)
+
+	mu.Lock()
+	subsessionIDs["cust_id"] = append(subsessionIDs["cust_id"], 10)
+	subsessionIDs["team_id"] = append(subsessionIDs["team_id"], 20)
+	mu.Unlock()
+
+	assert.NoError(t, watcher.Poll())
+
+	assert.Equal(t, []int64{10, 20}, called)
+
+	event := <-watcher.Events()
+	assert.Equal(t, Watch{Kind: WatchDriver, ID: 42}, event.Watch)
+	assert.Equal(t, int64(10), event.SubsessionID)
+
+	event = <-watcher.Events()
+	assert.Equal(t, Watch{Kind: WatchTeam, ID: 7}, event.Watch)
+	assert.Equal(t, int64(20), event.SubsessionID)
+}
+
+func TestWatcherFullEvents(t *testing.T) {
+	var mu sync.Mutex
+
+	subsessionIDs := []int{1}
+
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		rows := []map[string]int{}
+
+		for _, id := range subsessionIDs {
+			rows = append(rows, map[string]int{"subsession_id": id})
+		}
+
+		data, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{ChunkDataKey: rows}})
+		w.Write(data)
+	}))
+
+	watcher := fake.NewWatcher(24 * time.Hour)
+
+	watcher.events = make(chan NotifierEvent, 1)
+
+	watcher.WatchDriver(42)
+
+	assert.NoError(t, watcher.Poll())
+
+	mu.Lock()
+	subsessionIDs = append(subsessionIDs, 10, 11)
+	mu.Unlock()
+
+	// no room for 11
+	assert.Error(t, watcher.Poll())
+	assert.Equal(t, int64(10), (<-watcher.Events()).SubsessionID)
+
+	// so it's sent again
+	assert.NoError(t, watcher.Poll())
+	assert.Equal(t, int64(11), (<-watcher.Events()).SubsessionID)
+
+	assert.NoError(t, watcher.Poll())
+	assert.Empty(t, watcher.Events())
+}
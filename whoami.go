@@ -0,0 +1,33 @@
+package irdata
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Member identifies an iRacing account
+type Member struct {
+	CustID      int64  `json:"cust_id"`
+	DisplayName string `json:"display_name"`
+}
+
+// WhoAmI returns the member the client is authed as, e.g. to confirm which
+// account a creds file belongs to right after auth
+func (i *Irdata) WhoAmI(ctx context.Context) (Member, error) {
+	var member Member
+
+	data, err := i.GetCtx(ctx, "/data/member/info")
+	if err != nil {
+		return member, err
+	}
+
+	if err := json.Unmarshal(data, &member); err != nil {
+		return member, makeErrorf("unable to decode member info [%v]", err)
+	}
+
+	if member.CustID == 0 {
+		return member, makeErrorf("member info has no cust_id")
+	}
+
+	return member, nil
+}
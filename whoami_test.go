@@ -0,0 +1,29 @@
+package irdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhoAmI(t *testing.T) {
+	fake := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/data/member/info", r.URL.Path)
+
+		fmt.Fprint(w, `{"cust_id":123456,"display_name":"Ayrton Senna","flair_id":1}`)
+	}))
+
+	member, err := fake.WhoAmI(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, Member{CustID: 123456, DisplayName: "Ayrton Senna"}, member)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = fake.WhoAmI(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}